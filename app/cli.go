@@ -1,205 +1,357 @@
-package main
-
-import (
-	"fmt"
-	"os"
-	"unicode"
-
-	"github.com/asdine/storm/v3"
-	"github.com/gdamore/tcell/v2"
-	"github.com/rivo/tview"
-	flag "github.com/spf13/pflag"
-
-	"github.com/ajaxray/geek-life/model"
-	"github.com/ajaxray/geek-life/repository"
-	repo "github.com/ajaxray/geek-life/repository/storm"
-	"github.com/ajaxray/geek-life/util"
-)
-
-var (
-	app              *tview.Application
-	layout, contents *tview.Flex
-
-	statusBar         *StatusBar
-	projectPane       *ProjectPane
-	taskPane          *TaskPane
-	taskDetailPane    *TaskDetailPane
-	projectDetailPane *ProjectDetailPane
-
-	db          *storm.DB
-	projectRepo repository.ProjectRepository
-	taskRepo    repository.TaskRepository
-
-	// Flag variables
-	dbFile string
-)
-
-func init() {
-	flag.StringVarP(&dbFile, "db-file", "d", "", "Specify DB file path manually.")
-}
-
-func main() {
-	app = tview.NewApplication()
-	
-	// 设置全局主题：使用单线边框，但不强制背景色
-	blackColor := tcell.NewHexColor(0x0c0c0c)
-	tview.Styles.PrimitiveBackgroundColor = blackColor
-	// 不覆盖按钮和输入框的背景色设置
-	// tview.Styles.ContrastBackgroundColor = blackColor
-	// tview.Styles.MoreContrastBackgroundColor = blackColor
-	
-	// 设置边框为单线样式
-	tview.Borders.Horizontal = '─'
-	tview.Borders.Vertical = '│'
-	tview.Borders.TopLeft = '┌'
-	tview.Borders.TopRight = '┐'
-	tview.Borders.BottomLeft = '└'
-	tview.Borders.BottomRight = '┘'
-	tview.Borders.HorizontalFocus = '─'
-	tview.Borders.VerticalFocus = '│'
-	tview.Borders.TopLeftFocus = '┌'
-	tview.Borders.TopRightFocus = '┐'
-	tview.Borders.BottomLeftFocus = '└'
-	tview.Borders.BottomRightFocus = '┘'
-	
-	flag.Parse()
-
-	db = util.ConnectStorm(dbFile)
-	defer func() {
-		if err := db.Close(); err != nil {
-			util.LogIfError(err, "Error in closing storm Db")
-		}
-	}()
-
-	if flag.NArg() > 0 && flag.Arg(0) == "migrate" {
-		migrate(db)
-		fmt.Println("Database migrated successfully!")
-	} else {
-		projectRepo = repo.NewProjectRepository(db)
-		taskRepo = repo.NewTaskRepository(db)
-
-		titleBar := makeTitleBar()
-		contentPages := prepareContentPages()
-		statusBarPane := prepareStatusBar(app)
-		
-		layout = tview.NewFlex().SetDirection(tview.FlexRow).
-			AddItem(titleBar, 2, 0, false).
-			AddItem(contentPages, 0, 1, true).
-			AddItem(statusBarPane, 1, 0, false)
-		
-		layout.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-
-		setKeyboardShortcuts()
-
-		if err := app.SetRoot(layout, true).EnableMouse(true).Run(); err != nil {
-			panic(err)
-		}
-	}
-
-}
-
-func migrate(database *storm.DB) {
-	util.FatalIfError(database.ReIndex(&model.Project{}), "Error in migrating Projects")
-	util.FatalIfError(database.ReIndex(&model.Task{}), "Error in migrating Tasks")
-
-	fmt.Println("Migration completed. Start geek-life normally.")
-	os.Exit(0)
-}
-
-func setKeyboardShortcuts() *tview.Application {
-	return app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		// 首先检查是否在输入框中，如果是则直接返回事件，屏蔽所有快捷键
-		if ignoreKeyEvt() {
-			return event
-		}
-
-		// 只有不在输入框时才处理快捷键
-		// Global shortcuts
-		switch unicode.ToLower(event.Rune()) {
-		case 'p':
-			app.SetFocus(projectPane)
-			contents.RemoveItem(taskDetailPane)
-			return nil
-		case 'q':
-			// 退出功能
-			return nil
-		case 't':
-			app.SetFocus(taskPane)
-			contents.RemoveItem(taskDetailPane)
-			return nil
-		}
-
-		// Handle based on current focus. Handlers may modify event
-		switch {
-		case projectPane.HasFocus():
-			event = projectPane.handleShortcuts(event)
-		case taskPane.HasFocus():
-			event = taskPane.handleShortcuts(event)
-			if event != nil && projectDetailPane.isShowing() {
-				event = projectDetailPane.handleShortcuts(event)
-			}
-		case taskDetailPane.HasFocus():
-			event = taskDetailPane.handleShortcuts(event)
-		}
-
-		return event
-	})
-}
-
-func prepareContentPages() *tview.Flex {
-	projectPane = NewProjectPane(projectRepo)
-	taskPane = NewTaskPane(projectRepo, taskRepo)
-	projectDetailPane = NewProjectDetailPane()
-	taskDetailPane = NewTaskDetailPane(taskRepo)
-
-	contents = tview.NewFlex().
-		AddItem(projectPane, 25, 1, true).
-		AddItem(taskPane, 0, 2, false)
-		
-	contents.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-	return contents
-
-}
-
-func makeTitleBar() *tview.Flex {
-	titleText := tview.NewTextView()
-	titleText.SetText("[lime::b]Geek-Life")
-	titleText.SetDynamicColors(true)
-	titleText.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-	titleText.SetTextAlign(tview.AlignLeft)
-	
-	versionInfo := tview.NewTextView()
-	versionInfo.SetText("[::d]Version: 0.1.2")
-	versionInfo.SetTextAlign(tview.AlignRight)
-	versionInfo.SetDynamicColors(true)
-	versionInfo.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-
-	titleBar := tview.NewFlex()
-	titleBar.SetDirection(tview.FlexColumn)
-	titleBar.AddItem(titleText, 0, 2, false)
-	titleBar.AddItem(versionInfo, 0, 1, false)
-	titleBar.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-	titleBar.SetBorder(false)
-	
-	return titleBar
-}
-
-func AskYesNo(text string, f func()) {
-
-	activePane := app.GetFocus()
-	modal := tview.NewModal().
-		SetText(text).
-		AddButtons([]string{"Yes", "No"}).
-		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
-			if buttonLabel == "Yes" {
-				f()
-			}
-			app.SetRoot(layout, true).EnableMouse(true)
-			app.SetFocus(activePane)
-		})
-
-	pages := tview.NewPages().
-		AddPage("background", layout, true, true).
-		AddPage("modal", modal, true, true)
-	_ = app.SetRoot(pages, true).EnableMouse(true)
-}
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unicode"
+
+	"github.com/asdine/storm/v3"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+	flag "github.com/spf13/pflag"
+
+	"github.com/ajaxray/geek-life/migration"
+	"github.com/ajaxray/geek-life/repository"
+	sqliteRepo "github.com/ajaxray/geek-life/repository/sqlite"
+	repo "github.com/ajaxray/geek-life/repository/storm"
+	"github.com/ajaxray/geek-life/undo"
+	"github.com/ajaxray/geek-life/util"
+)
+
+var (
+	app              *tview.Application
+	layout, contents *tview.Flex
+
+	statusBar         *StatusBar
+	projectPane       *ProjectPane
+	taskPane          *TaskPane
+	taskDetailPane    *TaskDetailPane
+	projectDetailPane *ProjectDetailPane
+	trashPane         *TrashPane
+	timeTracker       *TimeTracker
+	pomodoro          *Pomodoro
+
+	db          *storm.DB
+	sqlDB       *sql.DB
+	migrator    *migration.Migrator
+	projectRepo repository.ProjectRepository
+	taskRepo    repository.TaskRepository
+	transactor  repository.Transactor
+	undoManager *undo.Manager
+
+	// Flag variables
+	dbFile    string
+	backend   string
+	dryRun    bool
+	trashDays int
+	syncDir   string
+
+	// CalDAV server to sync with
+	caldavURL, caldavUser, caldavPassword string
+
+	// Pomodoro phase lengths in minutes
+	pomodoroWork, pomodoroShortBreak, pomodoroLongBreak int
+
+	// Hours of estimated effort a day can take before it is overbooked
+	dailyCapacity int
+)
+
+func init() {
+	flag.StringVarP(&dbFile, "db-file", "d", "", "Specify DB file path manually.")
+	flag.StringVarP(&backend, "backend", "b", "", "Storage backend: storm or sqlite. Detected from DB file extension if not mentioned.")
+	flag.BoolVar(&dryRun, "dry-run", false, "With migrate command, list pending migrations without applying them.")
+	flag.StringVar(&syncDir, "sync-dir", util.GetEnvStr("SYNC_DIR", ""), "With sync command, the shared directory to exchange changes with other machines through.")
+	flag.StringVar(&caldavURL, "caldav-url", util.GetEnvStr("CALDAV_URL", ""), "With sync caldav command, URL of your calendar home on CalDAV server.")
+	flag.StringVar(&caldavUser, "caldav-user", util.GetEnvStr("CALDAV_USER", ""), "With sync caldav command, user name on CalDAV server.")
+	flag.StringVar(&caldavPassword, "caldav-password", util.GetEnvStr("CALDAV_PASSWORD", ""), "With sync caldav command, password on CalDAV server. Prefer CALDAV_PASSWORD env to keep it out of shell history.")
+	flag.IntVar(&trashDays, "trash-days", util.GetEnvInt("TRASH_DAYS", 30), "Days to keep deleted projects and tasks in Trash. 0 keeps them forever.")
+	flag.IntVar(&pomodoroWork, "pomodoro-work", util.GetEnvInt("POMODORO_WORK", 25), "Minutes of a Pomodoro focus session.")
+	flag.IntVar(&pomodoroShortBreak, "pomodoro-short-break", util.GetEnvInt("POMODORO_SHORT_BREAK", 5), "Minutes of a short break between Pomodoros.")
+	flag.IntVar(&pomodoroLongBreak, "pomodoro-long-break", util.GetEnvInt("POMODORO_LONG_BREAK", 15), "Minutes of the long break after every 4 Pomodoros.")
+	flag.IntVar(&dailyCapacity, "daily-capacity", util.GetEnvInt("DAILY_CAPACITY", 8), "Hours of estimated effort a day can take, days estimated over it are shown overbooked.")
+}
+
+func main() {
+	app = tview.NewApplication()
+
+	// 设置全局主题：使用单线边框，但不强制背景色
+	blackColor := tcell.NewHexColor(0x0c0c0c)
+	tview.Styles.PrimitiveBackgroundColor = blackColor
+	// 不覆盖按钮和输入框的背景色设置
+	// tview.Styles.ContrastBackgroundColor = blackColor
+	// tview.Styles.MoreContrastBackgroundColor = blackColor
+
+	// 设置边框为单线样式
+	tview.Borders.Horizontal = '─'
+	tview.Borders.Vertical = '│'
+	tview.Borders.TopLeft = '┌'
+	tview.Borders.TopRight = '┐'
+	tview.Borders.BottomLeft = '└'
+	tview.Borders.BottomRight = '┘'
+	tview.Borders.HorizontalFocus = '─'
+	tview.Borders.VerticalFocus = '│'
+	tview.Borders.TopLeftFocus = '┌'
+	tview.Borders.TopRightFocus = '┐'
+	tview.Borders.BottomLeftFocus = '└'
+	tview.Borders.BottomRightFocus = '┘'
+
+	flag.Parse()
+
+	connectDB()
+	defer closeDB()
+
+	if flag.NArg() > 0 && flag.Arg(0) == "migrate" {
+		migrate()
+	} else if flag.NArg() > 0 && flag.Arg(0) == "sync" {
+		autoMigrate()
+		if flag.NArg() > 1 && flag.Arg(1) == "caldav" {
+			syncCalDAV()
+		} else {
+			syncFiles()
+		}
+	} else {
+		autoMigrate()
+		purgeExpiredTrash()
+		enableUndo()
+
+		titleBar := makeTitleBar()
+		contentPages := prepareContentPages()
+		statusBarPane := prepareStatusBar(app)
+		pomodoro = NewPomodoro(pomodoroWork, pomodoroShortBreak, pomodoroLongBreak)
+
+		layout = tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(titleBar, 2, 0, false).
+			AddItem(contentPages, 0, 1, true).
+			AddItem(statusBarPane, 1, 0, false)
+
+		layout.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+
+		setKeyboardShortcuts()
+		go timeTracker.tick()
+		go watchReminders()
+		go taskPane.reloadOnNewDay()
+
+		if err := app.SetRoot(layout, true).EnableMouse(true).Run(); err != nil {
+			panic(err)
+		}
+	}
+
+}
+
+// connectDB opens the database of selected backend and prepares repositories on it
+func connectDB() {
+	switch util.DetectBackend(backend, dbFile) {
+	case util.BackendStorm:
+		db = util.ConnectStorm(dbFile)
+		migrator = repo.NewMigrator(db)
+		projectRepo = repo.NewProjectRepository(db)
+		taskRepo = repo.NewTaskRepository(db)
+		transactor = repo.NewTransactor(db)
+	case util.BackendSQLite:
+		sqlDB = util.ConnectSQLite(dbFile)
+		migrator = sqliteRepo.NewMigrator(sqlDB)
+		projectRepo = sqliteRepo.NewProjectRepository(sqlDB)
+		taskRepo = sqliteRepo.NewTaskRepository(sqlDB)
+		transactor = sqliteRepo.NewTransactor(sqlDB)
+	default:
+		fmt.Println("Unknown backend: " + backend + ". Please use storm or sqlite.")
+		os.Exit(1)
+	}
+}
+
+func closeDB() {
+	if db != nil {
+		util.LogIfError(db.Close(), "Error in closing storm Db")
+	}
+	if sqlDB != nil {
+		util.LogIfError(sqlDB.Close(), "Error in closing SQLite Db")
+	}
+}
+
+// migrate applies pending migrations, or only lists them in case of --dry-run
+func migrate() {
+	result, err := migrator.Migrate(dryRun)
+	if result.BackupPath != "" {
+		fmt.Println("Database backed up to " + result.BackupPath)
+	}
+	for _, step := range result.Steps {
+		fmt.Printf("  v%d: %s\n", step.Version, step.Description)
+	}
+	util.FatalIfError(err, "Error in migrating database")
+
+	switch {
+	case len(result.Steps) == 0:
+		fmt.Printf("Database is up to date (v%d).\n", result.ToVersion)
+	case result.DryRun:
+		fmt.Printf("Dry run: above migrations would bring database from v%d to v%d.\n", result.FromVersion, result.ToVersion)
+	default:
+		fmt.Printf("Database migrated from v%d to v%d. Start geek-life normally.\n", result.FromVersion, result.ToVersion)
+	}
+}
+
+// autoMigrate brings database up to date before starting the app,
+// and refuses to touch a database migrated by a newer version of geek-life
+func autoMigrate() {
+	pending, err := migrator.Pending()
+	if errors.Is(err, migration.ErrNewerSchema) {
+		fmt.Println(err.Error() + ". Please upgrade geek-life to open this database.")
+		closeDB()
+		os.Exit(1)
+	}
+	util.FatalIfError(err, "Could not check database version")
+
+	if len(pending) > 0 {
+		migrate()
+	}
+}
+
+// purgeExpiredTrash permanently deletes projects and tasks kept in Trash for more than trashDays
+func purgeExpiredTrash() {
+	if trashDays <= 0 {
+		return
+	}
+
+	err := transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		_, err := repository.PurgeTrash(projects, tasks, time.Now().AddDate(0, 0, -trashDays))
+		return err
+	})
+	util.LogIfError(err, "Error in purging expired Trash")
+}
+
+// enableUndo makes changes done through repositories and transactions recordable, to undo and redo them
+func enableUndo() {
+	undoManager = undo.New(projectRepo, taskRepo, transactor, 100)
+	projectRepo, taskRepo, transactor = undoManager.Projects(), undoManager.Tasks(), undoManager.Transactor()
+}
+
+func setKeyboardShortcuts() *tview.Application {
+	return app.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// 首先检查是否在输入框中，如果是则直接返回事件，屏蔽所有快捷键
+		if ignoreKeyEvt() {
+			return event
+		}
+
+		// 只有不在输入框时才处理快捷键
+		// Global shortcuts
+		if event.Key() == tcell.KeyCtrlR {
+			redoLastAction()
+			return nil
+		}
+
+		switch unicode.ToLower(event.Rune()) {
+		case 'p':
+			app.SetFocus(projectPane)
+			contents.RemoveItem(taskDetailPane)
+			return nil
+		case 'q':
+			// 退出功能
+			return nil
+		case 'u':
+			undoLastAction()
+			return nil
+		case 't':
+			if trashPane.showing {
+				app.SetFocus(trashPane)
+				return nil
+			}
+			app.SetFocus(taskPane)
+			contents.RemoveItem(taskDetailPane)
+			return nil
+		}
+
+		// Handle based on current focus. Handlers may modify event
+		switch {
+		case projectPane.HasFocus():
+			event = projectPane.handleShortcuts(event)
+		case taskPane.HasFocus():
+			event = taskPane.handleShortcuts(event)
+			if event != nil && projectDetailPane.isShowing() {
+				event = projectDetailPane.handleShortcuts(event)
+			}
+		case taskDetailPane.HasFocus():
+			event = taskDetailPane.handleShortcuts(event)
+		case trashPane.HasFocus():
+			event = trashPane.handleShortcuts(event)
+		}
+
+		return event
+	})
+}
+
+func prepareContentPages() *tview.Flex {
+	trashPane = NewTrashPane(projectRepo, taskRepo)
+	projectPane = NewProjectPane(projectRepo)
+	taskPane = NewTaskPane(projectRepo, taskRepo)
+	projectDetailPane = NewProjectDetailPane()
+	taskDetailPane = NewTaskDetailPane(taskRepo)
+
+	contents = tview.NewFlex().
+		AddItem(projectPane, 25, 1, true).
+		AddItem(taskPane, 0, 2, false)
+
+	contents.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+	return contents
+
+}
+
+func makeTitleBar() *tview.Flex {
+	titleText := tview.NewTextView()
+	titleText.SetText("[lime::b]Geek-Life")
+	titleText.SetDynamicColors(true)
+	titleText.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+	titleText.SetTextAlign(tview.AlignLeft)
+
+	versionInfo := tview.NewTextView()
+	versionInfo.SetText("[::d]Version: 0.1.2")
+	versionInfo.SetTextAlign(tview.AlignRight)
+	versionInfo.SetDynamicColors(true)
+	versionInfo.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+
+	// 正在计时的任务显示在标题栏中间
+	timeTracker = NewTimeTracker()
+
+	titleBar := tview.NewFlex()
+	titleBar.SetDirection(tview.FlexColumn)
+	titleBar.AddItem(titleText, 0, 2, false)
+	titleBar.AddItem(timeTracker, 0, 2, false)
+	titleBar.AddItem(versionInfo, 0, 1, false)
+	titleBar.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+	titleBar.SetBorder(false)
+
+	return titleBar
+}
+
+func AskYesNo(text string, f func()) {
+	AskChoice(text, []string{"Yes", "No"}, func(label string) {
+		if label == "Yes" {
+			f()
+		}
+	})
+}
+
+// modalOpen tells if a modal is being shown by AskChoice
+var modalOpen bool
+
+// AskChoice shows a modal with buttons, calling f with label of the chosen one (empty if closed with Esc)
+func AskChoice(text string, buttons []string, f func(label string)) {
+
+	activePane := app.GetFocus()
+	modal := tview.NewModal().
+		SetText(text).
+		AddButtons(buttons).
+		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+			f(buttonLabel)
+			app.SetRoot(layout, true).EnableMouse(true)
+			app.SetFocus(activePane)
+			modalOpen = false
+		})
+
+	pages := tview.NewPages().
+		AddPage("background", layout, true, true).
+		AddPage("modal", modal, true, true)
+	_ = app.SetRoot(pages, true).EnableMouse(true)
+	modalOpen = true
+}
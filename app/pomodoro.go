@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// pomodoroPhase is a phase of the Pomodoro cycle
+type pomodoroPhase int
+
+const (
+	phaseFocus pomodoroPhase = iota
+	phaseShortBreak
+	phaseLongBreak
+)
+
+// pomodorosPerLongBreak is the number of focus phases after which a long break is taken
+const pomodorosPerLongBreak = 4
+
+// Pomodoro runs focus and break phases against a task, counting completed focus phases on it.
+// Its state is only changed in the event loop; the ticking goroutine just queues updates.
+type Pomodoro struct {
+	lengths   map[pomodoroPhase]time.Duration
+	task      model.Task
+	phase     pomodoroPhase
+	endsAt    time.Time
+	completed int           // Focus phases completed since the pomodoro was started
+	stop      chan struct{} // Stops the ticking goroutine, nil when the pomodoro is not running
+}
+
+// NewPomodoro initializes a Pomodoro with lengths of the phases in minutes
+func NewPomodoro(focus, shortBreak, longBreak int) *Pomodoro {
+	return &Pomodoro{
+		lengths: map[pomodoroPhase]time.Duration{
+			phaseFocus:      minutesOr(focus, 25),
+			phaseShortBreak: minutesOr(shortBreak, 5),
+			phaseLongBreak:  minutesOr(longBreak, 15),
+		},
+	}
+}
+
+// Toggle starts a pomodoro on a task, or stops it if one is running on the task already
+func (p *Pomodoro) Toggle(task model.Task) {
+	if p.IsRunning(task.ID) {
+		p.Stop()
+		statusBar.showForSeconds("[yellow::]Pomodoro stopped: "+task.Title, 5)
+		return
+	}
+
+	p.Start(task)
+}
+
+// Start starts focusing on a task, stopping the running pomodoro
+func (p *Pomodoro) Start(task model.Task) {
+	if task.Completed {
+		statusBar.showForSeconds("[yellow::]Task is completed. Resume it to focus on it", 5)
+		return
+	}
+
+	if p.task.ID != task.ID {
+		p.completed = 0
+	}
+	p.Stop()
+
+	p.task = task
+	p.stop = make(chan struct{})
+	p.startPhase(phaseFocus)
+	go p.tick(p.stop)
+
+	statusBar.showForSeconds(fmt.Sprintf("[lime::]Focusing for %d minutes: %s", int(p.lengths[phaseFocus].Minutes()), task.Title), 5)
+}
+
+// Stop stops the running pomodoro, if any
+func (p *Pomodoro) Stop() {
+	if p.stop == nil {
+		return
+	}
+
+	close(p.stop)
+	p.stop = nil
+	statusBar.setCountdown("")
+}
+
+// IsRunning tells if a pomodoro is running on a task
+func (p *Pomodoro) IsRunning(taskID int64) bool {
+	return p.stop != nil && p.task.ID == taskID
+}
+
+func (p *Pomodoro) startPhase(phase pomodoroPhase) {
+	p.phase = phase
+	p.endsAt = time.Now().Add(p.lengths[phase])
+	p.refresh()
+}
+
+// tick queues update of the countdown every second, until stop is closed
+func (p *Pomodoro) tick(stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			app.QueueUpdateDraw(func() {
+				// The pomodoro may have been stopped or restarted while this update was waiting in queue
+				if p.stop == stop {
+					p.update()
+				}
+			})
+		}
+	}
+}
+
+// update refreshes the countdown, moving to next phase when current one is over
+func (p *Pomodoro) update() {
+	if time.Now().Before(p.endsAt) {
+		p.refresh()
+		return
+	}
+
+	statusBar.ringBell()
+	if p.phase != phaseFocus {
+		p.Stop()
+		statusBar.showForSeconds("[lime::]Break is over. Press f to focus again on "+p.task.Title, 10)
+		return
+	}
+
+	p.completed++
+	p.countOnTask()
+
+	next, name := phaseShortBreak, "short"
+	if p.completed%pomodorosPerLongBreak == 0 {
+		next, name = phaseLongBreak, "long"
+	}
+	p.startPhase(next)
+	statusBar.showForSeconds(fmt.Sprintf("[lime::]Pomodoro completed: %s. Take a %s break!", p.task.Title, name), 10)
+}
+
+// countOnTask adds a completed pomodoro to the task
+func (p *Pomodoro) countOnTask() {
+	task, err := taskRepo.GetByID(strconv.FormatInt(p.task.ID, 10))
+	if err == nil {
+		err = taskRepo.UpdateField(&task, "Pomodoros", task.Pomodoros+1)
+	}
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not count pomodoro: "+err.Error(), 5)
+		return
+	}
+
+	p.task = task
+	if idx := taskPane.indexOfTaskID(task.ID); idx != -1 {
+		taskPane.tasks[idx].Pomodoros = task.Pomodoros
+	}
+	if taskDetailPane.task != nil && taskDetailPane.task.ID == task.ID {
+		taskDetailPane.task.Pomodoros = task.Pomodoros
+		taskDetailPane.setTrackedDisplay()
+	}
+}
+
+// refresh shows the countdown of current phase in status bar
+func (p *Pomodoro) refresh() {
+	remaining := time.Until(p.endsAt).Round(time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	clock := fmt.Sprintf("%02d:%02d", int(remaining.Minutes()), int(remaining.Seconds())%60)
+
+	switch p.phase {
+	case phaseFocus:
+		statusBar.setCountdown(fmt.Sprintf("[tomato]🍅 Focus #%d: %s [::b]%s[::-]", p.completed+1, p.task.Title, clock))
+	case phaseShortBreak:
+		statusBar.setCountdown(fmt.Sprintf("[lime]☕ Short break [::b]%s[::-]", clock))
+	case phaseLongBreak:
+		statusBar.setCountdown(fmt.Sprintf("[lime]☕ Long break [::b]%s[::-]", clock))
+	}
+}
+
+// minutesOr converts minutes to Duration, using the fallback if minutes is not positive
+func minutesOr(minutes, fallback int) time.Duration {
+	if minutes <= 0 {
+		minutes = fallback
+	}
+
+	return time.Duration(minutes) * time.Minute
+}
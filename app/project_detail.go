@@ -1,73 +1,316 @@
-package main
-
-import (
-	"unicode"
-
-	"github.com/gdamore/tcell/v2"
-	"github.com/rivo/tview"
-
-	"github.com/ajaxray/geek-life/model"
-)
-
-// ProjectDetailPane Displays relevant actions of current project
-type ProjectDetailPane struct {
-	*tview.Flex
-	project *model.Project
-}
-
-func removeProjectWithConfirmation() {
-	AskYesNo("Do you want to delete Project?", projectPane.RemoveActivateProject)
-}
-
-func clearCompletedWithConfirmation() {
-	AskYesNo("Do you want to clear completed tasks?", taskPane.ClearCompletedTasks)
-}
-
-// NewProjectDetailPane Initializes ProjectDetailPane
-func NewProjectDetailPane() *ProjectDetailPane {
-	pane := ProjectDetailPane{
-		Flex: tview.NewFlex().SetDirection(tview.FlexRow),
-	}
-	// 创建红色的删除按钮 - 尝试不同的设置方式
-	deleteBtn := tview.NewButton("Delete Project")
-	deleteBtn.SetSelectedFunc(removeProjectWithConfirmation)
-	deleteBtn.SetLabelColor(tcell.ColorWhite)
-	// 强制设置按钮的样式
-	deleteBtn.SetStyle(tcell.StyleDefault.Background(tcell.ColorRed).Foreground(tcell.ColorWhite))
-	deleteBtn.SetBackgroundColor(tcell.ColorRed)
-	deleteBtn.SetBorder(false)
-	
-	clearBtn := makeButton("Clear Completed Tasks", clearCompletedWithConfirmation)
-	pane.
-		AddItem(deleteBtn, 3, 1, false).
-		AddItem(blankCell, 1, 1, false).
-		AddItem(clearBtn, 3, 1, false).
-		AddItem(blankCell, 0, 1, false)
-
-	pane.SetBorder(true).SetTitle("Actions").SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-
-	return &pane
-}
-
-// SetProject Sets the active Project
-func (pd *ProjectDetailPane) SetProject(project *model.Project) {
-	pd.project = project
-	pd.SetTitle("[::b]" + pd.project.Title)
-}
-
-func (pd *ProjectDetailPane) isShowing() bool {
-	return taskPane.activeTask == nil && projectPane.activeProject != nil
-}
-
-func (pd *ProjectDetailPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey {
-	switch unicode.ToLower(event.Rune()) {
-	case 'd':
-		removeProjectWithConfirmation()
-		return nil
-	case 'c':
-		clearCompletedWithConfirmation()
-		return nil
-	}
-
-	return event
-}
+package main
+
+import (
+	"fmt"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pgavlin/femto"
+	"github.com/pgavlin/femto/runtime"
+	"github.com/rivo/tview"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// ProjectDetailPane Displays relevant actions of current project
+type ProjectDetailPane struct {
+	*tview.Flex
+	project     *model.Project
+	archiveBtn  *tview.Button
+	parentInput *tview.InputField
+	effort      *tview.TextView
+	counts      *tview.TextView
+	description *femto.View
+	editorHint  *tview.TextView
+}
+
+const projectEditorHint = "e = edit, x = external"
+
+func removeProjectWithConfirmation() {
+	question := "Do you want to move Project to Trash?"
+	if project := projectPane.GetActiveProject(); project != nil {
+		if subProjects := repository.ProjectSubtree(projectPane.projects, project.ID); len(subProjects) > 0 {
+			question = fmt.Sprintf("Do you want to move Project to Trash, along with %d sub-projects?", len(subProjects))
+		}
+	}
+	AskYesNo(question, projectPane.RemoveActivateProject)
+}
+
+func clearCompletedWithConfirmation() {
+	if !taskPane.canEdit() {
+		return
+	}
+	AskYesNo("Do you want to move completed tasks to Trash?", taskPane.ClearCompletedTasks)
+}
+
+// toggleArchived archives the active project, or unarchives it if it is archived
+func (pd *ProjectDetailPane) toggleArchived() {
+	if pd.project != nil {
+		projectPane.SetActiveProjectArchived(!pd.project.Archived)
+	}
+}
+
+// canEdit tells if the project is open and can be changed, warning if it is archived
+func (pd *ProjectDetailPane) canEdit() bool {
+	if pd.project == nil {
+		return false
+	}
+	if pd.project.Archived {
+		statusBar.showForSeconds("[yellow::]Archived projects are read-only. Unarchive it first", 5)
+		return false
+	}
+
+	return true
+}
+
+// editParent focuses the input to move active project under another one
+func (pd *ProjectDetailPane) editParent() {
+	if pd.canEdit() {
+		app.SetFocus(pd.parentInput)
+	}
+}
+
+// NewProjectDetailPane Initializes ProjectDetailPane
+func NewProjectDetailPane() *ProjectDetailPane {
+	pane := ProjectDetailPane{
+		Flex:       tview.NewFlex().SetDirection(tview.FlexRow),
+		effort:     tview.NewTextView().SetDynamicColors(true),
+		counts:     tview.NewTextView().SetDynamicColors(true),
+		editorHint: tview.NewTextView().SetText(projectEditorHint).SetTextColor(tcell.ColorDimGray),
+	}
+	pane.prepareDescriptionEditor()
+	// 创建红色的删除按钮 - 尝试不同的设置方式
+	deleteBtn := tview.NewButton("Delete Project")
+	deleteBtn.SetSelectedFunc(removeProjectWithConfirmation)
+	deleteBtn.SetLabelColor(tcell.ColorWhite)
+	// 强制设置按钮的样式
+	deleteBtn.SetStyle(tcell.StyleDefault.Background(tcell.ColorRed).Foreground(tcell.ColorWhite))
+	deleteBtn.SetBackgroundColor(tcell.ColorRed)
+	deleteBtn.SetBorder(false)
+	
+	clearBtn := makeButton("Clear Completed Tasks", clearCompletedWithConfirmation)
+	pane.archiveBtn = makeButton("Archive Project", pane.toggleArchived)
+	pane.parentInput = tview.NewInputField().
+		SetPlaceholder("Top level").
+		SetFieldTextColor(tcell.ColorWhite).
+		SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+	pane.parentInput.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			projectPane.SetActiveProjectParent(pane.parentInput.GetText())
+		}
+		if pane.project != nil {
+			pane.SetProject(pane.project)
+		}
+		app.SetFocus(taskPane)
+	})
+	parentLabel := tview.NewTextView().SetTextColor(tcell.ColorDimGray).SetText("Parent (m = move)")
+
+	pane.
+		AddItem(deleteBtn, 3, 1, false).
+		AddItem(blankCell, 1, 1, false).
+		AddItem(clearBtn, 3, 1, false).
+		AddItem(blankCell, 1, 1, false).
+		AddItem(pane.archiveBtn, 3, 1, false).
+		AddItem(blankCell, 1, 1, false).
+		AddItem(parentLabel, 1, 1, false).
+		AddItem(pane.parentInput, 1, 1, false).
+		AddItem(blankCell, 1, 1, false).
+		AddItem(pane.counts, 3, 1, false).
+		AddItem(pane.effort, 3, 1, false).
+		AddItem(blankCell, 1, 1, false).
+		AddItem(tview.NewTextView().SetText("Description:"), 1, 1, false).
+		AddItem(pane.description, 0, 1, false).
+		AddItem(pane.editorHint, 1, 1, false)
+
+	pane.SetBorder(true).SetTitle("Actions").SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+
+	return &pane
+}
+
+// SetProject Sets the active Project
+func (pd *ProjectDetailPane) SetProject(project *model.Project) {
+	pd.project = project
+	if project.Archived {
+		pd.SetTitle("[::b]" + pd.project.Title + " [::d](archived)")
+		pd.archiveBtn.SetLabel("Unarchive Project")
+	} else {
+		pd.SetTitle("[::b]" + pd.project.Title)
+		pd.archiveBtn.SetLabel("Archive Project")
+	}
+	pd.parentInput.SetText(projectPane.projectPath(project.ParentID, 0))
+	pd.description.Buf = makeBufferFromString(project.Description)
+	pd.description.SetColorscheme(loadNoteColorScheme())
+	pd.description.Start()
+	pd.setCountsDisplay()
+	pd.setEffortDisplay()
+}
+
+// subtreeTasks loads tasks of the project, including its sub-projects
+func (pd *ProjectDetailPane) subtreeTasks() ([]model.Task, error) {
+	projects := append([]model.Project{*pd.project}, repository.ProjectSubtree(projectPane.projects, pd.project.ID)...)
+
+	var tasks []model.Task
+	for _, project := range projects {
+		projectTasks, err := taskRepo.GetAllByProject(project)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, projectTasks...)
+	}
+
+	return tasks, nil
+}
+
+// setCountsDisplay shows open, completed and overdue tasks of the project (including its sub-projects) and its completion percentage
+func (pd *ProjectDetailPane) setCountsDisplay() {
+	if pd.project == nil {
+		return
+	}
+
+	tasks, err := pd.subtreeTasks()
+	if err != nil {
+		pd.counts.SetText("Tasks: [red]" + err.Error())
+		return
+	}
+
+	counts := repository.CountTasks(tasks, time.Now())
+	text := fmt.Sprintf("Tasks: %d open, %d done", counts.Total-counts.Completed, counts.Completed)
+	if counts.Overdue > 0 {
+		text += fmt.Sprintf("\nOverdue: [red]%d[-]", counts.Overdue)
+	} else {
+		text += "\nOverdue: [::d]none[::-]"
+	}
+	text += fmt.Sprintf("\nCompleted: [#5FAFD7]%d%%[-]", counts.CompletionPercent())
+	pd.counts.SetText(text)
+}
+
+// setEffortDisplay summarizes effort on tasks of the project, including its sub-projects:
+// time tracked on them, and their estimated effort against the actual one (time tracked and spent as entered)
+func (pd *ProjectDetailPane) setEffortDisplay() {
+	if pd.project == nil {
+		return
+	}
+
+	tasks, err := pd.subtreeTasks()
+	var tracked, estimate, actual time.Duration
+	if err == nil {
+		tracked, err = repository.TasksTrackedTime(taskRepo, tasks, time.Now())
+	}
+	if err == nil {
+		estimate, actual, err = repository.TasksEffort(taskRepo, tasks, time.Now())
+	}
+	if err != nil {
+		pd.effort.SetText("Tracked: [red]" + err.Error())
+		return
+	}
+
+	text := "Tracked: [::d]none[::-]"
+	if tracked > 0 {
+		text = "Tracked: [#5FAFD7]" + formatDuration(tracked) + "[-]"
+	}
+
+	switch {
+	case estimate == 0:
+		text += "\nEstimated: [::d]none[::-]"
+	case actual > estimate:
+		text += fmt.Sprintf("\nEstimated: [#5FAFD7]%s[-]\nActual: [red]%s (%d%%)[-]", formatDuration(estimate), formatDuration(actual), actual*100/estimate)
+	default:
+		text += fmt.Sprintf("\nEstimated: [#5FAFD7]%s[-]\nActual: %s (%d%%)", formatDuration(estimate), formatDuration(actual), actual*100/estimate)
+	}
+	pd.effort.SetText(text)
+}
+
+func (pd *ProjectDetailPane) isShowing() bool {
+	return taskPane.activeTask == nil && projectPane.activeProject != nil
+}
+
+func (pd *ProjectDetailPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey {
+	switch unicode.ToLower(event.Rune()) {
+	case 'd':
+		removeProjectWithConfirmation()
+		return nil
+	case 'c':
+		clearCompletedWithConfirmation()
+		return nil
+	case 'v':
+		pd.toggleArchived()
+		return nil
+	case 'm':
+		pd.editParent()
+		return nil
+	case 'e':
+		pd.activateEditor()
+		return nil
+	case 'x':
+		pd.editInExternalEditor()
+		return nil
+	}
+
+	return event
+}
+
+func (pd *ProjectDetailPane) prepareDescriptionEditor() {
+	pd.description = femto.NewView(makeBufferFromString(""))
+	pd.description.SetRuntimeFiles(runtime.Files)
+	pd.description.Readonly = true
+	pd.description.SetBorder(true)
+	pd.description.SetBorderColor(tcell.ColorLightSlateGray)
+	pd.description.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+
+	pd.description.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEsc {
+			pd.updateDescription(pd.description.Buf.String())
+			pd.deactivateEditor()
+			return nil
+		}
+
+		return event
+	})
+}
+
+// activateEditor starts editing description of the project in femto editor
+func (pd *ProjectDetailPane) activateEditor() {
+	if !pd.canEdit() {
+		return
+	}
+
+	pd.description.Readonly = false
+	pd.description.SetBorderColor(tcell.ColorDarkOrange)
+	pd.editorHint.SetText("Esc to save changes")
+	app.SetFocus(pd.description)
+}
+
+func (pd *ProjectDetailPane) deactivateEditor() {
+	pd.description.Readonly = true
+	pd.description.SetBorderColor(tcell.ColorLightSlateGray)
+	pd.editorHint.SetText(projectEditorHint)
+	app.SetFocus(taskPane)
+}
+
+func (pd *ProjectDetailPane) editInExternalEditor() {
+	if !pd.canEdit() {
+		return
+	}
+
+	if updatedContent := editInExternalEditor(pd.project.Description); updatedContent != "" {
+		pd.updateDescription(updatedContent)
+		pd.SetProject(pd.project)
+	}
+}
+
+func (pd *ProjectDetailPane) updateDescription(description string) {
+	if pd.project == nil || description == pd.project.Description {
+		return
+	}
+
+	defer recordAction("Edit description of " + pd.project.Title)()
+	if err := projectRepo.UpdateField(pd.project, "Description", description); err != nil {
+		statusBar.showForSeconds("[red]Could not save: "+err.Error(), 5)
+		return
+	}
+
+	pd.project.Description = description
+	statusBar.showForSeconds("[lime]Saved project description", 5)
+}
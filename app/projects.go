@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -14,18 +15,6 @@ import (
 	"github.com/ajaxray/geek-life/repository"
 )
 
-// ProjectYearGroup 用于按年份分组项目
-type ProjectYearGroup struct {
-	Year     int
-	Projects []ProjectWithIndex
-}
-
-// ProjectWithIndex 项目及其在原始列表中的索引
-type ProjectWithIndex struct {
-	Project model.Project
-	Index   int
-}
-
 // ProjectPane Displays projects and dynamic lists
 type ProjectPane struct {
 	*tview.Flex
@@ -38,6 +27,9 @@ type ProjectPane struct {
 	projectListStarting int // The index in list where project names starts
 	renameMode          bool             // 是否处于重命名模式
 	renameIndex         int              // 正在重命名的项目索引
+	archivedShown       bool             // Whether archived projects are listed
+	projectOfItem       map[int]int      // Index in projects for list items of projects
+	collapsed           map[int64]bool   // IDs of projects with hidden sub-projects
 }
 
 // NewProjectPane initializes
@@ -50,6 +42,7 @@ func NewProjectPane(repo repository.ProjectRepository) *ProjectPane {
 		repo:        repo,
 		renameMode:  false,
 		renameIndex: -1,
+		collapsed:   make(map[int64]bool),
 	}
 	
 	// 设置项目列表背景色和文字色
@@ -86,22 +79,70 @@ func NewProjectPane(repo repository.ProjectRepository) *ProjectPane {
 	return &pane
 }
 
+// addNewProject creates a project by the name typed in input.
+// A "Parent/Child" path creates it as a sub-project, creating the missing parents too.
 func (pane *ProjectPane) addNewProject() {
-	name := pane.newProject.GetText()
-	if len(name) < 3 {
+	titles := splitProjectPath(pane.newProject.GetText())
+	if len(titles) == 0 || len(titles[len(titles)-1]) < 3 {
 		statusBar.showForSeconds("[red::]Project name should be at least 3 character", 5)
 		return
 	}
+	name := titles[len(titles)-1]
+
+	defer recordAction("Add project " + name)()
+	var project model.Project
+	err := transactor.Transaction(func(projects repository.ProjectRepository, _ repository.TaskRepository) error {
+		project = model.Project{}
+		for i, title := range titles {
+			parentID := project.ID
+			if parent := pane.childProjectByTitle(parentID, title); parent != -1 && i < len(titles)-1 {
+				project = pane.projects[parent]
+				continue
+			}
+
+			var err error
+			if project, err = createProject(projects, title, parentID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 
-	project, err := pane.repo.Create(name, "")
 	if err != nil {
 		statusBar.showForSeconds("[red::]Failed to create Project:"+err.Error(), 5)
+		pane.loadListItems(false)
 	} else {
 		statusBar.showForSeconds(fmt.Sprintf("[yellow::]Project %s created. Press n to start adding new tasks.", name), 10)
-		pane.projects = append(pane.projects, project)
-		pane.addProjectToList(len(pane.projects)-1, true)
 		pane.newProject.SetText("")
+		delete(pane.collapsed, project.ParentID)
+		pane.loadListItems(false)
+		if idx := pane.indexOfProject(project.ID); idx != -1 {
+			pane.activateProject(idx)
+			pane.selectProject(project.ID)
+		}
+	}
+}
+
+// createProject creates a project under the given parent, or on top level if parentID is 0
+func createProject(repo repository.ProjectRepository, title string, parentID int64) (model.Project, error) {
+	project, err := repo.Create(title, "")
+	if err == nil && parentID != 0 {
+		err = repository.SetProjectParent(repo, &project, parentID)
+	}
+
+	return project, err
+}
+
+// childProjectByTitle finds index of a (not archived) project by title among the sub-projects of a parent
+func (pane *ProjectPane) childProjectByTitle(parentID int64, title string) int {
+	for i, project := range pane.projects {
+		if project.ParentID == parentID && project.Title == title && !project.Archived {
+			return i
+		}
 	}
+
+	return -1
 }
 
 func (pane *ProjectPane) addDynamicLists() {
@@ -110,18 +151,88 @@ func (pane *ProjectPane) addDynamicLists() {
 	pane.list.AddItem("  • Tomorrow", "", 0, func() { taskPane.LoadDynamicList("tomorrow") })
 	pane.list.AddItem("  • Upcoming", "", 0, func() { taskPane.LoadDynamicList("upcoming") })
 	pane.list.AddItem("  • Unscheduled", "", 0, func() { taskPane.LoadDynamicList("unscheduled") })
+	pane.list.AddItem("  • Ready", "", 0, func() { taskPane.LoadReadyTasks() })
+	pane.list.AddItem("  • Trash", "", 0, func() { trashPane.Show() })
+	pane.addTagList()
 }
 
-func (pane *ProjectPane) addProjectList() {
-	pane.addSection("Projects")
-	pane.projectListStarting = pane.list.GetItemCount()
+// addTagList lists all tags in use, with number of tasks labeled by each
+func (pane *ProjectPane) addTagList() {
+	tags, err := taskRepo.GetAllTags()
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not load Tags: "+err.Error(), 5)
+		return
+	}
+	if tags = pane.withoutArchivedTags(tags); len(tags) == 0 {
+		return
+	}
 
+	pane.list.AddItem("", "", 0, nil)
+	pane.addSection("Tags")
+	for _, tag := range tags {
+		pane.list.AddItem(fmt.Sprintf("  [#5FAFD7]#[-] %s [::d](%d)", tag.Tag, tag.Tasks), "", 0, func(tag string) func() {
+			return func() { taskPane.LoadTagTasks(tag) }
+		}(tag.Tag))
+	}
+}
+
+// refreshTags reloads the list to update tag counts, keeping current selection
+func (pane *ProjectPane) refreshTags() {
+	current := pane.list.GetCurrentItem()
+	pane.loadListItems(false)
+
+	if pane.activeProject == nil {
+		pane.list.SetCurrentItem(current)
+		return
+	}
+
+	if idx := pane.indexOfProject(pane.activeProject.ID); idx != -1 {
+		pane.activeProject = &pane.projects[idx]
+		pane.selectProject(pane.activeProject.ID)
+	}
+}
+
+// withoutArchivedTags discounts tasks of archived projects from tag counts, dropping tags used only by them
+func (pane *ProjectPane) withoutArchivedTags(tags []repository.TagCount) []repository.TagCount {
+	for _, project := range pane.projects {
+		if !project.Archived {
+			continue
+		}
+
+		tasks, err := taskRepo.GetAllByProject(project)
+		if err != nil {
+			continue
+		}
+		for _, archived := range repository.CountTags(tasks) {
+			for i := range tags {
+				if tags[i].Tag == archived.Tag {
+					tags[i].Tasks -= archived.Tasks
+				}
+			}
+		}
+	}
+
+	var result []repository.TagCount
+	for _, tag := range tags {
+		if tag.Tasks > 0 {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// loadProjects loads all projects, including archived ones
+func (pane *ProjectPane) loadProjects() {
 	var err error
-	pane.projects, err = pane.repo.GetAll()
-	if err != nil {
+	if pane.projects, err = pane.repo.GetAll(); err != nil {
 		statusBar.showForSeconds("Could not load Projects: "+err.Error(), 5)
-		return
 	}
+}
+
+func (pane *ProjectPane) addProjectList() {
+	pane.addSection("Projects")
+	pane.projectListStarting = pane.list.GetItemCount()
 
 	// 按年份分组显示项目
 	pane.addProjectsByYear()
@@ -129,6 +240,293 @@ func (pane *ProjectPane) addProjectList() {
 	pane.list.SetCurrentItem(2) // Keep "Today" selected on start
 }
 
+// addArchivedList adds the collapsible "Archived" section, listing archived projects when it is expanded
+func (pane *ProjectPane) addArchivedList() {
+	var archived []int
+	for i := range pane.projects {
+		if pane.projects[i].Archived {
+			archived = append(archived, i)
+		}
+	}
+	if len(archived) == 0 {
+		return
+	}
+
+	if last, _ := pane.list.GetItemText(pane.list.GetItemCount() - 1); last != "" {
+		pane.list.AddItem("", "", 0, nil)
+	}
+
+	arrow := "▸"
+	if pane.archivedShown {
+		arrow = "▾"
+	}
+	pane.list.AddItem(fmt.Sprintf("[gray]  Archived (%d) %s", len(archived), arrow), "", 0, pane.toggleArchivedList)
+	if !pane.archivedShown {
+		return
+	}
+
+	for _, i := range archived {
+		pane.list.AddItem("  [::d]▪ "+pane.projects[i].Title, "", 0, func(idx int) func() {
+			return func() { pane.activateProject(idx) }
+		}(i))
+		pane.projectOfItem[pane.list.GetItemCount()-1] = i
+	}
+}
+
+// toggleArchivedList expands or collapses the list of archived projects
+func (pane *ProjectPane) toggleArchivedList() {
+	current := pane.list.GetCurrentItem()
+	pane.archivedShown = !pane.archivedShown
+	pane.refreshTags()
+	pane.list.SetCurrentItem(current)
+}
+
+// moveProject swaps the project under cursor with its next (step 1) or previous (step -1) sibling in its section
+func (pane *ProjectPane) moveProject(step int) {
+	item := pane.list.GetCurrentItem()
+	idx, ok := pane.projectOfItem[item]
+	if !ok || pane.projects[idx].Archived {
+		return
+	}
+
+	project := pane.projects[idx]
+	other := -1
+	for i := item + step; other == -1; i += step {
+		j, ok := pane.projectOfItem[i]
+		if !ok || pane.projects[j].ID == project.ParentID {
+			return
+		}
+		if pane.projects[j].ParentID == project.ParentID {
+			other = j
+		}
+	}
+
+	neighbour := pane.projects[other]
+	defer recordAction("Move project " + project.Title)()
+	err := transactor.Transaction(func(projects repository.ProjectRepository, _ repository.TaskRepository) error {
+		if err := projects.UpdateField(&project, "Position", neighbour.Position); err != nil {
+			return err
+		}
+
+		return projects.UpdateField(&neighbour, "Position", pane.projects[idx].Position)
+	})
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not move project: "+err.Error(), 5)
+	}
+
+	pane.refreshTags()
+	for i := item; i >= 0 && i < pane.list.GetItemCount(); i += step {
+		if j, ok := pane.projectOfItem[i]; ok && pane.projects[j].ID == project.ID {
+			pane.list.SetCurrentItem(i)
+			return
+		}
+	}
+}
+
+// toggleCollapse hides or shows sub-projects of the project under cursor
+func (pane *ProjectPane) toggleCollapse() {
+	item := pane.list.GetCurrentItem()
+	idx, ok := pane.projectOfItem[item]
+	if !ok || !pane.hasSubProjects(pane.projects[idx].ID) {
+		return
+	}
+
+	id := pane.projects[idx].ID
+	pane.collapsed[id] = !pane.collapsed[id]
+	pane.refreshTags()
+	pane.list.SetCurrentItem(item)
+}
+
+// hasSubProjects tells if a project has any sub-project which is not archived
+func (pane *ProjectPane) hasSubProjects(id int64) bool {
+	for _, project := range pane.projects {
+		if project.ParentID == id && !project.Archived {
+			return true
+		}
+	}
+
+	return false
+}
+
+// indexOfProject provides index of a project in pane.projects by ID, -1 if it is not loaded
+func (pane *ProjectPane) indexOfProject(id int64) int {
+	for i := range pane.projects {
+		if id != 0 && pane.projects[i].ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// projectPath makes the "Parent/Child" path of a project, starting below the ancestor with ID from.
+// The path starts from top level if from is 0.
+func (pane *ProjectPane) projectPath(id, from int64) string {
+	var titles []string
+	for depth := 0; id != from && depth <= len(pane.projects); depth++ {
+		idx := pane.indexOfProject(id)
+		if idx == -1 {
+			break
+		}
+		titles = append([]string{pane.projects[idx].Title}, titles...)
+		id = pane.projects[idx].ParentID
+	}
+
+	return strings.Join(titles, "/")
+}
+
+// findProjectByPath finds a (not archived) project by its "Parent/Child" path.
+// Leading parents may be left out, as long as only one project matches the rest of the path.
+func (pane *ProjectPane) findProjectByPath(path string) (int, error) {
+	path = strings.Join(splitProjectPath(path), "/")
+
+	found := -1
+	for i, project := range pane.projects {
+		if project.Archived {
+			continue
+		}
+
+		fullPath := pane.projectPath(project.ID, 0)
+		if fullPath == path {
+			return i, nil
+		}
+		if strings.HasSuffix(fullPath, "/"+path) {
+			if found != -1 {
+				return -1, errors.New("more than one project at " + path + ", type the full path")
+			}
+			found = i
+		}
+	}
+
+	if found == -1 {
+		return -1, errors.New("no project at " + path)
+	}
+
+	return found, nil
+}
+
+// splitProjectPath splits a "Parent/Child" path into project titles, dropping empty ones
+func splitProjectPath(path string) []string {
+	var titles []string
+	for _, title := range strings.Split(path, "/") {
+		if title = strings.TrimSpace(title); title != "" {
+			titles = append(titles, title)
+		}
+	}
+
+	return titles
+}
+
+// SetActiveProjectParent moves the active project under the project at path, or to top level if path is empty
+func (pane *ProjectPane) SetActiveProjectParent(path string) {
+	if pane.activeProject == nil {
+		return
+	}
+
+	var parentID int64
+	if strings.TrimSpace(path) != "" {
+		parent, err := pane.findProjectByPath(path)
+		if err != nil {
+			statusBar.showForSeconds("[red::]Could not move project: "+err.Error(), 5)
+			return
+		}
+		parentID = pane.projects[parent].ID
+	}
+
+	project := *pane.activeProject
+	if project.ParentID == parentID {
+		return
+	}
+
+	defer recordAction("Move project " + project.Title)()
+	if err := repository.SetProjectParent(pane.repo, &project, parentID); err != nil {
+		statusBar.showForSeconds("[red::]Could not move project: "+err.Error(), 5)
+		return
+	}
+
+	delete(pane.collapsed, parentID)
+	pane.refreshTags()
+	projectDetailPane.SetProject(pane.activeProject)
+	if parentID == 0 {
+		statusBar.showForSeconds("[yellow::]Moved "+project.Title+" to top level", 5)
+	} else {
+		statusBar.showForSeconds("[yellow::]Moved "+project.Title+" under "+pane.projectPath(parentID, 0), 5)
+	}
+}
+
+// sortByProjects orders a priority-first list of tasks from many projects by the manual order of their projects,
+// keeping the order of tasks within each project
+func (pane *ProjectPane) sortByProjects(tasks []model.Task) {
+	rank := make(map[int64]int)
+	for i, project := range pane.projects {
+		rank[project.ID] = i
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].Priority != tasks[j].Priority {
+			return tasks[i].Priority > tasks[j].Priority
+		}
+
+		return rank[tasks[i].ProjectID] < rank[tasks[j].ProjectID]
+	})
+}
+
+// isArchived tells if a project is archived
+func (pane *ProjectPane) isArchived(projectID int64) bool {
+	if idx := pane.indexOfProject(projectID); idx != -1 {
+		return pane.projects[idx].Archived
+	}
+
+	return false
+}
+
+// withoutArchived filters out tasks of archived projects
+func (pane *ProjectPane) withoutArchived(tasks []model.Task) []model.Task {
+	var result []model.Task
+	for _, task := range tasks {
+		if !pane.isArchived(task.ProjectID) {
+			result = append(result, task)
+		}
+	}
+
+	return result
+}
+
+// SetActiveProjectArchived archives or unarchives the active project, which stays active
+func (pane *ProjectPane) SetActiveProjectArchived(archived bool) {
+	if pane.activeProject == nil || pane.activeProject.Archived == archived {
+		return
+	}
+
+	project := *pane.activeProject
+	if archived {
+		defer recordAction("Archive project " + project.Title)()
+		project.Working = false
+	} else {
+		defer recordAction("Unarchive project " + project.Title)()
+	}
+	project.Archived = archived
+
+	if err := pane.repo.Update(&project); err != nil {
+		statusBar.showForSeconds("[red::]Could not update project: "+err.Error(), 5)
+		return
+	}
+
+	if archived {
+		pane.archivedShown = true
+		timeTracker.Check()
+		statusBar.showForSeconds("[yellow::]Archived project "+project.Title, 5)
+	} else {
+		statusBar.showForSeconds("[yellow::]Unarchived project "+project.Title, 5)
+	}
+
+	pane.loadListItems(false)
+	if idx := pane.indexOfProject(project.ID); idx != -1 {
+		pane.activateProject(idx)
+		pane.selectProject(project.ID)
+	}
+}
+
 // getProjectYears 获取项目的年份信息
 func (pane *ProjectPane) getProjectYears(project model.Project) []int {
 	tasks, err := taskRepo.GetAllByProject(project)
@@ -153,20 +551,39 @@ func (pane *ProjectPane) getProjectYears(project model.Project) []int {
 }
 
 // addProjectsByYear 按年份分组添加项目
+// A project is listed in the years of its own tasks and the tasks of its sub-projects
 func (pane *ProjectPane) addProjectsByYear() {
+	ownYears := make(map[int64][]int)
+	for _, project := range pane.projects {
+		if !project.Archived {
+			ownYears[project.ID] = pane.getProjectYears(project)
+		}
+	}
+
 	// 收集所有项目的年份信息
-	yearGroups := make(map[int][]ProjectWithIndex)
-	defaultProjects := make([]ProjectWithIndex, 0)
+	yearGroups := make(map[int]map[int64]bool)
+	defaultProjects := make(map[int64]bool)
+
+	for _, project := range pane.projects {
+		if project.Archived {
+			continue
+		}
+
+		years := append([]int(nil), ownYears[project.ID]...)
+		for _, sub := range repository.ProjectSubtree(pane.projects, project.ID) {
+			years = append(years, ownYears[sub.ID]...)
+		}
 
-	for i, project := range pane.projects {
-		years := pane.getProjectYears(project)
 		if len(years) == 0 {
 			// 没有任务或所有任务都没有日期
-			defaultProjects = append(defaultProjects, ProjectWithIndex{project, i})
+			defaultProjects[project.ID] = true
 		} else {
 			// 为每个年份添加项目
 			for _, year := range years {
-				yearGroups[year] = append(yearGroups[year], ProjectWithIndex{project, i})
+				if yearGroups[year] == nil {
+					yearGroups[year] = make(map[int64]bool)
+				}
+				yearGroups[year][project.ID] = true
 			}
 		}
 	}
@@ -181,42 +598,73 @@ func (pane *ProjectPane) addProjectsByYear() {
 	// 首先显示默认组
 	if len(defaultProjects) > 0 {
 		pane.addSection("Default")
-		for _, projectWithIndex := range defaultProjects {
-			pane.addProjectToList(projectWithIndex.Index, false)
-		}
+		pane.addProjectTree(defaultProjects)
 		pane.list.AddItem("", "", 0, nil) // 空行分隔
 	}
 
 	// 按年份显示项目
 	for _, year := range allYears {
 		pane.addSection(fmt.Sprintf("%d", year))
-		for _, projectWithIndex := range yearGroups[year] {
-			pane.addProjectToList(projectWithIndex.Index, false)
-		}
+		pane.addProjectTree(yearGroups[year])
 		if year != allYears[len(allYears)-1] { // 不是最后一个年份
 			pane.list.AddItem("", "", 0, nil) // 空行分隔
 		}
 	}
 }
 
-func (pane *ProjectPane) addProjectToList(i int, selectItem bool) {
+// addProjectTree lists projects of a section as a tree, with sub-projects indented under their parents.
+// Projects whose parent is not in the section are listed on top level, along with the path of their parent.
+func (pane *ProjectPane) addProjectTree(inSection map[int64]bool) {
+	for i, project := range pane.projects {
+		if inSection[project.ID] && !inSection[project.ParentID] {
+			pane.addProjectSubtree(i, 0, inSection)
+		}
+	}
+}
+
+// addProjectSubtree adds a project in list, followed by its sub-projects in the section unless it is collapsed
+func (pane *ProjectPane) addProjectSubtree(i, depth int, inSection map[int64]bool) {
+	var children []int
+	for j, project := range pane.projects {
+		if project.ParentID == pane.projects[i].ID && inSection[project.ID] {
+			children = append(children, j)
+		}
+	}
+
+	pane.addProjectToList(i, depth, len(children) > 0)
+	if pane.collapsed[pane.projects[i].ID] || depth >= len(pane.projects) {
+		return
+	}
+	for _, child := range children {
+		pane.addProjectSubtree(child, depth+1, inSection)
+	}
+}
+
+func (pane *ProjectPane) addProjectToList(i, depth int, hasChildren bool) {
 	// To avoid overriding of loop variables - https://www.calhoun.io/gotchas-and-common-mistakes-with-closures-in-go/
 	// 根据项目工作状态选择显示符号
+	indent := strings.Repeat("  ", depth)
 	var symbol string
 	if pane.projects[i].Working {
-		symbol = "  [orange]★[-] " // 橙色星号表示正在工作，[-]恢复默认颜色，添加空格间距
+		symbol = "  " + indent + "[orange]★[-] " // 橙色星号表示正在工作，[-]恢复默认颜色，添加空格间距
 	} else {
-		symbol = "  • " // 普通圆点，添加空格间距
+		symbol = "  " + indent + "• " // 普通圆点，添加空格间距
 	}
-	
-	pane.list.AddItem(symbol+pane.projects[i].Title, "", 0, func(idx int) func() {
-		return func() { pane.activateProject(idx) }
-	}(i))
 
-	if selectItem {
-		pane.list.SetCurrentItem(-1)
-		pane.activateProject(i)
+	title := pane.projects[i].Title
+	if parent := pane.projectPath(pane.projects[i].ParentID, 0); depth == 0 && parent != "" {
+		title = "[::d]" + parent + "/[::-]" + title
 	}
+	if hasChildren && pane.collapsed[pane.projects[i].ID] {
+		title += " [gray]▸"
+	} else if hasChildren {
+		title += " [gray]▾"
+	}
+
+	pane.list.AddItem(symbol+title, "", 0, func(idx int) func() {
+		return func() { pane.activateProject(idx) }
+	}(i))
+	pane.projectOfItem[pane.list.GetItemCount()-1] = i
 }
 
 func (pane *ProjectPane) addSection(name string) {
@@ -235,8 +683,8 @@ func (pane *ProjectPane) addSection(name string) {
 		pane.list.AddItem("[gray]  "+name, "", 0, nil)
 	}
 	
-	// 只有Dynamic Lists和Projects需要横线，年份组不需要
-	if name == "Dynamic Lists" || name == "Projects" {
+	// 只有Dynamic Lists、Tags和Projects需要横线，年份组不需要
+	if name == "Dynamic Lists" || name == "Tags" || name == "Projects" {
 		pane.list.AddItem("[gray]  "+strings.Repeat("─", 25), "", 0, nil)
 	}
 }
@@ -247,6 +695,15 @@ func (pane *ProjectPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey
 		return event
 	}
 
+	switch event.Rune() {
+	case 'J':
+		pane.moveProject(1)
+		return nil
+	case 'K':
+		pane.moveProject(-1)
+		return nil
+	}
+
 	switch unicode.ToLower(event.Rune()) {
 	case 'j':
 		pane.list.SetCurrentItem(pane.list.GetCurrentItem() + 1)
@@ -263,6 +720,9 @@ func (pane *ProjectPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey
 	case 'b':
 		pane.toggleWorkingStatus()
 		return nil
+	case 'z':
+		pane.toggleCollapse()
+		return nil
 	}
 
 	return event
@@ -279,27 +739,39 @@ func (pane *ProjectPane) activateProject(idx int) {
 	app.SetFocus(pane)
 }
 
-// RemoveActivateProject deletes the currently active project
+// RemoveActivateProject moves the currently active project to Trash, along with its tasks
 func (pane *ProjectPane) RemoveActivateProject() {
-	if pane.activeProject != nil && pane.repo.Delete(pane.activeProject) == nil {
+	if pane.activeProject == nil {
+		return
+	}
 
-		for i := range taskPane.tasks {
-			_ = taskRepo.Delete(&taskPane.tasks[i])
-		}
-		taskPane.ClearList()
+	defer recordAction("Move project " + pane.activeProject.Title + " to Trash")()
+	project := *pane.activeProject
+	err := transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		return repository.TrashProject(projects, tasks, &project)
+	})
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not remove project: "+err.Error(), 5)
+		return
+	}
+	taskPane.ClearList()
+	timeTracker.Check()
 
-		statusBar.showForSeconds("[lime]Removed Project: "+pane.activeProject.Title, 5)
-		removeThirdCol()
+	statusBar.showForSeconds("[lime]Moved Project to Trash: "+pane.activeProject.Title, 5)
+	removeThirdCol()
+	pane.activeProject = nil
 
-		pane.loadListItems(true)
-	}
+	pane.loadListItems(true)
 }
 
 func (pane *ProjectPane) loadListItems(focus bool) {
 	pane.list.Clear()
+	pane.projectOfItem = make(map[int]int)
+	pane.loadProjects()
 	pane.addDynamicLists()
 	pane.list.AddItem("", "", 0, nil)
 	pane.addProjectList()
+	pane.addArchivedList()
 
 	if focus {
 		app.SetFocus(pane)
@@ -357,6 +829,7 @@ func (pane *ProjectPane) finishRenaming() {
 	}
 	
 	// 更新项目名称
+	defer recordAction("Rename project " + pane.projects[pane.renameIndex].Title)()
 	pane.projects[pane.renameIndex].Title = newName
 	err := pane.repo.Update(&pane.projects[pane.renameIndex])
 	if err != nil {
@@ -372,7 +845,7 @@ func (pane *ProjectPane) finishRenaming() {
 	pane.loadListItems(true)
 	
 	// 选中刚才重命名的项目
-	pane.selectProjectByName(newName)
+	pane.selectProject(pane.projects[pane.renameIndex].ID)
 	pane.exitRenameMode()
 }
 
@@ -395,62 +868,26 @@ func (pane *ProjectPane) exitRenameMode() {
 
 // findProjectIndexByListItem 根据列表项索引找到对应的项目索引
 func (pane *ProjectPane) findProjectIndexByListItem(listIndex int) int {
-	// 获取当前选中项的文本
-	main, _ := pane.list.GetItemText(listIndex)
-	
-	// 移除调试信息
-	
-	// 简化检查：只要包含项目符号就认为是项目
-	if !strings.Contains(main, "•") && !strings.Contains(main, "⚡") && !strings.Contains(main, "·") && !strings.Contains(main, "★") {
+	idx, ok := pane.projectOfItem[listIndex]
+	if !ok {
 		statusBar.showForSeconds("[red::]Please select a project (not a section or empty line)", 3)
 		return -1
 	}
-	
-	// 提取项目名称，通过找到符号位置来提取（考虑符号后可能有空格）
-	var projectName string
-	if idx := strings.Index(main, "★"); idx != -1 {
-		projectName = strings.TrimSpace(main[idx+len("★"):])
-	} else if idx := strings.Index(main, "⚡"); idx != -1 {
-		projectName = strings.TrimSpace(main[idx+len("⚡"):])
-	} else if idx := strings.Index(main, "•"); idx != -1 {
-		projectName = strings.TrimSpace(main[idx+len("•"):])
-	} else if idx := strings.Index(main, "·"); idx != -1 {
-		projectName = strings.TrimSpace(main[idx+len("·"):])
-	}
-	
-	// 在项目列表中查找匹配的项目
-	for j, project := range pane.projects {
-		if project.Title == projectName {
-			return j
-		}
+
+	if pane.projects[idx].Archived {
+		statusBar.showForSeconds("[yellow::]Archived projects are read-only. Unarchive it first", 3)
+		return -1
 	}
-	
-	statusBar.showForSeconds("[red::]Project not found", 3)
-	return -1
+
+	return idx
 }
 
-// selectProjectByName 在列表中选中指定名称的项目
-func (pane *ProjectPane) selectProjectByName(projectName string) {
-	// 遍历列表项，寻找匹配的项目名称
+// selectProject 在列表中选中指定的项目
+func (pane *ProjectPane) selectProject(id int64) {
 	for i := 0; i < pane.list.GetItemCount(); i++ {
-		main, _ := pane.list.GetItemText(i)
-		
-		// 检查是否是项目项且名称匹配（考虑不同的符号格式）
-		if (strings.Contains(main, "•") || strings.Contains(main, "⚡") || strings.Contains(main, "·") || strings.Contains(main, "★")) && i >= pane.projectListStarting {
-			var itemProjectName string
-			if idx := strings.Index(main, "★"); idx != -1 {
-				itemProjectName = strings.TrimSpace(main[idx+len("★"):])
-			} else if idx := strings.Index(main, "⚡"); idx != -1 {
-				itemProjectName = strings.TrimSpace(main[idx+len("⚡"):])
-			} else if idx := strings.Index(main, "•"); idx != -1 {
-				itemProjectName = strings.TrimSpace(main[idx+len("•"):])
-			} else if idx := strings.Index(main, "·"); idx != -1 {
-				itemProjectName = strings.TrimSpace(main[idx+len("·"):])
-			}
-			if itemProjectName == projectName {
-				pane.list.SetCurrentItem(i)
-				return
-			}
+		if idx, ok := pane.projectOfItem[i]; ok && pane.projects[idx].ID == id {
+			pane.list.SetCurrentItem(i)
+			return
 		}
 	}
 }
@@ -467,39 +904,58 @@ func (pane *ProjectPane) toggleWorkingStatus() {
 		return
 	}
 	
-	// 如果要设置为工作状态，先清除其他项目的工作状态（确保只有一个项目处于工作中）
-	if !pane.projects[projectIndex].Working {
-		for i := range pane.projects {
-			if pane.projects[i].Working {
-				pane.projects[i].Working = false
-				err := pane.repo.Update(&pane.projects[i])
-				if err != nil {
-					statusBar.showForSeconds("[red::]Failed to update project: "+err.Error(), 5)
-					return
-				}
-			}
-		}
+	defer recordAction("Change working project")()
+
+	// 确保只有一个项目处于工作中
+	working := projectIndex
+	if pane.projects[projectIndex].Working {
+		working = -1
 	}
-	
-	// 切换当前项目的工作状态
-	pane.projects[projectIndex].Working = !pane.projects[projectIndex].Working
-	err := pane.repo.Update(&pane.projects[projectIndex])
+	err := transactor.Transaction(func(projects repository.ProjectRepository, _ repository.TaskRepository) error {
+		return pane.saveWorkingProject(projects, working)
+	})
 	if err != nil {
 		statusBar.showForSeconds("[red::]Failed to update project: "+err.Error(), 5)
 		return
 	}
+	pane.markWorkingProject(working)
 	
 	// 显示状态消息
-	projectName := pane.projects[projectIndex].Title
+	projectID, projectName := pane.projects[projectIndex].ID, pane.projects[projectIndex].Title
 	if pane.projects[projectIndex].Working {
 		statusBar.showForSeconds(fmt.Sprintf("[green::]'%s' is now your working project ⚡", projectName), 5)
 	} else {
 		statusBar.showForSeconds(fmt.Sprintf("[yellow::]'%s' is no longer your working project", projectName), 5)
 	}
+	// 切换工作项目时停止其他项目任务的计时
+	timeTracker.Check()
 	
 	// 重新加载列表并保持选中当前项目
 	pane.loadListItems(true)
-	pane.selectProjectByName(projectName)
+	pane.selectProject(projectID)
+}
+
+// saveWorkingProject saves a project as the only working one, or none of them if idx is -1.
+// Listed projects are left unchanged, see markWorkingProject.
+func (pane *ProjectPane) saveWorkingProject(repo repository.ProjectRepository, idx int) error {
+	for i := range pane.projects {
+		if working := i == idx; pane.projects[i].Working != working {
+			project := pane.projects[i]
+			project.Working = working
+			if err := repo.Update(&project); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// markWorkingProject marks a listed project as the only working one, once it is saved by saveWorkingProject
+func (pane *ProjectPane) markWorkingProject(idx int) {
+	for i := range pane.projects {
+		pane.projects[i].Working = i == idx
+	}
 }
 
 // loadTasksByYear 按年份加载所有相关任务
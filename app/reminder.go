@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// reminderCheckInterval is how often pending reminders are looked for
+const reminderCheckInterval = 30 * time.Second
+
+// snoozeOptions are the buttons of a reminder, with how long each one snoozes it for
+var snoozeOptions = []struct {
+	label    string
+	duration time.Duration
+}{
+	{"5 min", 5 * time.Minute},
+	{"15 min", 15 * time.Minute},
+	{"1 hour", time.Hour},
+	{"Tomorrow", 24 * time.Hour},
+}
+
+const dismissReminder = "Dismiss"
+
+// watchReminders checks for pending reminders periodically, until the app stops
+func watchReminders() {
+	ticker := time.NewTicker(reminderCheckInterval)
+	for ; true; <-ticker.C {
+		app.QueueUpdateDraw(checkReminders)
+	}
+}
+
+// checkReminders pops up the earliest pending reminder, unless a modal (maybe another reminder) is open
+func checkReminders() {
+	if modalOpen {
+		return
+	}
+
+	tasks, err := repository.PendingReminders(taskRepo, time.Now())
+	if err != nil || len(tasks) == 0 {
+		return
+	}
+
+	showReminder(tasks[0])
+}
+
+// showReminder pops up reminder of a task, asking to snooze or dismiss it
+func showReminder(task model.Task) {
+	buttons := make([]string, 0, len(snoozeOptions)+1)
+	for _, option := range snoozeOptions {
+		buttons = append(buttons, option.label)
+	}
+	buttons = append(buttons, dismissReminder)
+
+	text := "⏰ " + task.Title + "\n\nDue " + repository.DueAt(task).Format(dateLayoutHuman+", 15:04")
+	if project, err := projectRepo.GetByID(task.ProjectID); err == nil {
+		text += " in " + project.Title
+	}
+
+	statusBar.ringBell()
+	AskChoice(text+"\n\nSnooze for:", buttons, func(label string) {
+		snoozeReminder(task, label)
+	})
+}
+
+// snoozeReminder postpones reminder of a task by the chosen snooze option, or dismisses it for any other choice
+func snoozeReminder(task model.Task, label string) {
+	var at int64
+	for _, option := range snoozeOptions {
+		if option.label == label {
+			at = time.Now().Add(option.duration).Unix()
+		}
+	}
+
+	// Reload the task, in case it was changed while reminder was shown
+	task, err := taskRepo.GetByID(strconv.FormatInt(task.ID, 10))
+	if err == nil {
+		err = repository.SetReminder(taskRepo, &task, at)
+	}
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not update reminder: "+err.Error(), 5)
+		return
+	}
+
+	if at != 0 {
+		statusBar.showForSeconds("[yellow::]Reminder snoozed until "+time.Unix(at, 0).Format("Jan 02 15:04"), 5)
+	}
+	if taskDetailPane.task != nil && taskDetailPane.task.ID == task.ID {
+		taskDetailPane.task.RemindAt = task.RemindAt
+		taskDetailPane.setReminderDisplay()
+	}
+}
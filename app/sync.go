@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/ajaxray/geek-life/caldav"
+	"github.com/ajaxray/geek-life/filesync"
+	"github.com/ajaxray/geek-life/util"
+)
+
+// syncFiles exchanges changes with other replicas through a shared directory, given as argument or by --sync-dir.
+// Conflicts are printed and appended to a report next to the DB file.
+func syncFiles() {
+	dir := syncDir
+	if flag.NArg() > 1 {
+		dir = flag.Arg(1)
+	}
+	if dir == "" {
+		fmt.Println("Please mention the shared directory to sync through, e.g. geek-life sync ~/Sync/geek-life")
+		os.Exit(1)
+	}
+
+	path := databasePath()
+	result, err := filesync.New(transactor, dir, path+".sync.json").Sync(time.Now())
+	util.FatalIfError(err, "Error in syncing through %s", dir)
+
+	fmt.Printf("Synced through %s: %d changes exported, %d imported.\n", dir, result.Exported, result.Imported)
+	if len(result.Conflicts) == 0 {
+		return
+	}
+
+	report := filesync.Report(result.Conflicts)
+	fmt.Printf("%d conflicts, resolved by keeping the latest change:\n%s\n", len(result.Conflicts), report)
+
+	reportPath := path + ".sync-conflicts.txt"
+	f, err := os.OpenFile(reportPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if !util.LogIfError(err, "Could not write conflict report") {
+		defer f.Close()
+		_, err = fmt.Fprintf(f, "## %s\n%s\n\n", time.Now().Format("2006-01-02 15:04"), report)
+		if !util.LogIfError(err, "Could not write conflict report") {
+			fmt.Println("Conflicts are also listed in " + reportPath)
+		}
+	}
+}
+
+// databasePath finds the file of connected database, to keep sync state next to it
+func databasePath() string {
+	if db != nil {
+		return db.Bolt.Path()
+	}
+
+	var seq int
+	var name, file string
+	util.FatalIfError(sqlDB.QueryRow("PRAGMA database_list").Scan(&seq, &name, &file), "Could not find database file")
+	return file
+}
+
+// syncCalDAV syncs projects and tasks with the calendar home on a CalDAV server, given by --caldav-url
+func syncCalDAV() {
+	if caldavURL == "" {
+		fmt.Println("Please mention your calendar home by --caldav-url or CALDAV_URL, e.g. https://dav.example.com/calendars/me/")
+		os.Exit(1)
+	}
+
+	client, err := caldav.NewClient(caldavURL, caldavUser, caldavPassword)
+	util.FatalIfError(err, "Invalid CalDAV URL")
+
+	// Changes are saved task by task, so running it again after an error continues where it stopped
+	result, err := caldav.New(client, transactor).Sync(time.Now())
+	util.FatalIfError(err, "Error in syncing with CalDAV server")
+
+	fmt.Printf("Synced with %s: %d tasks uploaded, %d downloaded, %d deleted.\n", caldavURL, result.Uploaded, result.Downloaded, result.Deleted)
+	for _, conflict := range result.Conflicts {
+		fmt.Println("  " + conflict.String())
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 	"unicode"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/rivo/tview"
 
 	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/recurrence"
 	"github.com/ajaxray/geek-life/repository"
 	"github.com/ajaxray/geek-life/util"
 )
@@ -28,13 +30,30 @@ type TaskDetailPane struct {
 	*tview.Flex
 	header           *TaskDetailHeader
 	taskDateDisplay  *tview.TextView
+	taskPriority     *tview.TextView
+	taskRepeatInfo   *tview.TextView
+	taskReminderInfo *tview.TextView
+	taskStartInfo    *tview.TextView
+	taskBlockersInfo *tview.TextView
+	taskTimestamps   *tview.TextView
+	taskTracked      *tview.TextView
+	taskEffortInfo   *tview.TextView
+	taskHistory      *tview.TextView
 	editorHint       *tview.TextView
 	taskDate         *tview.InputField
+	taskTags         *tview.InputField
+	taskRepeat       *tview.InputField
+	taskReminder     *tview.InputField
+	taskStart        *tview.InputField
+	taskBlockedBy    *tview.InputField
+	taskEstimate     *tview.InputField
+	taskSpent        *tview.InputField
 	taskStatusToggle *tview.Button
 	taskDetailView   *femto.View
 	colorScheme      femto.Colorscheme
 	taskRepo         repository.TaskRepository
 	task             *model.Task
+	historyShown     bool
 }
 
 // NewTaskDetailPane initializes and configures a TaskDetailPane
@@ -43,11 +62,20 @@ func NewTaskDetailPane(taskRepo repository.TaskRepository) *TaskDetailPane {
 		Flex:             tview.NewFlex().SetDirection(tview.FlexRow),
 		header:           NewTaskDetailHeader(taskRepo),
 		taskDateDisplay:  tview.NewTextView().SetDynamicColors(true),
+		taskPriority:     tview.NewTextView().SetDynamicColors(true),
+		taskRepeatInfo:   tview.NewTextView().SetDynamicColors(true),
+		taskReminderInfo: tview.NewTextView().SetDynamicColors(true),
+		taskStartInfo:    tview.NewTextView().SetDynamicColors(true),
+		taskBlockersInfo: tview.NewTextView().SetDynamicColors(true),
+		taskTimestamps:   tview.NewTextView().SetDynamicColors(true),
+		taskTracked:      tview.NewTextView().SetDynamicColors(true),
+		taskEffortInfo:   tview.NewTextView().SetDynamicColors(true),
 		taskStatusToggle: tview.NewButton("Complete"),
 		taskRepo:         taskRepo,
 	}
 
 	pane.prepareDetailsEditor()
+	pane.taskHistory = pane.makeHistoryView()
 
 	toggleHint := tview.NewTextView().SetTextColor(tcell.ColorDimGray).SetText("<space> to toggle")
 	// 初始化按钮样式和事件
@@ -81,10 +109,20 @@ func NewTaskDetailPane(taskRepo repository.TaskRepository) *TaskDetailPane {
 		AddItem(pane.header, 4, 1, true).
 		AddItem(blankCell, 1, 1, false).
 		AddItem(pane.makeDateRow(), 1, 1, true).
+		AddItem(pane.makeStartRow(), 1, 1, false).
+		AddItem(pane.makeRepeatRow(), 1, 1, false).
+		AddItem(pane.makeReminderRow(), 1, 1, false).
+		AddItem(pane.makePriorityRow(), 1, 1, false).
+		AddItem(pane.makeTagsRow(), 1, 1, false).
+		AddItem(pane.makeBlockersRow(), 1, 1, false).
+		AddItem(pane.makeTimestampsRow(), 1, 1, false).
+		AddItem(pane.makeTrackedRow(), 1, 1, false).
+		AddItem(pane.makeEffortRow(), 1, 1, false).
 		AddItem(blankCell, 1, 1, false).
 		AddItem(editorLabel, 1, 1, false).
 		AddItem(pane.taskDetailView, 15, 4, false).
 		AddItem(editorHelp, 1, 1, false).
+		AddItem(pane.taskHistory, 0, 0, false).
 		AddItem(blankCell, 0, 1, false).
 		AddItem(toggleHint, 1, 1, false).
 		AddItem(pane.taskStatusToggle, 3, 1, false)
@@ -101,6 +139,18 @@ func (td *TaskDetailPane) Export() {
 	if td.taskDate.GetText() != "" {
 		content.WriteString("\n> Due Date: " + td.taskDate.GetText() + " \n")
 	}
+	if rule, err := recurrence.Parse(td.task.Recurrence); err == nil {
+		content.WriteString("\n> Repeats: " + rule.Describe() + " \n")
+	}
+	if td.task.Priority != model.PriorityNone {
+		content.WriteString("\n> Priority: " + td.task.Priority.String() + " \n")
+	}
+	if len(td.task.Tags) > 0 {
+		content.WriteString("\n> Tags: " + model.FormatTags(td.task.Tags) + " \n")
+	}
+	if blockers, err := repository.OpenBlockers(td.taskRepo, *td.task); err == nil && len(blockers) > 0 {
+		content.WriteString("\n> Blocked by: " + joinTaskTitles(blockers) + " \n")
+	}
 	content.WriteString("\n" + td.task.Details + " \n")
 
 	_ = clipboard.WriteAll(content.String())
@@ -112,17 +162,17 @@ func (td *TaskDetailPane) makeDateRow() *tview.Flex {
 
 	// 为日期输入框创建特殊样式
 	td.taskDate = tview.NewInputField().
-		SetPlaceholder("yyyy-mm-dd").
+		SetPlaceholder("yyyy-mm-dd [hh:mm]").
 		SetLabel("Set:").
 		SetLabelColor(tcell.ColorWhiteSmoke).
-		SetFieldWidth(12).
+		SetFieldWidth(18).
 		SetFieldTextColor(tcell.ColorWhite).
 		SetFieldBackgroundColor(tcell.ColorDarkSlateGray).  // 使用深灰色，不影响可见性
 		SetDoneFunc(func(key tcell.Key) {
 			switch key {
 			case tcell.KeyEnter:
-				date := parseDateInputOrCurrent(td.taskDate.GetText())
-				td.setTaskDate(date.Unix(), true)
+				date, clock := parseDueInput(td.taskDate.GetText())
+				td.setTaskDue(date.Unix(), clock)
 			case tcell.KeyEsc:
 				td.setTaskDate(td.task.DueDate, false)
 			}
@@ -131,7 +181,7 @@ func (td *TaskDetailPane) makeDateRow() *tview.Flex {
 
 	return tview.NewFlex().
 		AddItem(td.taskDateDisplay, 0, 2, true).
-		AddItem(td.taskDate, 14, 0, true).
+		AddItem(td.taskDate, 22, 0, true).
 		AddItem(blankCell, 1, 0, false).
 		AddItem(makeButton("today", td.todaySelector), 8, 1, false).
 		AddItem(blankCell, 1, 0, false).
@@ -140,6 +190,479 @@ func (td *TaskDetailPane) makeDateRow() *tview.Flex {
 		AddItem(makeButton("-1", td.prevDaySelector), 4, 1, false)
 }
 
+func (td *TaskDetailPane) makeRepeatRow() *tview.Flex {
+	td.taskRepeat = tview.NewInputField().
+		SetPlaceholder("e,g, weekly or FREQ=MONTHLY;BYDAY=1MO").
+		SetFieldTextColor(tcell.ColorWhite).
+		SetFieldBackgroundColor(tcell.ColorDarkSlateGray).
+		SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				td.setRecurrence(td.taskRepeat.GetText())
+			case tcell.KeyEsc:
+				td.setRecurrenceDisplay()
+			}
+			app.SetFocus(td)
+		})
+
+	return tview.NewFlex().
+		AddItem(td.taskRepeatInfo, 0, 1, false).
+		AddItem(td.taskRepeat, 0, 1, false)
+}
+
+// setRecurrence parses and saves the repeat rule of task. Empty text stops repeating.
+func (td *TaskDetailPane) setRecurrence(text string) {
+	if !taskPane.canEdit() {
+		return
+	}
+
+	value := ""
+	if strings.TrimSpace(text) != "" {
+		rule, err := recurrence.Parse(text)
+		if err != nil {
+			statusBar.showForSeconds("[red::]"+err.Error(), 5)
+			td.setRecurrenceDisplay()
+			return
+		}
+
+		var due time.Time
+		if td.task.DueDate != 0 {
+			due = time.Unix(td.task.DueDate, 0)
+		}
+		value = rule.WithDefaults(due).String()
+	}
+
+	defer recordAction("Change repeat rule of " + td.task.Title)()
+	if err := td.taskRepo.UpdateField(td.task, "Recurrence", value); err != nil {
+		statusBar.showForSeconds("[red::]Could not update repeat rule: "+err.Error(), 5)
+		return
+	}
+
+	td.task.Recurrence = value
+	td.setRecurrenceDisplay()
+	taskPane.ReloadCurrentTask()
+}
+
+func (td *TaskDetailPane) setRecurrenceDisplay() {
+	td.taskRepeat.SetText(td.task.Recurrence)
+
+	rule, err := recurrence.Parse(td.task.Recurrence)
+	if err != nil {
+		td.taskRepeatInfo.SetText("Repeat: [::d]Never")
+		return
+	}
+
+	td.taskRepeatInfo.SetText("Repeat: [#5FAFD7]" + rule.Describe())
+}
+
+func (td *TaskDetailPane) makeStartRow() *tview.Flex {
+	td.taskStart = tview.NewInputField().
+		SetPlaceholder("yyyy-mm-dd, empty = any time").
+		SetFieldTextColor(tcell.ColorWhite).
+		SetFieldBackgroundColor(tcell.ColorDarkSlateGray).
+		SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				td.setStartDate(td.taskStart.GetText())
+			case tcell.KeyEsc:
+				td.setStartDisplay()
+			}
+			app.SetFocus(td)
+		})
+
+	return tview.NewFlex().
+		AddItem(td.taskStartInfo, 0, 1, false).
+		AddItem(td.taskStart, 0, 1, false)
+}
+
+// setStartDate defers the task until a date, so it is hidden from lists until then. Empty text removes the start date.
+func (td *TaskDetailPane) setStartDate(text string) {
+	if !taskPane.canEdit() {
+		return
+	}
+
+	var start int64
+	if text = strings.TrimSpace(text); text != "" {
+		date, err := time.ParseInLocation(dateLayoutISO, text, time.Local)
+		if err != nil {
+			statusBar.showForSeconds("[red::]Start date should be like yyyy-mm-dd", 5)
+			td.setStartDisplay()
+			return
+		}
+		start = repository.RoundDueDate(date)
+	}
+
+	defer recordAction("Change start date of " + td.task.Title)()
+	if err := td.taskRepo.UpdateField(td.task, "StartDate", start); err != nil {
+		statusBar.showForSeconds("[red::]Could not update start date: "+err.Error(), 5)
+		return
+	}
+
+	td.task.StartDate = start
+	td.setStartDisplay()
+	td.refreshHistory()
+	taskPane.ReloadCurrentTask()
+
+	if td.task.DueDate != 0 && start > td.task.DueDate {
+		statusBar.showForSeconds("[yellow::]Task starts after its due date", 5)
+	} else if start > time.Now().Unix() && !taskPane.showDeferred {
+		statusBar.showForSeconds("[yellow::]Task is deferred, it will be hidden from lists until "+time.Unix(start, 0).Format(dateLayoutHuman), 5)
+	}
+}
+
+func (td *TaskDetailPane) setStartDisplay() {
+	if td.task.StartDate == 0 {
+		td.taskStart.SetText("")
+		td.taskStartInfo.SetText("Starts: [::d]Any time")
+		return
+	}
+
+	start := time.Unix(td.task.StartDate, 0)
+	td.taskStart.SetText(start.Format(dateLayoutISO))
+	if td.task.StartDate > time.Now().Unix() {
+		td.taskStartInfo.SetText("Starts: [gray]" + start.Format(dateLayoutHuman) + " [::d](deferred)")
+	} else {
+		td.taskStartInfo.SetText("Starts: [#5FAFD7]" + start.Format(dateLayoutHuman))
+	}
+}
+
+func (td *TaskDetailPane) makeReminderRow() *tview.Flex {
+	td.taskReminder = tview.NewInputField().
+		SetPlaceholder("e,g, 15m, 2h, 1d before or none").
+		SetFieldTextColor(tcell.ColorWhite).
+		SetFieldBackgroundColor(tcell.ColorDarkSlateGray).
+		SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				td.setReminder(td.taskReminder.GetText())
+			case tcell.KeyEsc:
+				td.setReminderDisplay()
+			}
+			app.SetFocus(td)
+		})
+
+	return tview.NewFlex().
+		AddItem(td.taskReminderInfo, 0, 1, false).
+		AddItem(td.taskReminder, 0, 1, false)
+}
+
+// setReminder parses and saves how long before due time the task should be reminded
+func (td *TaskDetailPane) setReminder(text string) {
+	if !taskPane.canEdit() {
+		return
+	}
+
+	minutes, err := repository.ParseReminder(text)
+	if err != nil {
+		statusBar.showForSeconds("[red::]"+err.Error(), 5)
+		td.setReminderDisplay()
+		return
+	}
+
+	defer recordAction("Change reminder of " + td.task.Title)()
+	if err := td.updateSchedule(td.task.DueDate, td.task.DueTime, minutes); err != nil {
+		statusBar.showForSeconds("[red::]Could not update reminder: "+err.Error(), 5)
+		return
+	}
+
+	td.refreshHistory()
+	if td.task.DueTime == "" && minutes != model.NoReminder {
+		statusBar.showForSeconds("[yellow::]Set a due time (d) to get reminded", 5)
+	}
+}
+
+// updateSchedule saves due date, due time and reminder offset of the task, rescheduling its reminder along with them
+func (td *TaskDetailPane) updateSchedule(dueDate int64, dueTime string, remindBefore int) error {
+	task := *td.task
+	err := transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+		fields := []struct {
+			name    string
+			changed bool
+			value   interface{}
+		}{
+			{"DueTime", dueTime != task.DueTime, dueTime},
+			{"DueDate", dueDate != task.DueDate, dueDate},
+			{"RemindBefore", remindBefore != task.RemindBefore, remindBefore},
+		}
+		task.DueDate, task.DueTime, task.RemindBefore = dueDate, dueTime, remindBefore
+		for _, field := range fields {
+			if field.changed {
+				if err := tasks.UpdateField(&task, field.name, field.value); err != nil {
+					return err
+				}
+			}
+		}
+
+		return repository.ScheduleReminder(tasks, &task, time.Now())
+	})
+	if err == nil {
+		*td.task = task
+	}
+	td.setReminderDisplay()
+
+	return err
+}
+
+func (td *TaskDetailPane) setReminderDisplay() {
+	td.taskReminder.SetText(repository.FormatReminder(td.task.RemindBefore))
+
+	text := "Remind: [#5FAFD7]" + repository.FormatReminder(td.task.RemindBefore) + " before"
+	switch {
+	case td.task.RemindBefore == model.NoReminder:
+		text = "Remind: [::d]Never"
+	case td.task.DueTime == "":
+		text = "Remind: [::d]Needs a due time"
+	case td.task.RemindBefore == 0:
+		text = "Remind: [#5FAFD7]At due time"
+	}
+	if td.task.RemindAt != 0 {
+		text += " [::d](" + time.Unix(td.task.RemindAt, 0).Format("Jan 02 15:04") + ")"
+	}
+
+	td.taskReminderInfo.SetText(text)
+}
+
+func (td *TaskDetailPane) makePriorityRow() *tview.Flex {
+	return tview.NewFlex().
+		AddItem(td.taskPriority, 0, 1, false).
+		AddItem(tview.NewTextView().SetTextColor(tcell.ColorDimGray).SetTextAlign(tview.AlignRight).
+			SetText("0-4 = set priority"), 0, 1, false)
+}
+
+func (td *TaskDetailPane) makeTagsRow() *tview.Flex {
+	td.taskTags = tview.NewInputField().
+		SetPlaceholder("#tag #another").
+		SetLabel("Tags: ").
+		SetLabelColor(tcell.ColorWhiteSmoke).
+		SetFieldTextColor(tcell.NewHexColor(0x5FAFD7)).
+		SetFieldBackgroundColor(tcell.NewHexColor(0x0c0c0c)).
+		SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				taskPane.SetTaskTags(td.task, model.NormalizeTags(strings.Fields(td.taskTags.GetText())))
+			}
+			td.taskTags.SetText(model.FormatTags(td.task.Tags))
+			app.SetFocus(td)
+		})
+
+	return tview.NewFlex().
+		AddItem(td.taskTags, 0, 1, false).
+		AddItem(tview.NewTextView().SetTextColor(tcell.ColorDimGray).SetTextAlign(tview.AlignRight).
+			SetText("g = edit tags"), 14, 0, false)
+}
+
+func (td *TaskDetailPane) makeTrackedRow() *tview.Flex {
+	return tview.NewFlex().
+		AddItem(td.taskTracked, 0, 1, false).
+		AddItem(tview.NewTextView().SetTextColor(tcell.ColorDimGray).SetTextAlign(tview.AlignRight).
+			SetText("s = timer, f = pomodoro"), 24, 0, false)
+}
+
+// toggleTimer starts tracking time of the task, or stops it if its timer is running
+func (td *TaskDetailPane) toggleTimer() {
+	if taskPane.canEdit() {
+		timeTracker.Toggle(*td.task)
+	}
+}
+
+// setTrackedDisplay shows total time tracked on the task and the pomodoros completed on it
+func (td *TaskDetailPane) setTrackedDisplay() {
+	entries, err := td.taskRepo.GetTimeEntries(*td.task)
+	if err != nil {
+		td.taskTracked.SetText("Tracked: [red]" + err.Error())
+		return
+	}
+
+	text := "Tracked: [::d]none"
+	if total := repository.TrackedTime(entries, time.Now()); total > 0 {
+		text = "Tracked: [#5FAFD7]" + formatDuration(total)
+	}
+	if timeTracker.IsRunning(td.task.ID) {
+		text += " [orange](running)"
+	}
+	if td.task.Pomodoros > 0 {
+		text += fmt.Sprintf("[-:-:-]  Pomodoros: [tomato]%d", td.task.Pomodoros)
+	}
+	td.taskTracked.SetText(text)
+
+	// Actual effort includes the tracked time
+	td.setEffortDisplay()
+}
+
+func (td *TaskDetailPane) makeEffortRow() *tview.Flex {
+	td.taskEstimate = td.makeEffortInput("estimate, e,g, 1h30m", "Estimate")
+	td.taskSpent = td.makeEffortInput("spent, besides tracked", "Spent")
+
+	return tview.NewFlex().
+		AddItem(td.taskEffortInfo, 0, 2, false).
+		AddItem(td.taskEstimate, 0, 1, false).
+		AddItem(td.taskSpent, 0, 1, false)
+}
+
+// makeEffortInput makes the input of an effort field, Estimate or Spent
+func (td *TaskDetailPane) makeEffortInput(placeholder, field string) *tview.InputField {
+	input := tview.NewInputField().
+		SetPlaceholder(placeholder).
+		SetFieldTextColor(tcell.ColorWhite).
+		SetFieldBackgroundColor(tcell.ColorDarkSlateGray)
+
+	input.SetDoneFunc(func(key tcell.Key) {
+		switch key {
+		case tcell.KeyEnter:
+			td.setEffort(field, input.GetText())
+		case tcell.KeyEsc:
+			td.setEffortDisplay()
+		}
+		app.SetFocus(td)
+	})
+
+	return input
+}
+
+// setEffort parses and saves estimated effort or time spent on the task, field being Estimate or Spent
+func (td *TaskDetailPane) setEffort(field, text string) {
+	if !taskPane.canEdit() {
+		return
+	}
+
+	minutes, err := repository.ParseEffort(text)
+	if err != nil {
+		statusBar.showForSeconds("[red::]"+err.Error(), 5)
+		td.setEffortDisplay()
+		return
+	}
+
+	defer recordAction("Change effort of " + td.task.Title)()
+	if err := td.taskRepo.UpdateField(td.task, field, minutes); err != nil {
+		statusBar.showForSeconds("[red::]Could not update effort: "+err.Error(), 5)
+		return
+	}
+
+	if field == "Estimate" {
+		td.task.Estimate = minutes
+	} else {
+		td.task.Spent = minutes
+	}
+	td.setEffortDisplay()
+	td.refreshHistory()
+	taskPane.showWorkload()
+}
+
+// setEffortDisplay shows estimated effort of the task against the actual effort, i,e, time spent and tracked on it
+func (td *TaskDetailPane) setEffortDisplay() {
+	td.taskEstimate.SetText(formatEffortInput(td.task.Estimate))
+	td.taskSpent.SetText(formatEffortInput(td.task.Spent))
+
+	estimate, actual, err := repository.TasksEffort(td.taskRepo, []model.Task{*td.task}, time.Now())
+	if err != nil {
+		td.taskEffortInfo.SetText("Effort: [red]" + err.Error())
+		return
+	}
+
+	td.taskEffortInfo.SetText("Effort: " + describeEffort(estimate, actual))
+}
+
+func (td *TaskDetailPane) makeBlockersRow() *tview.Flex {
+	td.taskBlockedBy = tview.NewInputField().
+		SetPlaceholder("title to add, -title to remove").
+		SetFieldTextColor(tcell.ColorWhite).
+		SetFieldBackgroundColor(tcell.ColorDarkSlateGray).
+		SetDoneFunc(func(key tcell.Key) {
+			switch key {
+			case tcell.KeyEnter:
+				td.changeBlockers(td.taskBlockedBy.GetText())
+			}
+			td.taskBlockedBy.SetText("")
+			app.SetFocus(td)
+		})
+
+	return tview.NewFlex().
+		AddItem(td.taskBlockersInfo, 0, 1, false).
+		AddItem(td.taskBlockedBy, 0, 1, false)
+}
+
+// changeBlockers adds the open task matching text as a blocker of current task.
+// "-text" removes the matching blocker and "-" alone removes all of them.
+func (td *TaskDetailPane) changeBlockers(text string) {
+	if !taskPane.canEdit() {
+		return
+	}
+
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+
+	defer recordAction("Change blockers of " + td.task.Title)()
+	var err error
+	if text == "-" {
+		if err = td.taskRepo.UpdateField(td.task, "BlockedBy", []int64(nil)); err == nil {
+			td.task.BlockedBy = nil
+		}
+	} else if strings.HasPrefix(text, "-") {
+		var blockers []model.Task
+		if blockers, err = repository.Blockers(td.taskRepo, *td.task); err == nil {
+			var blocker model.Task
+			if blocker, err = matchTaskTitle(blockers, strings.TrimSpace(text[1:])); err == nil {
+				err = repository.RemoveBlocker(td.taskRepo, td.task, blocker.ID)
+			}
+		}
+	} else {
+		var candidates []model.Task
+		if candidates, err = td.taskRepo.Find(repository.TaskQuery{Text: text, Status: repository.StatusPending}); err == nil {
+			var blocker model.Task
+			if blocker, err = matchTaskTitle(excludeTask(candidates, td.task.ID), text); err == nil {
+				err = repository.AddBlocker(td.taskRepo, td.task, blocker)
+			}
+		}
+	}
+
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not update blockers: "+err.Error(), 5)
+		return
+	}
+
+	td.setBlockersDisplay()
+	taskPane.ReloadCurrentTask()
+}
+
+func (td *TaskDetailPane) setBlockersDisplay() {
+	blockers, err := repository.OpenBlockers(td.taskRepo, *td.task)
+	if err != nil || len(blockers) == 0 {
+		td.taskBlockersInfo.SetText("Blocked by: [::d]None")
+		return
+	}
+
+	td.taskBlockersInfo.SetText("Blocked by: [orange]" + joinTaskTitles(blockers))
+}
+
+// setPriority updates priority of the task and shows it
+func (td *TaskDetailPane) setPriority(priority model.Priority) {
+	if !taskPane.canEdit() {
+		return
+	}
+
+	defer recordAction("Set priority of " + td.task.Title)()
+	if err := td.taskRepo.UpdateField(td.task, "Priority", priority); err != nil {
+		statusBar.showForSeconds("[red::]Could not update priority: "+err.Error(), 5)
+		return
+	}
+
+	td.task.Priority = priority
+	td.setPriorityDisplay()
+	taskPane.ReloadCurrentTask()
+	statusBar.showForSeconds("[yellow::]Priority set to "+priority.String(), 5)
+}
+
+func (td *TaskDetailPane) setPriorityDisplay() {
+	if td.task.Priority == model.PriorityNone {
+		td.taskPriority.SetText("Priority: [::d]None")
+		return
+	}
+
+	td.taskPriority.SetText(fmt.Sprintf("Priority: [%s]%s", priorityColors[td.task.Priority], td.task.Priority))
+}
+
 func (td *TaskDetailPane) updateToggleDisplay() {
 	if td.task == nil {
 		return
@@ -166,47 +689,126 @@ func (td *TaskDetailPane) updateToggleDisplay() {
 	td.taskStatusToggle.SetBorder(false)  // 去掉边框
 }
 
+// toggleTaskStatus completes or reopens the task. Completing a task blocked by open tasks needs confirmation.
 func (td *TaskDetailPane) toggleTaskStatus() {
+	if !taskPane.canEdit() {
+		return
+	}
+
 	status := !td.task.Completed
-	
-	// 如果任务正在被标记为完成，记录完成时间
-	var completedAt int64
 	if status {
-		completedAt = time.Now().Unix()
+		if blockers, err := repository.OpenBlockers(td.taskRepo, *td.task); err == nil && len(blockers) > 0 {
+			AskYesNo("Blocked by "+joinTaskTitles(blockers)+". Complete anyway?", func() { td.setTaskStatus(true) })
+			return
+		}
+	}
+
+	td.setTaskStatus(status)
+}
+
+func (td *TaskDetailPane) setTaskStatus(status bool) {
+	if status {
+		defer recordAction("Complete " + td.task.Title)()
 	} else {
-		completedAt = 0 // 取消完成时清除完成时间
+		defer recordAction("Resume " + td.task.Title)()
 	}
-	
-	// 同时更新完成状态和完成时间
-	if taskRepo.UpdateField(td.task, "Completed", status) == nil && 
-	   taskRepo.UpdateField(td.task, "CompletedAt", completedAt) == nil {
-		td.task.Completed = status
-		td.task.CompletedAt = completedAt
-		td.updateToggleDisplay() // 更新按钮显示
+
+	// 如果任务正在被标记为完成，记录完成时间
+	task := *td.task
+	completedAt := time.Now().Unix()
+
+	// 重复任务完成后，生成下一次的任务
+	var next time.Time
+	var repeatErr error
+	if status && task.Recurrence != "" {
+		task.CompletedAt = completedAt
+		next, repeatErr = nextOccurrence(task)
+	}
+
+	// 完成状态、子任务和下一次的任务一起保存
+	var subtasks []model.Task
+	var occurrence model.Task
+	err := transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+		if err := repository.SetCompleted(tasks, &task, status, completedAt); err != nil || !status {
+			return err
+		}
+
+		var err error
+		if subtasks, err = repository.CompleteDescendants(tasks, task); err != nil {
+			return err
+		}
+		if !next.IsZero() {
+			occurrence, err = repository.CreateOccurrence(tasks, &task, next, time.Now())
+		}
+		return err
+	})
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not update task: "+err.Error(), 5)
+		return
+	}
+
+	*td.task = task
+	if status {
+		// 完成父任务时，同时完成其所有子任务
+		taskPane.ShowCompletedSubtasks(subtasks)
+		timeTracker.Check()
+		if occurrence.ID != 0 {
+			taskPane.ShowNextOccurrence(task, occurrence)
+		} else if repeatErr != nil {
+			statusBar.showForSeconds("[red::]Could not repeat task: "+repeatErr.Error(), 5)
+		}
+	}
+	td.updateToggleDisplay() // 更新按钮显示
+	taskPane.ReloadCurrentTask()
+}
+
+// setTaskDue changes due date and time of the task together, as entered in date input
+func (td *TaskDetailPane) setTaskDue(unixDate int64, clock string) {
+	if !taskPane.canEdit() {
+		td.setTaskDate(td.task.DueDate, false)
+		return
+	}
+
+	defer recordAction("Change due date of " + td.task.Title)()
+	if err := td.updateSchedule(unixDate, clock, td.task.RemindBefore); err != nil {
+		statusBar.showForSeconds("[red::]Could not update due date: "+err.Error(), 5)
+	} else {
+		td.refreshHistory()
 		taskPane.ReloadCurrentTask()
 	}
+
+	td.setTaskDate(td.task.DueDate, false)
 }
 
 // Display Task date in detail pane, and update date if asked to
 func (td *TaskDetailPane) setTaskDate(unixDate int64, update bool) {
+	if update && !taskPane.canEdit() {
+		unixDate, update = td.task.DueDate, false
+	}
 	if update {
-		td.task.DueDate = unixDate
-		if err := td.taskRepo.UpdateField(td.task, "DueDate", unixDate); err != nil {
+		defer recordAction("Change due date of " + td.task.Title)()
+		if err := td.updateSchedule(unixDate, td.task.DueTime, td.task.RemindBefore); err != nil {
 			statusBar.showForSeconds("Could not update due date: "+err.Error(), 5)
 			return
 		}
+		td.refreshHistory()
 	}
 
 	if unixDate != 0 {
-		due := time.Unix(unixDate, 0)
+		due := repository.DueAt(model.Task{DueDate: unixDate, DueTime: td.task.DueTime})
 		color := "white"
 		humanDate := due.Format(dateLayoutHuman)
+		inputDate := due.Format(dateLayoutISO)
+		if td.task.DueTime != "" {
+			humanDate += ", " + td.task.DueTime
+			inputDate += " " + td.task.DueTime
+		}
 
 		if due.Before(time.Now()) {
 			color = "red"
 		}
 		td.taskDateDisplay.SetText(fmt.Sprintf("Due: [%s]%s", color, humanDate))
-		td.taskDate.SetText(due.Format(dateLayoutISO))
+		td.taskDate.SetText(inputDate)
 	} else {
 		td.taskDate.SetText("")
 		td.taskDateDisplay.SetText("Due: [::d]Not Set")
@@ -217,13 +819,7 @@ func (td *TaskDetailPane) prepareDetailsEditor() {
 
 	td.taskDetailView = femto.NewView(makeBufferFromString(""))
 	td.taskDetailView.SetRuntimeFiles(runtime.Files)
-
-	// var colorScheme femto.Colorscheme
-	if monokai := runtime.Files.FindFile(femto.RTColorscheme, "monokai"); monokai != nil {
-		if data, err := monokai.Data(); err == nil {
-			td.colorScheme = femto.ParseColorscheme(string(data))
-		}
-	}
+	td.colorScheme = loadNoteColorScheme()
 
 	td.taskDetailView.SetColorscheme(td.colorScheme)
 	td.taskDetailView.SetBorder(true)
@@ -243,15 +839,33 @@ func (td *TaskDetailPane) prepareDetailsEditor() {
 }
 
 func (td *TaskDetailPane) updateTaskNote(note string) {
+	if note == td.task.Details {
+		return
+	}
+
+	defer recordAction("Edit note of " + td.task.Title)()
 	td.task.Details = note
 	err := taskRepo.Update(td.task)
 	if err == nil {
+		td.refreshHistory()
 		statusBar.showForSeconds("[lime]Saved task detail", 5)
 	} else {
 		statusBar.showForSeconds("[red]Could not save: "+err.Error(), 5)
 	}
 }
 
+// loadNoteColorScheme loads the color scheme of markdown notes in femto editor
+func loadNoteColorScheme() femto.Colorscheme {
+	var colorScheme femto.Colorscheme
+	if monokai := runtime.Files.FindFile(femto.RTColorscheme, "monokai"); monokai != nil {
+		if data, err := monokai.Data(); err == nil {
+			colorScheme = femto.ParseColorscheme(string(data))
+		}
+	}
+
+	return colorScheme
+}
+
 func makeBufferFromString(content string) *femto.Buffer {
 	buff := femto.NewBufferFromString(content, "")
 	// taskDetail.Settings["ruler"] = false
@@ -265,6 +879,10 @@ func makeBufferFromString(content string) *femto.Buffer {
 }
 
 func (td *TaskDetailPane) activateEditor() {
+	if !taskPane.canEdit() {
+		return
+	}
+
 	td.taskDetailView.Readonly = false
 	td.taskDetailView.SetBorderColor(tcell.ColorDarkOrange)
 	td.editorHint.SetText(" Esc to save changes")
@@ -279,11 +897,23 @@ func (td *TaskDetailPane) deactivateEditor() {
 }
 
 func (td *TaskDetailPane) editInExternalEditor() {
+	if !taskPane.canEdit() {
+		return
+	}
 
-	tmpFileName, err := writeToTmpFile(td.task.Details)
+	if updatedContent := editInExternalEditor(td.task.Details); updatedContent != "" {
+		td.updateTaskNote(updatedContent)
+		td.SetTask(td.task)
+	}
+}
+
+// editInExternalEditor opens content in external editor (EDITOR, default vim) while the app is suspended.
+// It provides the edited content, empty if editing failed.
+func editInExternalEditor(content string) string {
+	tmpFileName, err := writeToTmpFile(content)
 	if err != nil {
-		statusBar.showForSeconds("[red::]Failed to create tmp file. Try in-app editing by pressing i", 5)
-		return
+		statusBar.showForSeconds("[red::]Failed to create tmp file. Try in-app editing by pressing e", 5)
+		return ""
 	}
 
 	var messageToShow, updatedContent string
@@ -308,16 +938,11 @@ func (td *TaskDetailPane) editInExternalEditor() {
 		statusBar.showForSeconds(messageToShow, 10)
 	}
 
-	if updatedContent != "" {
-		td.updateTaskNote(updatedContent)
-		td.SetTask(td.task)
-	}
-
 	app.EnableMouse(true)
 
 	_ = os.Remove(tmpFileName)
 
-	// app.SetFocus(td)
+	return updatedContent
 }
 
 // writeToTmpFile writes given content to a tmpFile and returns the filename
@@ -358,11 +983,43 @@ func (td *TaskDetailPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey
 			td.editInExternalEditor()
 			return nil
 		case 'd':
-			app.SetFocus(td.taskDate)
+			td.focusInput(td.taskDate)
 			return nil
 		case 'r':
 			td.header.ShowRename()
 			return nil
+		case 'g':
+			td.focusInput(td.taskTags)
+			return nil
+		case 'w':
+			td.focusInput(td.taskRepeat)
+			return nil
+		case 'a':
+			td.focusInput(td.taskReminder)
+			return nil
+		case 'l':
+			td.focusInput(td.taskStart)
+			return nil
+		case 'b':
+			td.focusInput(td.taskBlockedBy)
+			return nil
+		case 'm':
+			td.focusInput(td.taskEstimate)
+			return nil
+		case 'n':
+			td.focusInput(td.taskSpent)
+			return nil
+		case 'h':
+			td.toggleHistory()
+			return nil
+		case 's':
+			td.toggleTimer()
+			return nil
+		case 'f':
+			if taskPane.canEdit() {
+				pomodoro.Toggle(*td.task)
+			}
+			return nil
 		case ' ':
 			td.toggleTaskStatus()
 			return nil
@@ -378,12 +1035,22 @@ func (td *TaskDetailPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey
 		case '-':
 			td.prevDaySelector()
 			return nil
+		case '0', '1', '2', '3', '4':
+			td.setPriority(model.Priority(event.Rune() - '0'))
+			return nil
 		}
 	}
 
 	return event
 }
 
+// focusInput starts editing a field of the task, if it can be changed
+func (td *TaskDetailPane) focusInput(input *tview.InputField) {
+	if taskPane.canEdit() {
+		app.SetFocus(input)
+	}
+}
+
 // SetTask sets a Task to be displayed
 func (td *TaskDetailPane) SetTask(task *model.Task) {
 	td.task = task
@@ -393,6 +1060,14 @@ func (td *TaskDetailPane) SetTask(task *model.Task) {
 	td.taskDetailView.SetColorscheme(td.colorScheme)
 	td.taskDetailView.Start()
 	td.setTaskDate(td.task.DueDate, false)
+	td.setStartDisplay()
+	td.setRecurrenceDisplay()
+	td.setReminderDisplay()
+	td.setPriorityDisplay()
+	td.taskTags.SetText(model.FormatTags(td.task.Tags))
+	td.setBlockersDisplay()
+	td.setTrackedDisplay()
+	td.refreshHistory()
 	td.updateToggleDisplay() // 确保按钮状态正确
 	td.deactivateEditor()
 }
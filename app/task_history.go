@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+const timeLayoutHuman = "02 Jan 2006 15:04"
+
+// fieldLabels names the task fields in history, fields not listed here are shown by their name
+var fieldLabels = map[string]string{
+	"ProjectID":    "Project",
+	"ParentID":     "Parent task",
+	"Details":      "Note",
+	"CompletedAt":  "Completed at",
+	"DueDate":      "Due date",
+	"DueTime":      "Due time",
+	"StartDate":    "Start date",
+	"Recurrence":   "Repeat",
+	"BlockedBy":    "Blocked by",
+	"DeletedAt":    "Trashed at",
+	"RemindBefore": "Reminder",
+	"Spent":        "Time spent",
+}
+
+func (td *TaskDetailPane) makeTimestampsRow() *tview.Flex {
+	return tview.NewFlex().
+		AddItem(td.taskTimestamps, 0, 1, false).
+		AddItem(tview.NewTextView().SetTextColor(tcell.ColorDimGray).SetTextAlign(tview.AlignRight).
+			SetText("h = history"), 12, 0, false)
+}
+
+func (td *TaskDetailPane) makeHistoryView() *tview.TextView {
+	view := tview.NewTextView().SetDynamicColors(true).SetScrollable(true)
+	view.SetBorder(true).SetTitle("History").SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+
+	return view
+}
+
+// toggleHistory shows or hides the list of changes made to the task
+func (td *TaskDetailPane) toggleHistory() {
+	td.historyShown = !td.historyShown
+	if td.historyShown {
+		td.ResizeItem(td.taskHistory, 10, 0)
+		td.refreshHistory()
+	} else {
+		td.ResizeItem(td.taskHistory, 0, 0)
+	}
+}
+
+func (td *TaskDetailPane) setTimestampsDisplay() {
+	if td.task.CreatedAt == 0 {
+		td.taskTimestamps.SetText("Created: [::d]Unknown")
+		return
+	}
+
+	text := "Created: [::d]" + time.Unix(td.task.CreatedAt, 0).Format(timeLayoutHuman) + "[::-]"
+	if td.task.UpdatedAt > td.task.CreatedAt {
+		text += "  Updated: [::d]" + time.Unix(td.task.UpdatedAt, 0).Format(timeLayoutHuman)
+	}
+	td.taskTimestamps.SetText(text)
+}
+
+// refreshHistory reloads changes of the task in history panel (if shown), latest first
+func (td *TaskDetailPane) refreshHistory() {
+	td.setTimestampsDisplay()
+	if !td.historyShown {
+		return
+	}
+
+	history, err := td.taskRepo.GetHistory(*td.task)
+	if err != nil {
+		td.taskHistory.SetText("[red::]Could not load history: " + err.Error())
+		return
+	}
+	if len(history) == 0 {
+		td.taskHistory.SetText("[::d]No change yet")
+		return
+	}
+
+	var lines []string
+	for i := len(history) - 1; i >= 0; i-- {
+		lines = append(lines, describeChange(history[i]))
+	}
+	td.taskHistory.SetText(strings.Join(lines, "\n")).ScrollToBeginning()
+}
+
+// describeChange makes a line of history, e,g, "15 Oct 2026 10:20  Due date: 16 Oct, Friday → Not Set"
+func describeChange(change model.TaskChange) string {
+	label, ok := fieldLabels[change.Field]
+	if !ok {
+		label = change.Field
+	}
+
+	text := fmt.Sprintf("[::d]%s[::-]  %s: ", time.Unix(change.ChangedAt, 0).Format(timeLayoutHuman), label)
+	if change.Field == "Details" {
+		return text + "edited"
+	}
+
+	return text + "[::d]" + describeFieldValue(change.Field, change.OldValue) + "[::-] → " + describeFieldValue(change.Field, change.NewValue)
+}
+
+// describeFieldValue makes a value stored in history readable, e,g, titles of tasks for their IDs
+func describeFieldValue(field, value string) string {
+	if value == "" {
+		return "None"
+	}
+
+	switch field {
+	case "DueDate", "StartDate", "CompletedAt", "DeletedAt":
+		if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+			if unix == 0 {
+				return "Not Set"
+			} else if field == "DueDate" || field == "StartDate" {
+				return time.Unix(unix, 0).Format(dateLayoutHuman)
+			}
+			return time.Unix(unix, 0).Format(timeLayoutHuman)
+		}
+	case "RemindBefore":
+		if minutes, err := strconv.Atoi(value); err == nil {
+			if minutes == model.NoReminder {
+				return "Never"
+			}
+			return repository.FormatReminder(minutes) + " before"
+		}
+	case "Estimate", "Spent":
+		if minutes, err := strconv.Atoi(value); err == nil {
+			if minutes == 0 {
+				return "Not Set"
+			}
+			return formatDuration(time.Duration(minutes) * time.Minute)
+		}
+	case "Completed":
+		if value == "true" {
+			return "Done"
+		}
+		return "Open"
+	case "Priority":
+		if p, err := strconv.Atoi(value); err == nil {
+			return model.Priority(p).String()
+		}
+	case "ProjectID":
+		if id, err := strconv.ParseInt(value, 10, 64); err == nil {
+			if project, err := projectRepo.GetByID(id); err == nil {
+				return project.Title
+			}
+		}
+	case "ParentID", "BlockedBy":
+		var titles []string
+		for _, id := range strings.Split(value, ",") {
+			if id == "0" {
+				return "None"
+			} else if task, err := taskRepo.GetByID(id); err == nil {
+				titles = append(titles, task.Title)
+			} else {
+				titles = append(titles, "#"+id)
+			}
+		}
+		return strings.Join(titles, ", ")
+	case "Tags":
+		return model.FormatTags(strings.Split(value, ","))
+	}
+
+	return value
+}
@@ -1,16 +1,18 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"sort"
+	"strconv"
+	"strings"
 	"time"
 	"unicode"
 
-	"github.com/asdine/storm/v3"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 
 	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/recurrence"
 	"github.com/ajaxray/geek-life/repository"
 )
 
@@ -19,20 +21,40 @@ type TaskPane struct {
 	*tview.Flex
 	list       *tview.List
 	tasks      []model.Task
+	taskOfItem []int // Index in tasks for each list item, -1 for headings
 	activeTask *model.Task
 
+	collapsed     map[int64]bool            // IDs of tasks with hidden subtasks
+	progress      map[int64]subtaskProgress // Cached subtask counts, by parent ID
+	blockers      map[int64][]string        // Cached titles of open blockers, by task ID
+	newTaskParent int64                     // ID of the task new tasks are added under, 0 for top level
+	reload        func()                    // Loads the current list again
+	readOnly      bool                      // Tasks of archived projects can not be changed
+	showDeferred  bool                      // Whether tasks deferred to a later start date are listed
+	loadedOn      time.Time                 // Date the list was loaded on, to load it again when a new day starts
+	workloadFrom  time.Time                 // First date of the days listed, to show their workload in title. Zero if the list is not of days
+	workloadTo    time.Time                 // Last date of the days listed
+
 	newTask     *tview.InputField
 	projectRepo repository.ProjectRepository
 	taskRepo    repository.TaskRepository
 	hint        *tview.TextView
 }
 
+// subtaskProgress counts completed and total (direct) subtasks of a task
+type subtaskProgress struct {
+	done, total int
+}
+
 // NewTaskPane initializes and configures a TaskPane
 func NewTaskPane(projectRepo repository.ProjectRepository, taskRepo repository.TaskRepository) *TaskPane {
 	pane := TaskPane{
 		Flex:        tview.NewFlex().SetDirection(tview.FlexRow),
 		list:        tview.NewList().ShowSecondaryText(false),
 		newTask:     makeLightTextInput("+[New Task]"),
+		collapsed:   make(map[int64]bool),
+		progress:    make(map[int64]subtaskProgress),
+		blockers:    make(map[int64][]string),
 		projectRepo: projectRepo,
 		taskRepo:    taskRepo,
 		hint:        tview.NewTextView().SetTextColor(tcell.ColorYellow).SetTextAlign(tview.AlignCenter),
@@ -48,7 +70,7 @@ func NewTaskPane(projectRepo repository.ProjectRepository, taskRepo repository.T
 	pane.newTask.SetDoneFunc(func(key tcell.Key) {
 		switch key {
 		case tcell.KeyEnter:
-			name := pane.newTask.GetText()
+			name, tags := model.ParseTags(pane.newTask.GetText())
 			if len(name) < 3 {
 				statusBar.showForSeconds("[red::]Task title should be at least 3 character", 5)
 				return
@@ -59,18 +81,58 @@ func NewTaskPane(projectRepo repository.ProjectRepository, taskRepo repository.T
 				return
 			}
 
-			task, err := taskRepo.Create(*projectPane.GetActiveProject(), name, "", "", 0)
+			// Subtasks go in the project of their parent, which may be a sub-project of the active one
+			project := *projectPane.GetActiveProject()
+			if parent := pane.indexOfTaskID(pane.newTaskParent); parent != -1 {
+				project = model.Project{ID: pane.tasks[parent].ProjectID}
+			}
+
+			defer recordAction("Add task " + name)()
+			var task model.Task
+			err := transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+				var err error
+				if task, err = tasks.Create(project, name, "", "", 0); err != nil {
+					return err
+				}
+
+				if pane.newTaskParent != 0 {
+					if err := tasks.UpdateField(&task, "ParentID", pane.newTaskParent); err != nil {
+						return err
+					}
+					task.ParentID = pane.newTaskParent
+				}
+
+				if len(tags) > 0 {
+					if err := tasks.UpdateField(&task, "Tags", tags); err != nil {
+						return err
+					}
+					task.Tags = tags
+				}
+
+				return nil
+			})
 			if err != nil {
 				statusBar.showForSeconds("[red::]Could not create Task:"+err.Error(), 5)
 				return
 			}
+			if len(tags) > 0 {
+				projectPane.refreshTags()
+			}
 
-			pane.tasks = append(pane.tasks, task)
-			pane.addTaskToList(len(pane.tasks) - 1)
+			idx := pane.appendTask(task)
+			if parent := pane.indexOfTaskID(task.ParentID); parent != -1 {
+				delete(pane.progress, task.ParentID)
+				delete(pane.collapsed, task.ParentID)
+				pane.refreshSubtree(parent)
+			} else {
+				pane.addTopLevelTask(idx)
+			}
 			pane.newTask.SetText("")
+			projectDetailPane.setCountsDisplay()
 			statusBar.showForSeconds("[yellow::]Task created. Add another task or press Esc.", 5)
 		case tcell.KeyEsc:
 			pane.newTask.SetText("")
+			pane.setNewTaskParent(nil)
 			app.SetFocus(pane)
 		}
 	})
@@ -89,28 +151,401 @@ func NewTaskPane(projectRepo repository.ProjectRepository, taskRepo repository.T
 func (pane *TaskPane) ClearList() {
 	pane.list.Clear()
 	pane.tasks = nil
+	pane.taskOfItem = nil
 	pane.activeTask = nil
+	pane.progress = make(map[int64]subtaskProgress)
+	pane.blockers = make(map[int64][]string)
+	pane.setNewTaskParent(nil)
+	pane.reload = nil
+	pane.readOnly = false
+	pane.workloadFrom, pane.workloadTo = time.Time{}, time.Time{}
+	pane.showWorkload()
 
 	pane.RemoveItem(pane.newTask)
+	trashPane.Hide()
 }
 
 // SetList Sets a list of tasks to be displayed
 func (pane *TaskPane) SetList(tasks []model.Task) {
 	pane.ClearList()
 	pane.tasks = tasks
+	pane.loadedOn = toDate(time.Now())
 
 	for i := range pane.tasks {
-		pane.addTaskToList(i)
+		if pane.isTopLevel(i) {
+			pane.addTaskTreeToList(i)
+		}
 	}
 }
 
 func (pane *TaskPane) addTaskToList(i int) *tview.List {
-	return pane.list.AddItem(makeTaskListingTitle(pane.tasks[i]), "", 0, func(taskidx int) func() {
+	return pane.insertTaskToList(len(pane.taskOfItem), i)
+}
+
+// insertTaskToList adds a task in list at the given item index
+func (pane *TaskPane) insertTaskToList(item, i int) *tview.List {
+	pane.taskOfItem = append(pane.taskOfItem[:item], append([]int{i}, pane.taskOfItem[item:]...)...)
+	return pane.list.InsertItem(item, pane.listingTitle(i), "", 0, func(taskidx int) func() {
 		return func() { taskPane.ActivateTask(taskidx) }
 	}(i))
 }
 
+// addTopLevelTask lists a new top level task of the active project, above the tasks of its sub-projects
+func (pane *TaskPane) addTopLevelTask(idx int) {
+	for item, taskIdx := range pane.taskOfItem {
+		// Only sub-projects have headings in a project listing
+		if taskIdx == -1 {
+			if item == 0 {
+				// Separate from sub-projects, like when the project had tasks already
+				pane.taskOfItem = append([]int{-1}, pane.taskOfItem...)
+				pane.list.InsertItem(0, "", "", 0, nil)
+			}
+			pane.insertTaskToList(item, idx)
+			return
+		}
+	}
+
+	pane.addTaskToList(idx)
+}
+
+// addTaskTreeToList adds a task in list, followed by its visible subtasks
+func (pane *TaskPane) addTaskTreeToList(i int) {
+	pane.addTaskToList(i)
+	for _, sub := range pane.visibleSubtasks(i) {
+		pane.addTaskToList(sub)
+	}
+}
+
+// appendTask adds a task in pane.tasks, keeping the pointer to active task valid
+func (pane *TaskPane) appendTask(task model.Task) int {
+	active := pane.indexOfTask(pane.activeTask)
+	pane.tasks = append(pane.tasks, task)
+	if active != -1 {
+		pane.activeTask = &pane.tasks[active]
+		taskDetailPane.task = pane.activeTask
+	}
+
+	return len(pane.tasks) - 1
+}
+
+// indexOfTaskID provides index of a task in pane.tasks by ID, -1 if it is not loaded
+func (pane *TaskPane) indexOfTaskID(id int64) int {
+	for i := range pane.tasks {
+		if id != 0 && pane.tasks[i].ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// isTopLevel tells if a task is listed at the top level, i,e, its parent is not loaded in pane
+func (pane *TaskPane) isTopLevel(idx int) bool {
+	return pane.indexOfTaskID(pane.tasks[idx].ParentID) == -1
+}
+
+// childrenOf provides indexes of loaded subtasks of a task
+func (pane *TaskPane) childrenOf(idx int) []int {
+	var children []int
+	for i := range pane.tasks {
+		if pane.tasks[i].ParentID == pane.tasks[idx].ID {
+			children = append(children, i)
+		}
+	}
+
+	return children
+}
+
+// depthOf provides the nesting level of a loaded task, 0 for top level tasks
+func (pane *TaskPane) depthOf(idx int) int {
+	depth := 0
+	for parent := pane.indexOfTaskID(pane.tasks[idx].ParentID); parent != -1 && depth < len(pane.tasks); depth++ {
+		parent = pane.indexOfTaskID(pane.tasks[parent].ParentID)
+	}
+
+	return depth
+}
+
+// isDescendant tells if a loaded task is a subtask of another, in any depth
+func (pane *TaskPane) isDescendant(idx, ancestor int) bool {
+	for depth, parent := 0, pane.indexOfTaskID(pane.tasks[idx].ParentID); parent != -1 && depth < len(pane.tasks); depth++ {
+		if parent == ancestor {
+			return true
+		}
+		parent = pane.indexOfTaskID(pane.tasks[parent].ParentID)
+	}
+
+	return false
+}
+
+// visibleSubtasks provides descendants of a task in listing order, skipping subtasks of collapsed tasks
+func (pane *TaskPane) visibleSubtasks(idx int) []int {
+	if pane.collapsed[pane.tasks[idx].ID] {
+		return nil
+	}
+
+	var visible []int
+	for _, child := range pane.childrenOf(idx) {
+		visible = append(visible, child)
+		visible = append(visible, pane.visibleSubtasks(child)...)
+	}
+
+	return visible
+}
+
+// subtaskProgressOf counts subtasks of a task from repository, as not all of them may be loaded in list
+func (pane *TaskPane) subtaskProgressOf(task model.Task) subtaskProgress {
+	if progress, ok := pane.progress[task.ID]; ok {
+		return progress
+	}
+
+	var progress subtaskProgress
+	if subtasks, err := pane.taskRepo.Find(repository.TaskQuery{ParentID: task.ID}); err == nil {
+		progress.total = len(subtasks)
+		for _, sub := range subtasks {
+			if sub.Completed {
+				progress.done++
+			}
+		}
+	}
+	pane.progress[task.ID] = progress
+
+	return progress
+}
+
+// listingTitle makes the list item text of a task, indented by its depth and showing progress of subtasks
+func (pane *TaskPane) listingTitle(idx int) string {
+	task := pane.tasks[idx]
+
+	progress := ""
+	if p := pane.subtaskProgressOf(task); p.total > 0 {
+		progress = fmt.Sprintf("%d/%d", p.done, p.total)
+		if len(pane.childrenOf(idx)) > 0 {
+			if pane.collapsed[task.ID] {
+				progress += " ▸"
+			} else {
+				progress += " ▾"
+			}
+		}
+	}
+
+	return strings.Repeat("   ", pane.depthOf(idx)) + makeTaskListingTitle(task, progress, pane.openBlockersOf(task))
+}
+
+// openBlockersOf finds titles of the uncompleted tasks blocking a task, which may be in other projects
+func (pane *TaskPane) openBlockersOf(task model.Task) []string {
+	if len(task.BlockedBy) == 0 {
+		return nil
+	}
+	if titles, ok := pane.blockers[task.ID]; ok {
+		return titles
+	}
+
+	var titles []string
+	if blockers, err := repository.OpenBlockers(pane.taskRepo, task); err == nil {
+		for _, blocker := range blockers {
+			titles = append(titles, blocker.Title)
+		}
+	}
+	pane.blockers[task.ID] = titles
+
+	return titles
+}
+
+// refreshItem updates the list item of a task, if it is listed
+func (pane *TaskPane) refreshItem(idx int) {
+	if item := pane.itemOfTask(idx); item != -1 {
+		pane.list.SetItemText(item, pane.listingTitle(idx), "")
+	}
+}
+
+// refreshListing updates list items of all listed tasks, recounting subtasks
+func (pane *TaskPane) refreshListing() {
+	pane.progress = make(map[int64]subtaskProgress)
+	pane.blockers = make(map[int64][]string)
+	for item, idx := range pane.taskOfItem {
+		if idx != -1 {
+			pane.list.SetItemText(item, pane.listingTitle(idx), "")
+		}
+	}
+	pane.showWorkload()
+}
+
+// showWorkload shows estimated effort of open tasks in title when days are listed, marking days booked over daily capacity
+func (pane *TaskPane) showWorkload() {
+	if pane.workloadFrom.IsZero() {
+		pane.SetTitle("Tasks")
+		return
+	}
+
+	daily := repository.DailyEstimates(pane.tasks)
+	var total int
+	var overbooked []string
+	for date := pane.workloadFrom; !date.After(pane.workloadTo); date = date.AddDate(0, 0, 1) {
+		total += daily[date.Unix()]
+		if daily[date.Unix()] > dailyCapacity*60 {
+			overbooked = append(overbooked, date.Format("Mon 02"))
+		}
+	}
+
+	switch {
+	case total == 0:
+		pane.SetTitle("Tasks")
+	case pane.workloadFrom.Equal(pane.workloadTo) && len(overbooked) > 0:
+		pane.SetTitle(fmt.Sprintf("Tasks · est. [red]%s[-] of %dh, overbooked", formatDuration(time.Duration(total)*time.Minute), dailyCapacity))
+	case pane.workloadFrom.Equal(pane.workloadTo):
+		pane.SetTitle(fmt.Sprintf("Tasks · est. %s of %dh", formatDuration(time.Duration(total)*time.Minute), dailyCapacity))
+	case len(overbooked) > 0:
+		pane.SetTitle("Tasks · est. " + formatDuration(time.Duration(total)*time.Minute) + " · [red]overbooked " + strings.Join(overbooked, ", ") + "[-]")
+	default:
+		pane.SetTitle("Tasks · est. " + formatDuration(time.Duration(total)*time.Minute))
+	}
+}
+
+// refreshSubtree lists visible subtasks of a task again, after they are collapsed, expanded or added
+func (pane *TaskPane) refreshSubtree(idx int) {
+	item := pane.itemOfTask(idx)
+	if item == -1 {
+		return
+	}
+
+	current, removed := pane.list.GetCurrentItem(), 0
+	for item+1 < len(pane.taskOfItem) && pane.taskOfItem[item+1] != -1 && pane.isDescendant(pane.taskOfItem[item+1], idx) {
+		pane.list.RemoveItem(item + 1)
+		pane.taskOfItem = append(pane.taskOfItem[:item+1], pane.taskOfItem[item+2:]...)
+		removed++
+	}
+
+	subtasks := pane.visibleSubtasks(idx)
+	for i, sub := range subtasks {
+		pane.insertTaskToList(item+1+i, sub)
+	}
+	pane.refreshItem(idx)
+
+	// Keep cursor on the same item, or on the parent if the item under cursor got hidden
+	if current > item+removed {
+		current += len(subtasks) - removed
+	} else if current > item {
+		current = item
+	}
+	pane.list.SetCurrentItem(current)
+}
+
+// ToggleCollapse hides or shows subtasks of a task
+func (pane *TaskPane) ToggleCollapse(idx int) {
+	if len(pane.childrenOf(idx)) == 0 {
+		return
+	}
+
+	id := pane.tasks[idx].ID
+	pane.collapsed[id] = !pane.collapsed[id]
+	pane.refreshSubtree(idx)
+}
+
+// SetAllCollapsed hides or shows subtasks of all listed tasks
+func (pane *TaskPane) SetAllCollapsed(collapsed bool) {
+	var topLevel []int
+	for i := range pane.tasks {
+		if len(pane.childrenOf(i)) > 0 {
+			pane.collapsed[pane.tasks[i].ID] = collapsed
+		}
+		if pane.isTopLevel(i) {
+			topLevel = append(topLevel, i)
+		}
+	}
+
+	for _, idx := range topLevel {
+		pane.refreshSubtree(idx)
+	}
+}
+
+// setNewTaskParent makes new tasks to be added as subtasks of parent, or as top level tasks if nil
+func (pane *TaskPane) setNewTaskParent(parent *model.Task) {
+	if parent == nil {
+		pane.newTaskParent = 0
+		pane.newTask.SetPlaceholder("+[New Task]")
+		return
+	}
+
+	pane.newTaskParent = parent.ID
+	pane.newTask.SetPlaceholder("+[New Subtask of " + parent.Title + "]")
+}
+
+// startNewSubtask focuses new task input to add subtasks of the task under cursor
+func (pane *TaskPane) startNewSubtask() {
+	idx := pane.currentTaskIndex()
+	if idx == -1 || !pane.canEdit() {
+		return
+	}
+
+	if projectPane.GetActiveProject() == nil {
+		statusBar.showForSeconds("[red::]Open the project of the task to add subtasks", 5)
+		return
+	}
+
+	pane.setNewTaskParent(&pane.tasks[idx])
+	app.SetFocus(pane.newTask)
+}
+
+// addHeadingToList adds a non-task (title, blank line etc.) item in list
+func (pane *TaskPane) addHeadingToList(text string) *tview.List {
+	pane.taskOfItem = append(pane.taskOfItem, -1)
+	return pane.list.AddItem(text, "", 0, nil)
+}
+
+// currentTaskIndex provides index of the task under list cursor, -1 if cursor is on a heading
+func (pane *TaskPane) currentTaskIndex() int {
+	item := pane.list.GetCurrentItem()
+	if item < 0 || item >= len(pane.taskOfItem) {
+		return -1
+	}
+
+	return pane.taskOfItem[item]
+}
+
+// itemOfTask provides list item index of a task, -1 if it is not listed
+func (pane *TaskPane) itemOfTask(idx int) int {
+	for item, taskIdx := range pane.taskOfItem {
+		if taskIdx == idx {
+			return item
+		}
+	}
+
+	return -1
+}
+
+// SetTaskPriority updates priority of a listed task and refreshes its listing
+func (pane *TaskPane) SetTaskPriority(idx int, priority model.Priority) {
+	if !pane.canEdit() {
+		return
+	}
+
+	task := &pane.tasks[idx]
+	defer recordAction("Set priority of " + task.Title)()
+	if err := pane.taskRepo.UpdateField(task, "Priority", priority); err != nil {
+		statusBar.showForSeconds("[red::]Could not update priority: "+err.Error(), 5)
+		return
+	}
+
+	task.Priority = priority
+	pane.refreshItem(idx)
+	if pane.activeTask == task {
+		taskDetailPane.SetTask(task)
+	}
+
+	statusBar.showForSeconds("[yellow::]Priority set to "+priority.String(), 5)
+}
+
 func (pane *TaskPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case 'J':
+		pane.moveTask(1)
+		return nil
+	case 'K':
+		pane.moveTask(-1)
+		return nil
+	}
+
 	switch unicode.ToLower(event.Rune()) {
 	case 'j':
 		pane.list.SetCurrentItem(pane.list.GetCurrentItem() + 1)
@@ -122,68 +557,261 @@ func (pane *TaskPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey {
 		app.SetFocus(projectPane)
 		return nil
 	case 'n':
-		app.SetFocus(pane.newTask)
+		if pane.canEdit() {
+			pane.setNewTaskParent(nil)
+			app.SetFocus(pane.newTask)
+		}
+		return nil
+	case 'a':
+		pane.startNewSubtask()
+		return nil
+	case 's':
+		if idx := pane.currentTaskIndex(); idx != -1 && pane.canEdit() {
+			timeTracker.Toggle(pane.tasks[idx])
+		}
+		return nil
+	case 'f':
+		if idx := pane.currentTaskIndex(); idx != -1 && pane.canEdit() {
+			pomodoro.Toggle(pane.tasks[idx])
+		}
+		return nil
+	case 'z':
+		if idx := pane.currentTaskIndex(); idx != -1 {
+			pane.ToggleCollapse(idx)
+		}
+		return nil
+	case '.':
+		pane.ToggleDeferred()
+		return nil
+	case '<':
+		pane.SetAllCollapsed(true)
+		return nil
+	case '>':
+		pane.SetAllCollapsed(false)
+		return nil
+	case '0', '1', '2', '3', '4':
+		if idx := pane.currentTaskIndex(); idx != -1 {
+			pane.SetTaskPriority(idx, model.Priority(event.Rune()-'0'))
+		}
 		return nil
 	}
 
 	return event
 }
 
-// LoadProjectTasks loads tasks of a project in taskPane
+// moveTask swaps the task under cursor with its next (step 1) or previous (step -1) sibling in project list
+func (pane *TaskPane) moveTask(step int) {
+	idx := pane.currentTaskIndex()
+	if idx == -1 || !pane.canEdit() {
+		return
+	}
+	if projectPane.GetActiveProject() == nil {
+		statusBar.showForSeconds("[yellow::]Open the project of the task to reorder it", 5)
+		return
+	}
+
+	task := pane.tasks[idx]
+	other := -1
+	for i := idx + step; i >= 0 && i < len(pane.tasks); i += step {
+		if pane.tasks[i].ParentID == task.ParentID && pane.tasks[i].ProjectID == task.ProjectID {
+			other = i
+			break
+		}
+	}
+	if other == -1 {
+		return
+	}
+	if pane.tasks[other].Priority != task.Priority {
+		statusBar.showForSeconds("[yellow::]Tasks are listed by priority first. Change priority to move it further", 5)
+		return
+	}
+
+	defer recordAction("Move task " + task.Title)()
+	neighbour := pane.tasks[other]
+	err := transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+		if err := tasks.UpdateField(&task, "Position", neighbour.Position); err != nil {
+			return err
+		}
+
+		return tasks.UpdateField(&neighbour, "Position", pane.tasks[idx].Position)
+	})
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not move task: "+err.Error(), 5)
+	}
+
+	pane.Reload()
+	if item := pane.itemOfTask(pane.indexOfTaskID(task.ID)); item != -1 {
+		pane.list.SetCurrentItem(item)
+	}
+}
+
+// LoadProjectTasks loads tasks of a project in taskPane, followed by tasks of its sub-projects grouped by them
 func (pane *TaskPane) LoadProjectTasks(project model.Project) {
 	var tasks []model.Task
 	var err error
 
-	if tasks, err = taskRepo.GetAllByProject(project); err != nil && err != storm.ErrNotFound {
+	query := repository.TaskQuery{ProjectID: project.ID, OrderBy: "Position", PriorityFirst: true, AvailableBy: pane.availableBy(time.Now())}
+	if tasks, err = taskRepo.Find(query); err != nil {
 		statusBar.showForSeconds("[red::]Error: "+err.Error(), 5)
 	} else {
 		pane.SetList(tasks)
+		pane.addSubProjectTasks(project)
 	}
 
-	// 输入框正常高度
 	pane.RemoveItem(pane.hint)
-	pane.AddItem(pane.newTask, 1, 0, false)
+	pane.readOnly = project.Archived
+	if !pane.readOnly {
+		// 输入框正常高度
+		pane.AddItem(pane.newTask, 1, 0, false)
+	}
+	pane.reload = func() {
+		if fresh, err := pane.projectRepo.GetByID(project.ID); err == nil {
+			pane.LoadProjectTasks(fresh)
+		}
+	}
+}
+
+// addSubProjectTasks lists tasks of each (not archived) sub-project of a project in every depth, under its path
+func (pane *TaskPane) addSubProjectTasks(project model.Project) {
+	for _, sub := range repository.ProjectSubtree(projectPane.projects, project.ID) {
+		if sub.Archived {
+			continue
+		}
+
+		tasks, err := taskRepo.Find(repository.TaskQuery{ProjectID: sub.ID, OrderBy: "Position", PriorityFirst: true, AvailableBy: pane.availableBy(time.Now())})
+		if err != nil {
+			statusBar.showForSeconds("[red::]Error: "+err.Error(), 5)
+			return
+		}
+		if len(tasks) == 0 {
+			continue
+		}
+
+		if len(pane.taskOfItem) > 0 {
+			pane.addHeadingToList("")
+		}
+		pane.addHeadingToList("[::b]" + projectPane.projectPath(sub.ID, project.ID))
+
+		first := len(pane.tasks)
+		pane.tasks = append(pane.tasks, tasks...)
+		for idx := first; idx < len(pane.tasks); idx++ {
+			if pane.isTopLevel(idx) {
+				pane.addTaskTreeToList(idx)
+			}
+		}
+	}
+}
+
+// canEdit tells if listed tasks can be changed, explaining in status bar if they can not
+func (pane *TaskPane) canEdit() bool {
+	if pane.readOnly {
+		statusBar.showForSeconds("[yellow::]Project is archived, its tasks are read-only. Unarchive it to make changes", 5)
+		return false
+	}
+
+	return true
 }
 
 // LoadDynamicList loads tasks based on logic key
 func (pane *TaskPane) LoadDynamicList(logic string) {
-	var tasks []model.Task
-	var err error
-
 	today := toDate(time.Now())
-	zeroTime := time.Time{}
+	query := repository.TaskQuery{OrderBy: "ProjectID", PriorityFirst: true, AvailableBy: pane.availableBy(time.Now())}
 	rangeDesc := ""
 
 	switch logic {
 	case "today":
-		tasks, err = pane.taskRepo.GetAllByDate(today)
+		query.DueFrom, query.DueTo = today, today
 		rangeDesc = "Today"
 
 	case "tomorrow":
 		tomorrow := today.AddDate(0, 0, 1)
-		tasks, err = pane.taskRepo.GetAllByDate(tomorrow)
+		query.DueFrom, query.DueTo = tomorrow, tomorrow
+		query.AvailableBy = pane.availableBy(tomorrow)
 		rangeDesc = "Tomorrow"
 
 	case "upcoming":
 		week := today.Add(7 * 24 * time.Hour)
-		tasks, err = pane.taskRepo.GetAllByDateRange(today, week)
+		query.DueFrom, query.DueTo = today, week
 		rangeDesc = "Upcoming (next 7 days)"
 
 	case "unscheduled":
-		tasks, err = pane.taskRepo.GetAllByDate(zeroTime)
+		query.Unscheduled = true
 		rangeDesc = "Unscheduled (task with no due date) "
 	}
 
+	pane.loadQuery(query, rangeDesc)
+	if !query.DueFrom.IsZero() {
+		pane.workloadFrom, pane.workloadTo = query.DueFrom, query.DueTo
+		pane.showWorkload()
+	}
+	pane.reload = func() { pane.LoadDynamicList(logic) }
+}
+
+// availableBy provides the time tasks should be started by to be listed, zero time if deferred tasks are shown too
+func (pane *TaskPane) availableBy(at time.Time) time.Time {
+	if pane.showDeferred {
+		return time.Time{}
+	}
+
+	return at
+}
+
+// ToggleDeferred shows or hides tasks deferred to a later start date
+func (pane *TaskPane) ToggleDeferred() {
+	pane.showDeferred = !pane.showDeferred
+	pane.Reload()
+
+	if pane.showDeferred {
+		statusBar.showForSeconds("[yellow::]Showing deferred tasks. Press . to hide them", 5)
+	} else {
+		statusBar.showForSeconds("[yellow::]Deferred tasks are hidden until they start. Press . to show them", 5)
+	}
+}
+
+// reloadOnNewDay loads the list again when a new day starts, so that tasks starting (or due) on the day show up
+func (pane *TaskPane) reloadOnNewDay() {
+	for range time.Tick(time.Minute) {
+		app.QueueUpdateDraw(func() {
+			if pane.reload != nil && !pane.loadedOn.Equal(toDate(time.Now())) {
+				pane.Reload()
+			}
+		})
+	}
+}
+
+// LoadTagTasks loads tasks of all projects labeled with a tag
+func (pane *TaskPane) LoadTagTasks(tag string) {
+	query := repository.TaskQuery{Tag: tag, OrderBy: "ProjectID", PriorityFirst: true}
+	pane.loadQuery(query, "#"+tag)
+	pane.reload = func() { pane.LoadTagTasks(tag) }
+}
+
+// LoadReadyTasks loads open tasks of all projects which are not blocked by any other open task
+func (pane *TaskPane) LoadReadyTasks() {
+	tasks, err := repository.ReadyTasks(pane.taskRepo)
+	pane.loadTasks(tasks, err, "Ready (tasks not blocked by others)")
+	pane.reload = pane.LoadReadyTasks
+}
+
+// loadQuery loads the result of a task query which is not limited to a project
+func (pane *TaskPane) loadQuery(query repository.TaskQuery, rangeDesc string) {
+	tasks, err := pane.taskRepo.Find(query)
+	pane.loadTasks(tasks, err, rangeDesc)
+}
+
+// loadTasks lists tasks which are not limited to a project, reporting error of loading them
+func (pane *TaskPane) loadTasks(tasks []model.Task, err error, rangeDesc string) {
 	projectPane.activeProject = nil
 	taskPane.ClearList()
+	tasks = projectPane.withoutArchived(tasks)
+	projectPane.sortByProjects(tasks)
 
-	if err == storm.ErrNotFound {
+	if err != nil {
+		statusBar.showForSeconds("[red]Error: "+err.Error(), 5)
+	} else if len(tasks) == 0 {
 		statusBar.showForSeconds("[yellow]No Task in list - "+rangeDesc, 5)
 		pane.SetList(tasks)
-	} else if err != nil {
-		statusBar.showForSeconds("[red]Error: "+err.Error(), 5)
 	} else {
-		sort.Slice(tasks, func(i, j int) bool { return tasks[i].ProjectID < tasks[j].ProjectID })
 		pane.SetList(tasks)
 		app.SetFocus(taskPane)
 
@@ -204,22 +832,159 @@ func (pane *TaskPane) ActivateTask(idx int) {
 
 }
 
-// ClearCompletedTasks removes tasks from current list that are in completed state
+// ClearCompletedTasks moves tasks of current list that are in completed state to Trash, along with their subtasks
 func (pane *TaskPane) ClearCompletedTasks() {
-	count := 0
-	for i, task := range pane.tasks {
-		if task.Completed && pane.taskRepo.Delete(&pane.tasks[i]) == nil {
-			pane.list.RemoveItem(i)
-			count++
+	if !pane.canEdit() {
+		return
+	}
+
+	defer recordAction("Clear completed tasks")()
+	var completed []model.Task
+	for _, task := range pane.tasks {
+		if task.Completed {
+			completed = append(completed, task)
 		}
 	}
 
-	statusBar.showForSeconds(fmt.Sprintf("[yellow]%d tasks cleared!", count), 5)
+	var trashed []int64
+	err := transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+		var err error
+		trashed, err = repository.TrashTrees(tasks, completed)
+		return err
+	})
+	if err != nil {
+		statusBar.showForSeconds("[red]Could not move tasks to Trash: "+err.Error(), 5)
+		return
+	}
+
+	if project := projectPane.GetActiveProject(); project != nil {
+		pane.LoadProjectTasks(*project)
+	}
+	projectPane.refreshTags()
+	projectDetailPane.setCountsDisplay()
+	statusBar.showForSeconds(fmt.Sprintf("[yellow]%d tasks moved to Trash!", len(trashed)), 5)
+}
+
+// ShowCompletedSubtasks updates listed subtasks completed along with their parent
+func (pane *TaskPane) ShowCompletedSubtasks(subtasks []model.Task) {
+	for _, sub := range subtasks {
+		if idx := pane.indexOfTaskID(sub.ID); idx != -1 {
+			pane.tasks[idx].Completed = true
+			pane.tasks[idx].CompletedAt = sub.CompletedAt
+		}
+	}
+
+	if len(subtasks) > 0 {
+		statusBar.showForSeconds(fmt.Sprintf("[yellow::]Completed along with %d subtasks", len(subtasks)), 5)
+	}
+}
+
+// SetTaskTags replaces tags of a listed task and refreshes its listing and the tag list
+func (pane *TaskPane) SetTaskTags(task *model.Task, tags []string) {
+	if !pane.canEdit() {
+		return
+	}
+
+	defer recordAction("Change tags of " + task.Title)()
+	if err := pane.taskRepo.UpdateField(task, "Tags", tags); err != nil {
+		statusBar.showForSeconds("[red::]Could not update tags: "+err.Error(), 5)
+		return
+	}
+
+	task.Tags = tags
+	if idx := pane.indexOfTask(task); idx != -1 {
+		pane.refreshItem(idx)
+	}
+	if task == taskDetailPane.task {
+		taskDetailPane.refreshHistory()
+	}
+	projectPane.refreshTags()
+
+	statusBar.showForSeconds("[yellow::]Tags updated", 5)
+}
+
+// indexOfTask provides index of a task in pane.tasks, -1 if it is not there
+func (pane *TaskPane) indexOfTask(task *model.Task) int {
+	for i := range pane.tasks {
+		if &pane.tasks[i] == task {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// nextOccurrence finds due date of the next occurrence of a completed repeating task
+func nextOccurrence(task model.Task) (time.Time, error) {
+	rule, err := recurrence.Parse(task.Recurrence)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var due time.Time
+	if task.DueDate != 0 {
+		due = time.Unix(task.DueDate, 0)
+	}
+	next := rule.NextDueDate(due, time.Unix(task.CompletedAt, 0))
+	if next.IsZero() {
+		return next, errors.New("no next occurrence of " + rule.Describe())
+	}
+
+	return next, nil
+}
+
+// ShowNextOccurrence lists the next occurrence of a repeating task, created along with completing it
+func (pane *TaskPane) ShowNextOccurrence(task, occurrence model.Task) {
+	// 只在所属项目的任务列表中显示新任务
+	if project := projectPane.GetActiveProject(); project != nil && project.ID == occurrence.ProjectID {
+		idx := pane.appendTask(occurrence)
+		if parent := pane.indexOfTaskID(occurrence.ParentID); parent != -1 {
+			pane.refreshSubtree(parent)
+		} else {
+			pane.addTopLevelTask(idx)
+		}
+	} else if project != nil && pane.indexOfTaskID(task.ID) != -1 {
+		// A task of a sub-project, listed under its heading
+		pane.Reload()
+	}
+
+	statusBar.showForSeconds("[yellow::]Next occurrence is due on "+time.Unix(occurrence.DueDate, 0).Format(dateLayoutHuman), 5)
+}
+
+// Reload loads the current list again, keeping cursor position and the active task if it is still listed
+func (pane *TaskPane) Reload() {
+	if pane.reload == nil {
+		return
+	}
+
+	var activeID int64
+	if pane.activeTask != nil {
+		activeID = pane.activeTask.ID
+	}
+	focused := app.GetFocus()
+	current := pane.list.GetCurrentItem()
+
+	pane.reload()
+	pane.list.SetCurrentItem(current)
+
+	if idx := pane.indexOfTaskID(activeID); idx != -1 && pane.itemOfTask(idx) != -1 {
+		pane.ActivateTask(idx)
+	} else if activeID != 0 {
+		removeThirdCol()
+		if project := projectPane.GetActiveProject(); project != nil {
+			projectDetailPane.SetProject(project)
+			contents.AddItem(projectDetailPane, 25, 0, false)
+		}
+		if focused != projectPane.list {
+			focused = pane
+		}
+	}
+	app.SetFocus(focused)
 }
 
 // ReloadCurrentTask Loads the current task - in Task details and listing
 func (pane *TaskPane) ReloadCurrentTask() {
-	pane.list.SetItemText(pane.list.GetCurrentItem(), makeTaskListingTitle(*pane.activeTask), "")
+	pane.refreshListing()
 	taskDetailPane.SetTask(pane.activeTask)
 }
 
@@ -227,6 +992,7 @@ func (pane *TaskPane) ReloadCurrentTask() {
 func (pane *TaskPane) LoadTasksByYear(year string) {
 	// 清除当前任务列表和活动任务
 	pane.ClearList()
+	pane.reload = func() { pane.LoadTasksByYear(year) }
 	
 	// 获取所有项目
 	allProjects, err := pane.projectRepo.GetAll()
@@ -235,89 +1001,88 @@ func (pane *TaskPane) LoadTasksByYear(year string) {
 		return
 	}
 	
-	// 筛选该年份的项目和任务
-	var yearTasks []model.Task
-	projectTaskMap := make(map[string][]model.Task) // 项目名称 -> 任务列表
-	
+	// 筛选该年份的任务
+	yearNum, err := strconv.Atoi(year)
+	if err != nil {
+		statusBar.showForSeconds("[red::]Invalid year: "+year, 5)
+		return
+	}
+	firstDay := time.Date(yearNum, time.January, 1, 0, 0, 0, 0, time.Local)
+	lastDay := firstDay.AddDate(1, 0, -1)
+
+	yearTasks, err := pane.taskRepo.Find(repository.TaskQuery{DueFrom: firstDay, DueTo: lastDay, OrderBy: "DueDate"})
+	if err != nil {
+		statusBar.showForSeconds("[red::]Error loading tasks: "+err.Error(), 5)
+		return
+	}
+
+	projectTitles := make(map[int64]string) // 项目ID -> 项目名称
+	var projectOrder []string
 	for _, project := range allProjects {
-		tasks, err := pane.taskRepo.GetAllByProject(project)
-		if err != nil {
-			continue
-		}
-		
-		// 筛选该年份的任务
-		var projectYearTasks []model.Task
-		for _, task := range tasks {
-			if task.DueDate > 0 {
-				taskYear := time.Unix(task.DueDate, 0).Year()
-				if fmt.Sprintf("%d", taskYear) == year {
-					projectYearTasks = append(projectYearTasks, task)
-					yearTasks = append(yearTasks, task)
-				}
-			}
+		if !project.Archived {
+			projectTitles[project.ID] = project.Title
+			projectOrder = append(projectOrder, project.Title)
 		}
-		
-		// 如果该项目有该年份的任务，加入映射
-		if len(projectYearTasks) > 0 {
-			projectTaskMap[project.Title] = projectYearTasks
+	}
+
+	projectTaskMap := make(map[string][]model.Task) // 项目名称 -> 任务列表
+	for _, task := range yearTasks {
+		if title, ok := projectTitles[task.ProjectID]; ok {
+			projectTaskMap[title] = append(projectTaskMap[title], task)
 		}
 	}
-	
+
 	// 按项目分组显示任务
-	pane.displayTasksByYear(year, projectTaskMap)
+	pane.displayTasksByYear(year, projectOrder, projectTaskMap)
 	
 	pane.RemoveItem(pane.hint)
 	removeThirdCol()
 }
 
 // displayTasksByProject 按项目分组显示任务
-func (pane *TaskPane) displayTasksByYear(year string, projectTaskMap map[string][]model.Task) {
+func (pane *TaskPane) displayTasksByYear(year string, projectOrder []string, projectTaskMap map[string][]model.Task) {
 	pane.list.Clear()
 	pane.tasks = nil
+	pane.taskOfItem = nil
 	
 	// 添加年份标题（加粗显示，使用默认颜色）
-	pane.list.AddItem(fmt.Sprintf("[::b]%s", year), "", 0, nil)
-	pane.list.AddItem("", "", 0, nil) // 空行
+	pane.addHeadingToList(fmt.Sprintf("[::b]%s", year))
+	pane.addHeadingToList("") // 空行
 	
-	// 按项目名称排序
+	// 按项目的手动排序
 	var projectNames []string
-	for projectName := range projectTaskMap {
-		projectNames = append(projectNames, projectName)
+	listed := make(map[string]bool)
+	for _, projectName := range projectOrder {
+		if _, ok := projectTaskMap[projectName]; ok && !listed[projectName] {
+			projectNames = append(projectNames, projectName)
+			listed[projectName] = true
+		}
 	}
-	sort.Strings(projectNames)
 	
 	// 为每个项目显示任务
 	for i, projectName := range projectNames {
 		tasks := projectTaskMap[projectName]
 		
 		// 添加项目名称（加粗显示，使用默认颜色）
-		pane.list.AddItem(fmt.Sprintf("[::b]%s", projectName), "", 0, nil)
+		pane.addHeadingToList(fmt.Sprintf("[::b]%s", projectName))
 		
-		// 按日期排序任务
-		sort.Slice(tasks, func(i, j int) bool {
-			return tasks[i].DueDate < tasks[j].DueDate
-		})
-		
-		// 添加任务
-		for _, task := range tasks {
-			// 保存任务到列表中
-			pane.tasks = append(pane.tasks, task)
-			taskIndex := len(pane.tasks) - 1
-			
-			// 添加任务项
-			pane.list.AddItem(makeTaskListingTitle(task), "", 0, func(idx int) func() {
-				return func() { pane.ActivateTask(idx) }
-			}(taskIndex))
+		// 添加任务，子任务缩进显示在父任务下
+		first := len(pane.tasks)
+		pane.tasks = append(pane.tasks, tasks...)
+		for taskIndex := first; taskIndex < len(pane.tasks); taskIndex++ {
+			if pane.isTopLevel(taskIndex) {
+				pane.addTaskTreeToList(taskIndex)
+			}
 		}
 		
 		// 在项目之间添加空行（除了最后一个项目）
 		if i < len(projectNames)-1 {
-			pane.list.AddItem("", "", 0, nil)
+			pane.addHeadingToList("")
 		}
 	}
 	
 	if len(pane.tasks) == 0 {
-		pane.list.AddItem("[yellow]No tasks found for this year", "", 0, nil)
+		pane.addHeadingToList("[yellow]No tasks found for this year")
 	}
 }
 
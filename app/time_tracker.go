@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// TimeTracker starts and stops timers of tasks, showing the running one in title bar.
+// Timers run for tasks of the working project only.
+type TimeTracker struct {
+	*tview.TextView
+	running *model.TimeEntry
+	task    model.Task  // The task being tracked
+	ticking atomic.Bool // Whether a timer is running, read by the ticking goroutine
+}
+
+// NewTimeTracker initializes a TimeTracker, resuming the timer left running by last session
+func NewTimeTracker() *TimeTracker {
+	tracker := TimeTracker{
+		TextView: tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignCenter),
+	}
+	tracker.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+
+	if entry, err := taskRepo.GetRunningTimeEntry(); err == nil {
+		if task, err := taskRepo.GetByID(strconv.FormatInt(entry.TaskID, 10)); err == nil {
+			tracker.setRunning(&entry, task)
+		}
+	}
+	tracker.refresh()
+
+	return &tracker
+}
+
+// Toggle starts the timer of a task, or stops it if it is running already
+func (tt *TimeTracker) Toggle(task model.Task) {
+	if tt.running != nil && tt.task.ID == task.ID {
+		tt.Stop()
+		return
+	}
+
+	tt.Start(task)
+}
+
+// Start starts the timer of a task, stopping the running one. The project of the task becomes the working project.
+func (tt *TimeTracker) Start(task model.Task) {
+	if task.Completed {
+		statusBar.showForSeconds("[yellow::]Task is completed. Resume it to track time", 5)
+		return
+	}
+
+	var entry model.TimeEntry
+	idx := projectPane.indexOfProject(task.ProjectID)
+	switchProject := idx != -1 && !projectPane.projects[idx].Working
+	err := transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		if switchProject {
+			if err := projectPane.saveWorkingProject(projects, idx); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		entry, err = repository.StartTimer(tasks, task, time.Now())
+		return err
+	})
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not start timer: "+err.Error(), 5)
+		return
+	}
+
+	if switchProject {
+		projectPane.markWorkingProject(idx)
+		projectPane.refreshTags()
+	}
+
+	tt.setRunning(&entry, task)
+	tt.refreshDetails()
+	statusBar.showForSeconds("[lime::]Timer started: "+task.Title, 5)
+}
+
+// Stop stops the running timer, if any
+func (tt *TimeTracker) Stop() {
+	if tt.running == nil {
+		return
+	}
+
+	entry, err := repository.StopTimer(taskRepo, time.Now())
+	if err != nil && err != repository.ErrNotFound {
+		statusBar.showForSeconds("[red::]Could not stop timer: "+err.Error(), 5)
+		return
+	}
+
+	title := tt.task.Title
+	tt.setRunning(nil, model.Task{})
+	tt.refreshDetails()
+	statusBar.showForSeconds(fmt.Sprintf("[yellow::]Timer stopped: %s (%s)", title, formatDuration(time.Duration(entry.End-entry.Start)*time.Second)), 5)
+}
+
+// Check stops the running timer if its task is not open in the working project anymore,
+// e,g, when working project is switched or the task is completed.
+func (tt *TimeTracker) Check() {
+	if tt.running == nil {
+		return
+	}
+
+	task, err := taskRepo.GetByID(strconv.FormatInt(tt.task.ID, 10))
+	if err != nil || task.Completed || task.DeletedAt != 0 {
+		tt.Stop()
+		return
+	}
+
+	project, err := projectRepo.GetByID(task.ProjectID)
+	if err != nil || !project.Working || project.Archived || project.DeletedAt != 0 {
+		tt.Stop()
+		return
+	}
+	tt.task = task
+}
+
+// IsRunning tells if the timer of a task is running
+func (tt *TimeTracker) IsRunning(taskID int64) bool {
+	return tt.running != nil && tt.task.ID == taskID
+}
+
+func (tt *TimeTracker) setRunning(entry *model.TimeEntry, task model.Task) {
+	tt.running, tt.task = entry, task
+	tt.ticking.Store(entry != nil)
+	tt.refresh()
+}
+
+// refresh shows the running timer in title bar
+func (tt *TimeTracker) refresh() {
+	if tt.running == nil {
+		tt.SetText("")
+		return
+	}
+
+	elapsed := time.Since(time.Unix(tt.running.Start, 0)).Truncate(time.Second)
+	tt.SetText(fmt.Sprintf("[orange]⏱ %s [::b]%s", tt.task.Title, formatClock(elapsed)))
+}
+
+// refreshDetails updates tracked time shown in detail panes
+func (tt *TimeTracker) refreshDetails() {
+	if taskDetailPane.task != nil {
+		taskDetailPane.setTrackedDisplay()
+	}
+	if projectPane.activeProject != nil {
+		projectDetailPane.setEffortDisplay()
+	}
+}
+
+// tick refreshes the running timer every second, until the app stops
+func (tt *TimeTracker) tick() {
+	for range time.Tick(time.Second) {
+		if tt.ticking.Load() {
+			app.QueueUpdateDraw(tt.refresh)
+		}
+	}
+}
+
+// formatClock formats elapsed time of a running timer, like 1:05:09
+func formatClock(d time.Duration) string {
+	seconds := int64(d.Seconds())
+	return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// formatDuration formats tracked time in hours and minutes, like 3h 05m
+func formatDuration(d time.Duration) string {
+	minutes := int64(d.Minutes())
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+// describeEffort shows estimated effort against the actual one, in red if the estimate is exceeded
+func describeEffort(estimate, actual time.Duration) string {
+	text := "[::d]not estimated[::-]"
+	if estimate > 0 {
+		text = "[#5FAFD7]" + formatDuration(estimate) + "[-] est."
+	}
+
+	switch {
+	case actual == 0:
+		return text
+	case estimate == 0:
+		return text + " · " + formatDuration(actual) + " actual"
+	case actual > estimate:
+		return text + fmt.Sprintf(" · [red]%s actual (%d%%)[-]", formatDuration(actual), actual*100/estimate)
+	}
+
+	return text + fmt.Sprintf(" · %s actual (%d%%)", formatDuration(actual), actual*100/estimate)
+}
+
+// formatEffortInput formats minutes of effort for editing, empty if there is none
+func formatEffortInput(minutes int) string {
+	if minutes == 0 {
+		return ""
+	}
+
+	return repository.FormatEffort(minutes)
+}
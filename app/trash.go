@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"time"
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// TrashPane lists trashed projects and tasks, to restore or delete them forever.
+// It takes the place of TaskPane while showing.
+type TrashPane struct {
+	*tview.Flex
+	list     *tview.List
+	projects []model.Project
+	tasks    []model.Task
+	itemOf   []trashItem // What each list item stands for
+	showing  bool
+
+	projectRepo repository.ProjectRepository
+	taskRepo    repository.TaskRepository
+}
+
+// trashItem points to a trashed project or task of TrashPane, -1 for none (headings)
+type trashItem struct {
+	project, task int
+}
+
+// NewTrashPane initializes and configures a TrashPane
+func NewTrashPane(projectRepo repository.ProjectRepository, taskRepo repository.TaskRepository) *TrashPane {
+	pane := TrashPane{
+		Flex:        tview.NewFlex().SetDirection(tview.FlexRow),
+		list:        tview.NewList().ShowSecondaryText(false),
+		projectRepo: projectRepo,
+		taskRepo:    taskRepo,
+	}
+
+	pane.list.SetSelectedBackgroundColor(tcell.ColorWhite)
+	pane.list.SetSelectedTextColor(tcell.ColorBlack)
+	pane.list.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+	pane.list.SetDoneFunc(func() {
+		app.SetFocus(projectPane)
+	})
+
+	hint := tview.NewTextView().SetTextColor(tcell.ColorDimGray).SetTextAlign(tview.AlignCenter).
+		SetText("r = restore, x = delete forever, E = empty trash")
+
+	pane.
+		AddItem(pane.list, 0, 1, true).
+		AddItem(hint, 1, 0, false)
+
+	pane.SetBorder(true).SetTitle("[::u]T[::-]rash").SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+
+	return &pane
+}
+
+// Show loads trashed items and shows TrashPane in place of TaskPane
+func (pane *TrashPane) Show() {
+	projectPane.activeProject = nil
+	removeThirdCol()
+	if !pane.showing {
+		contents.RemoveItem(taskPane)
+		contents.AddItem(pane, 0, 2, false)
+		pane.showing = true
+	}
+
+	pane.load()
+	app.SetFocus(pane)
+}
+
+// Hide puts TaskPane back in place of TrashPane
+func (pane *TrashPane) Hide() {
+	if pane.showing {
+		contents.RemoveItem(pane)
+		contents.AddItem(taskPane, 0, 2, false)
+		pane.showing = false
+	}
+}
+
+func (pane *TrashPane) load() {
+	pane.list.Clear()
+	pane.itemOf = nil
+
+	var err error
+	if pane.projects, err = pane.projectRepo.GetTrashed(); err != nil {
+		statusBar.showForSeconds("[red::]Could not load trashed projects: "+err.Error(), 5)
+	}
+	if pane.tasks, err = pane.taskRepo.Find(repository.TaskQuery{Trash: repository.TrashedOnly, OrderBy: "ProjectID"}); err != nil {
+		statusBar.showForSeconds("[red::]Could not load trashed tasks: "+err.Error(), 5)
+	}
+
+	if len(pane.projects) > 0 {
+		pane.addHeading("Projects")
+		for i, project := range pane.projects {
+			if !pane.isProjectTrashRoot(project) {
+				continue
+			}
+
+			// Sub-projects trashed together are restored and deleted along with their parent
+			together := map[int64]bool{project.ID: true}
+			for _, sub := range repository.ProjectSubtree(pane.projects, project.ID) {
+				together[sub.ID] = sub.DeletedAt == project.DeletedAt
+			}
+
+			count := 0
+			for _, task := range pane.tasks {
+				if together[task.ProjectID] && task.DeletedAt == project.DeletedAt {
+					count++
+				}
+			}
+			pane.addItem(fmt.Sprintf("%s [::d](%d tasks) · %s", project.Title, count, trashAge(project.DeletedAt)), trashItem{i, -1})
+		}
+	}
+
+	projectTitles := make(map[int64]string)
+	heading := false
+	for i, task := range pane.tasks {
+		if !pane.isTrashRoot(task) {
+			continue
+		}
+		if !heading {
+			pane.addHeading("Tasks")
+			heading = true
+		}
+
+		title, ok := projectTitles[task.ProjectID]
+		if !ok {
+			if project, err := pane.projectRepo.GetByID(task.ProjectID); err == nil {
+				title = project.Title
+			}
+			projectTitles[task.ProjectID] = title
+		}
+		pane.addItem(fmt.Sprintf("%s [::d]%s · %s", makeTaskListingTitle(task, "", nil), title, trashAge(task.DeletedAt)), trashItem{-1, i})
+	}
+
+	if len(pane.itemOf) == 0 {
+		pane.addHeading("Trash is empty")
+	}
+}
+
+// isProjectTrashRoot tells if a trashed project is listed on its own, i,e, it was not trashed along with its parent
+func (pane *TrashPane) isProjectTrashRoot(project model.Project) bool {
+	for _, other := range pane.projects {
+		if other.ID == project.ParentID && other.DeletedAt == project.DeletedAt {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isTrashRoot tells if a trashed task is listed on its own,
+// i,e, it was not trashed along with its project or parent task
+func (pane *TrashPane) isTrashRoot(task model.Task) bool {
+	for _, project := range pane.projects {
+		if project.ID == task.ProjectID && project.DeletedAt == task.DeletedAt {
+			return false
+		}
+	}
+	for _, other := range pane.tasks {
+		if other.ID == task.ParentID && other.DeletedAt == task.DeletedAt {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (pane *TrashPane) addHeading(text string) {
+	pane.list.AddItem("[::d]"+text, "", 0, nil)
+	pane.itemOf = append(pane.itemOf, trashItem{-1, -1})
+}
+
+func (pane *TrashPane) addItem(text string, item trashItem) {
+	pane.list.AddItem(" "+text, "", 0, nil)
+	pane.itemOf = append(pane.itemOf, item)
+}
+
+// currentItem provides the trashed project or task under cursor
+func (pane *TrashPane) currentItem() trashItem {
+	idx := pane.list.GetCurrentItem()
+	if idx < 0 || idx >= len(pane.itemOf) {
+		return trashItem{-1, -1}
+	}
+
+	return pane.itemOf[idx]
+}
+
+// restore takes the project or task under cursor out of trash
+func (pane *TrashPane) restore() {
+	var err error
+	var title string
+
+	defer recordAction("Restore from Trash")()
+	switch item := pane.currentItem(); {
+	case item.project != -1:
+		project := pane.projects[item.project]
+		title = project.Title
+		err = transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+			return repository.RestoreProject(projects, tasks, &project)
+		})
+	case item.task != -1:
+		task := pane.tasks[item.task]
+		title = task.Title
+		err = transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+			return repository.RestoreTree(tasks, task)
+		})
+	default:
+		return
+	}
+
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not restore: "+err.Error(), 5)
+		return
+	}
+
+	pane.reload()
+	statusBar.showForSeconds("[lime]Restored: "+title, 5)
+}
+
+// deleteForever permanently deletes the project or task under cursor, after confirmation
+func (pane *TrashPane) deleteForever() {
+	switch item := pane.currentItem(); {
+	case item.project != -1:
+		project := pane.projects[item.project]
+		AskYesNo("Delete project \""+project.Title+"\" and its tasks forever?", func() {
+			err := transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+				_, err := repository.DeleteProject(projects, tasks, project)
+				return err
+			})
+			pane.afterDelete(project.Title, err)
+		})
+	case item.task != -1:
+		task := pane.tasks[item.task]
+		AskYesNo("Delete task \""+task.Title+"\" forever?", func() {
+			err := transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+				_, err := repository.DeleteTree(tasks, task)
+				return err
+			})
+			pane.afterDelete(task.Title, err)
+		})
+	}
+}
+
+func (pane *TrashPane) afterDelete(title string, err error) {
+	pane.reload()
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not delete: "+err.Error(), 5)
+		return
+	}
+	statusBar.showForSeconds("[yellow::]Deleted forever: "+title, 5)
+}
+
+// empty permanently deletes everything in trash, after confirmation
+func (pane *TrashPane) empty() {
+	AskYesNo("Delete everything in Trash forever?", func() {
+		var purged int
+		err := transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+			var err error
+			purged, err = repository.PurgeTrash(projects, tasks, time.Now().Add(time.Minute))
+			return err
+		})
+		pane.reload()
+		if err != nil {
+			statusBar.showForSeconds("[red::]Could not empty trash: "+err.Error(), 5)
+			return
+		}
+		statusBar.showForSeconds(fmt.Sprintf("[yellow::]%d items deleted forever", purged), 5)
+	})
+}
+
+// reload refreshes trash listing and Projects pane, keeping cursor position
+func (pane *TrashPane) reload() {
+	current := pane.list.GetCurrentItem()
+	pane.load()
+	pane.list.SetCurrentItem(current)
+	projectPane.refreshTags()
+}
+
+func (pane *TrashPane) handleShortcuts(event *tcell.EventKey) *tcell.EventKey {
+	switch event.Rune() {
+	case 'E':
+		pane.empty()
+		return nil
+	}
+
+	switch unicode.ToLower(event.Rune()) {
+	case 'j':
+		pane.list.SetCurrentItem(pane.list.GetCurrentItem() + 1)
+		return nil
+	case 'k':
+		pane.list.SetCurrentItem(pane.list.GetCurrentItem() - 1)
+		return nil
+	case 'h':
+		app.SetFocus(projectPane)
+		return nil
+	case 'r':
+		pane.restore()
+		return nil
+	case 'x':
+		pane.deleteForever()
+		return nil
+	}
+
+	return event
+}
+
+// trashAge describes since when an item is in trash, and when it will be deleted automatically
+func trashAge(deletedAt int64) string {
+	trashed := time.Unix(deletedAt, 0)
+	text := "trashed " + trashed.Format(timeLayoutHuman)
+
+	if trashDays > 0 {
+		left := int(time.Until(trashed.AddDate(0, 0, trashDays)).Hours()/24) + 1
+		text += fmt.Sprintf(", deleted in %d days", left)
+	}
+
+	return text
+}
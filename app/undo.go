@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/ajaxray/geek-life/undo"
+)
+
+// recordAction starts recording changes of a user action to undo later, and returns the function to finish it.
+// Usage: defer recordAction("Rename task")()
+func recordAction(description string) func() {
+	if undoManager == nil {
+		return func() {}
+	}
+
+	undoManager.Begin(description)
+	return undoManager.End
+}
+
+// undoLastAction reverts the last recorded action and refreshes views
+func undoLastAction() {
+	if undoManager == nil {
+		return
+	}
+
+	description, err := undoManager.Undo()
+	if errors.Is(err, undo.ErrNothingToUndo) {
+		statusBar.showForSeconds("[yellow::]Nothing to undo", 5)
+		return
+	}
+
+	refreshViews()
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not undo "+description+": "+err.Error(), 5)
+		return
+	}
+	statusBar.showForSeconds("[yellow::]Undone: "+description, 5)
+}
+
+// redoLastAction applies the last undone action again and refreshes views
+func redoLastAction() {
+	if undoManager == nil {
+		return
+	}
+
+	description, err := undoManager.Redo()
+	if errors.Is(err, undo.ErrNothingToRedo) {
+		statusBar.showForSeconds("[yellow::]Nothing to redo", 5)
+		return
+	}
+
+	refreshViews()
+	if err != nil {
+		statusBar.showForSeconds("[red::]Could not redo "+description+": "+err.Error(), 5)
+		return
+	}
+	statusBar.showForSeconds("[yellow::]Redone: "+description, 5)
+}
+
+// refreshViews loads projects and tasks again after they are changed behind the panes
+func refreshViews() {
+	active := projectPane.activeProject
+	projectPane.refreshTags()
+	timeTracker.Check()
+
+	if active != nil && projectPane.activeProject == active {
+		// Active project is not listed anymore
+		projectPane.activeProject = nil
+		taskPane.ClearList()
+		removeThirdCol()
+		return
+	}
+	if projectPane.activeProject != nil {
+		projectDetailPane.SetProject(projectPane.activeProject)
+	}
+
+	if trashPane.showing {
+		trashPane.reload()
+	} else {
+		taskPane.Reload()
+	}
+}
@@ -1,334 +1,469 @@
-package main
-
-import (
-	"fmt"
-	"time"
-
-	"github.com/gdamore/tcell/v2"
-	"github.com/pgavlin/femto"
-	"github.com/rivo/tview"
-
-	"github.com/ajaxray/geek-life/model"
-)
-
-var blankCell = func() *tview.TextView {
-	cell := tview.NewTextView()
-	cell.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-	return cell
-}()
-
-func makeHorizontalLine(lineChar rune, color tcell.Color) *tview.TextView {
-	hr := tview.NewTextView()
-	hr.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
-	hr.SetDrawFunc(func(screen tcell.Screen, x int, y int, width int, height int) (int, int, int, int) {
-		// Draw a horizontal line across the middle of the box.
-		style := tcell.StyleDefault.Foreground(color).Background(tcell.NewHexColor(0x0c0c0c))
-		centerY := y + height/2
-		for cx := x; cx < x+width; cx++ {
-			screen.SetContent(cx, centerY, lineChar, nil, style)
-		}
-
-		// Space for other content.
-		return x + 1, centerY + 1, width - 2, height - (centerY + 1 - y)
-	})
-
-	return hr
-}
-
-// CustomInputBox 创建一个自定义的输入框组件，使用Box包装InputField
-type CustomInputBox struct {
-	*tview.Box
-	input *tview.InputField
-	placeholder string
-}
-
-func NewCustomInputBox(placeholder string) *CustomInputBox {
-	input := tview.NewInputField()
-	input.SetPlaceholder(placeholder)
-	
-	box := &CustomInputBox{
-		Box: tview.NewBox(),
-		input: input,
-		placeholder: placeholder,
-	}
-	
-	// 设置Box的背景色
-	box.SetBackgroundColor(tcell.NewHexColor(0x484848))
-	
-	// 设置输入框的透明背景
-	input.SetBackgroundColor(tcell.ColorNone)
-	input.SetFieldBackgroundColor(tcell.ColorNone)
-	input.SetFieldTextColor(tcell.NewHexColor(0xC98E40))
-	input.SetPlaceholderTextColor(tcell.NewHexColor(0xC98E40))
-	
-	return box
-}
-
-func (c *CustomInputBox) Draw(screen tcell.Screen) {
-	// 先绘制背景Box
-	c.Box.Draw(screen)
-	
-	// 获取Box的坐标
-	x, y, width, height := c.GetInnerRect()
-	
-	// 绘制自定义背景
-	bgStyle := tcell.StyleDefault.Background(tcell.NewHexColor(0x484848))
-	for cy := y; cy < y+height; cy++ {
-		for cx := x; cx < x+width; cx++ {
-			screen.SetContent(cx, cy, ' ', nil, bgStyle)
-		}
-	}
-	
-	// 设置输入框的位置并绘制
-	c.input.SetRect(x, y, width, height)
-	c.input.Draw(screen)
-}
-
-func (c *CustomInputBox) GetText() string {
-	return c.input.GetText()
-}
-
-func (c *CustomInputBox) SetText(text string) {
-	c.input.SetText(text)
-}
-
-func (c *CustomInputBox) SetDoneFunc(handler func(key tcell.Key)) {
-	c.input.SetDoneFunc(handler)
-}
-
-func (c *CustomInputBox) SetChangedFunc(handler func(text string)) {
-	c.input.SetChangedFunc(handler)
-}
-
-func (c *CustomInputBox) HasFocus() bool {
-	return c.input.HasFocus()
-}
-
-func (c *CustomInputBox) Focus(delegate func(p tview.Primitive)) {
-	c.input.Focus(delegate)
-}
-
-func (c *CustomInputBox) Blur() {
-	c.input.Blur()
-}
-
-func (c *CustomInputBox) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
-	return c.input.InputHandler()
-}
-
-// makeCustomStyledInput 创建一个具有自定义样式的输入框，专门用于 New Project 和 New Task
-func makeCustomStyledInput(placeholder string) *tview.InputField {
-	// 自定义颜色：#484848 背景，#B9C87F 文字
-	bgColor := tcell.NewRGBColor(72, 72, 72)
-	// #B9C87F = RGB(185, 200, 127)
-	textColor := tcell.NewRGBColor(185, 200, 127)
-	
-	// 在创建输入框之前，临时修改全局样式
-	originalContrastBg := tview.Styles.ContrastBackgroundColor
-	tview.Styles.ContrastBackgroundColor = bgColor
-	
-	// 创建输入框
-	input := tview.NewInputField()
-	input.SetPlaceholder(placeholder)
-	
-	// 立即恢复全局样式
-	tview.Styles.ContrastBackgroundColor = originalContrastBg
-	
-	// 强制样式应用函数 - 无论是否有焦点都保持自定义颜色
-	applyCustomStyle := func() {
-		// 重新临时设置全局样式
-		originalBg := tview.Styles.ContrastBackgroundColor
-		tview.Styles.ContrastBackgroundColor = bgColor
-		
-		input.SetPlaceholderTextColor(textColor)
-		input.SetFieldTextColor(textColor)
-		input.SetFieldBackgroundColor(bgColor)
-		input.SetBackgroundColor(bgColor)
-		input.SetFieldStyle(tcell.StyleDefault.
-			Background(bgColor).
-			Foreground(textColor))
-		
-		// 恢复全局样式
-		tview.Styles.ContrastBackgroundColor = originalBg
-	}
-	
-	// 初始设置
-	applyCustomStyle()
-	
-	// 重写聚焦处理 - 保持自定义文字颜色，只改变背景
-	input.SetFocusFunc(func() {
-		// 聚焦时保持文字颜色为 #B9C87F，背景改为蓝色
-		input.SetFieldBackgroundColor(tcell.ColorBlue)
-		input.SetFieldTextColor(textColor) // 保持自定义文字颜色
-		input.SetPlaceholderTextColor(textColor) // 保持自定义文字颜色
-		input.SetBackgroundColor(tcell.ColorBlue)
-		input.SetFieldStyle(tcell.StyleDefault.
-			Background(tcell.ColorBlue).
-			Foreground(textColor)) // 保持自定义文字颜色
-	})
-	
-	// 失去焦点时：恢复自定义背景和文字颜色
-	input.SetBlurFunc(func() {
-		applyCustomStyle()
-	})
-	
-	// 设置Done回调来处理ESC键
-	input.SetDoneFunc(func(key tcell.Key) {
-		if key == tcell.KeyEscape {
-			// ESC键时恢复自定义样式
-			applyCustomStyle()
-		}
-		// ESC或Enter键时都可以触发失去焦点的行为
-	})
-	
-	return input
-}
-
-func makeLightTextInput(placeholder string) *tview.InputField {
-	// 对于 New Project 和 New Task，使用自定义样式
-	if placeholder == "+[New Project]" || placeholder == "+[New Task]" {
-		return makeCustomStyledInput(placeholder)
-	}
-	
-	// 其他输入框使用原来的样式
-	input := tview.NewInputField()
-	input.SetPlaceholder(placeholder)
-	
-	// 默认状态：深灰色背景 + 绿色文字
-	input.SetPlaceholderTextColor(tcell.ColorGreen)
-	input.SetFieldTextColor(tcell.ColorGreen)
-	input.SetFieldBackgroundColor(tcell.NewHexColor(0x404040))
-	input.SetBackgroundColor(tcell.NewHexColor(0x404040))
-	
-	// 强制设置默认样式
-	input.SetFieldStyle(tcell.StyleDefault.
-		Background(tcell.NewHexColor(0x404040)).
-		Foreground(tcell.ColorGreen))
-	
-	// 聚焦时：蓝色背景 + 白色文字
-	input.SetFocusFunc(func() {
-		input.SetFieldBackgroundColor(tcell.ColorBlue)
-		input.SetFieldTextColor(tcell.ColorWhite)
-		input.SetPlaceholderTextColor(tcell.ColorWhite)
-		// 强制设置聚焦样式
-		input.SetFieldStyle(tcell.StyleDefault.
-			Background(tcell.ColorBlue).
-			Foreground(tcell.ColorWhite))
-	})
-	
-	// 失去焦点时：深灰色背景 + 绿色文字
-	input.SetBlurFunc(func() {
-		input.SetFieldBackgroundColor(tcell.NewHexColor(0x404040))
-		input.SetFieldTextColor(tcell.ColorGreen)
-		input.SetPlaceholderTextColor(tcell.ColorGreen)
-		// 强制设置失焦样式
-		input.SetFieldStyle(tcell.StyleDefault.
-			Background(tcell.NewHexColor(0x404040)).
-			Foreground(tcell.ColorGreen))
-	})
-	
-	return input
-}
-
-// If input text is a valid date, parse it. Or get current date
-func parseDateInputOrCurrent(inputText string) time.Time {
-	if dateTime, err := time.Parse(dateLayoutISO, inputText); err == nil {
-		return toDate(dateTime)
-	}
-
-	return toDate(time.Now())
-}
-
-func toDate(dateTime time.Time) time.Time {
-	return time.Date(dateTime.Year(), dateTime.Month(), dateTime.Day(), 0, 0, 0, 0, time.Local)
-}
-
-func makeButton(label string, handler func()) *tview.Button {
-	btn := tview.NewButton(label)
-	btn.SetSelectedFunc(handler)
-	btn.SetLabelColor(tcell.ColorWhite)
-	btn.SetBackgroundColor(tcell.ColorCornflowerBlue)  // 保持蓝色用于普通按钮
-	btn.SetBorder(false)
-
-	return btn
-}
-
-func ignoreKeyEvt() bool {
-	// 检查项目输入框
-	if projectPane != nil && projectPane.newProject != nil && projectPane.newProject.HasFocus() {
-		return true
-	}
-	
-	// 检查项目重命名输入框
-	if projectPane != nil && projectPane.renameInput != nil && projectPane.renameInput.HasFocus() {
-		return true
-	}
-	
-	// 检查任务输入框
-	if taskPane != nil && taskPane.newTask != nil && taskPane.newTask.HasFocus() {
-		return true
-	}
-	
-	// 检查重命名输入框
-	if taskDetailPane != nil && taskDetailPane.header != nil && 
-	   taskDetailPane.header.renameText != nil && taskDetailPane.header.renameText.HasFocus() {
-		return true
-	}
-	
-	// 检查日期输入框
-	if taskDetailPane != nil && taskDetailPane.taskDate != nil && taskDetailPane.taskDate.HasFocus() {
-		return true
-	}
-	
-	// 检查femto编辑器
-	focused := app.GetFocus()
-	if focused != nil {
-		if _, ok := focused.(*femto.View); ok {
-			return true
-		}
-	}
-	
-	return false
-}
-
-// yetToImplement - to use as callback for unimplemented features
-// `yetToImplement` is unused (deadcode)
-// func yetToImplement(feature string) func() {
-// 	message := fmt.Sprintf("[yellow]%s is yet to implement. Please Check in next version.", feature)
-// 	return func() { statusBar.showForSeconds(message, 5) }
-// }
-
-func removeThirdCol() {
-	contents.RemoveItem(taskDetailPane)
-	contents.RemoveItem(projectDetailPane)
-}
-
-func getTaskTitleColor(task model.Task) string {
-	if task.Completed {
-		// 已完成任务使用 #3C8100
-		return "#3C8100"
-	} else {
-		// 未完成任务统一使用 #51AD00，不受截止日期影响
-		return "#51AD00"
-	}
-}
-
-func makeTaskListingTitle(task model.Task) string {
-	checkbox := "[ []"
-	if task.Completed {
-		checkbox = "[x[]"
-	}
-
-	return fmt.Sprintf("[%s]%s %s", getTaskTitleColor(task), checkbox, task.Title)
-}
-
-// `findProjectByID` is unused (deadcode)
-// func findProjectByID(id int64) *model.Project {
-// 	for i := range projectPane.projects {
-// 		if projectPane.projects[i].ID == id {
-// 			return &projectPane.projects[i]
-// 		}
-// 	}
-
-// 	return nil
-// }
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/pgavlin/femto"
+	"github.com/rivo/tview"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+var blankCell = func() *tview.TextView {
+	cell := tview.NewTextView()
+	cell.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+	return cell
+}()
+
+func makeHorizontalLine(lineChar rune, color tcell.Color) *tview.TextView {
+	hr := tview.NewTextView()
+	hr.SetBackgroundColor(tcell.NewHexColor(0x0c0c0c))
+	hr.SetDrawFunc(func(screen tcell.Screen, x int, y int, width int, height int) (int, int, int, int) {
+		// Draw a horizontal line across the middle of the box.
+		style := tcell.StyleDefault.Foreground(color).Background(tcell.NewHexColor(0x0c0c0c))
+		centerY := y + height/2
+		for cx := x; cx < x+width; cx++ {
+			screen.SetContent(cx, centerY, lineChar, nil, style)
+		}
+
+		// Space for other content.
+		return x + 1, centerY + 1, width - 2, height - (centerY + 1 - y)
+	})
+
+	return hr
+}
+
+// CustomInputBox 创建一个自定义的输入框组件，使用Box包装InputField
+type CustomInputBox struct {
+	*tview.Box
+	input *tview.InputField
+	placeholder string
+}
+
+func NewCustomInputBox(placeholder string) *CustomInputBox {
+	input := tview.NewInputField()
+	input.SetPlaceholder(placeholder)
+	
+	box := &CustomInputBox{
+		Box: tview.NewBox(),
+		input: input,
+		placeholder: placeholder,
+	}
+	
+	// 设置Box的背景色
+	box.SetBackgroundColor(tcell.NewHexColor(0x484848))
+	
+	// 设置输入框的透明背景
+	input.SetBackgroundColor(tcell.ColorNone)
+	input.SetFieldBackgroundColor(tcell.ColorNone)
+	input.SetFieldTextColor(tcell.NewHexColor(0xC98E40))
+	input.SetPlaceholderTextColor(tcell.NewHexColor(0xC98E40))
+	
+	return box
+}
+
+func (c *CustomInputBox) Draw(screen tcell.Screen) {
+	// 先绘制背景Box
+	c.Box.Draw(screen)
+	
+	// 获取Box的坐标
+	x, y, width, height := c.GetInnerRect()
+	
+	// 绘制自定义背景
+	bgStyle := tcell.StyleDefault.Background(tcell.NewHexColor(0x484848))
+	for cy := y; cy < y+height; cy++ {
+		for cx := x; cx < x+width; cx++ {
+			screen.SetContent(cx, cy, ' ', nil, bgStyle)
+		}
+	}
+	
+	// 设置输入框的位置并绘制
+	c.input.SetRect(x, y, width, height)
+	c.input.Draw(screen)
+}
+
+func (c *CustomInputBox) GetText() string {
+	return c.input.GetText()
+}
+
+func (c *CustomInputBox) SetText(text string) {
+	c.input.SetText(text)
+}
+
+func (c *CustomInputBox) SetDoneFunc(handler func(key tcell.Key)) {
+	c.input.SetDoneFunc(handler)
+}
+
+func (c *CustomInputBox) SetChangedFunc(handler func(text string)) {
+	c.input.SetChangedFunc(handler)
+}
+
+func (c *CustomInputBox) HasFocus() bool {
+	return c.input.HasFocus()
+}
+
+func (c *CustomInputBox) Focus(delegate func(p tview.Primitive)) {
+	c.input.Focus(delegate)
+}
+
+func (c *CustomInputBox) Blur() {
+	c.input.Blur()
+}
+
+func (c *CustomInputBox) InputHandler() func(event *tcell.EventKey, setFocus func(p tview.Primitive)) {
+	return c.input.InputHandler()
+}
+
+// makeCustomStyledInput 创建一个具有自定义样式的输入框，专门用于 New Project 和 New Task
+func makeCustomStyledInput(placeholder string) *tview.InputField {
+	// 自定义颜色：#484848 背景，#B9C87F 文字
+	bgColor := tcell.NewRGBColor(72, 72, 72)
+	// #B9C87F = RGB(185, 200, 127)
+	textColor := tcell.NewRGBColor(185, 200, 127)
+	
+	// 在创建输入框之前，临时修改全局样式
+	originalContrastBg := tview.Styles.ContrastBackgroundColor
+	tview.Styles.ContrastBackgroundColor = bgColor
+	
+	// 创建输入框
+	input := tview.NewInputField()
+	input.SetPlaceholder(placeholder)
+	
+	// 立即恢复全局样式
+	tview.Styles.ContrastBackgroundColor = originalContrastBg
+	
+	// 强制样式应用函数 - 无论是否有焦点都保持自定义颜色
+	applyCustomStyle := func() {
+		// 重新临时设置全局样式
+		originalBg := tview.Styles.ContrastBackgroundColor
+		tview.Styles.ContrastBackgroundColor = bgColor
+		
+		input.SetPlaceholderTextColor(textColor)
+		input.SetFieldTextColor(textColor)
+		input.SetFieldBackgroundColor(bgColor)
+		input.SetBackgroundColor(bgColor)
+		input.SetFieldStyle(tcell.StyleDefault.
+			Background(bgColor).
+			Foreground(textColor))
+		
+		// 恢复全局样式
+		tview.Styles.ContrastBackgroundColor = originalBg
+	}
+	
+	// 初始设置
+	applyCustomStyle()
+	
+	// 重写聚焦处理 - 保持自定义文字颜色，只改变背景
+	input.SetFocusFunc(func() {
+		// 聚焦时保持文字颜色为 #B9C87F，背景改为蓝色
+		input.SetFieldBackgroundColor(tcell.ColorBlue)
+		input.SetFieldTextColor(textColor) // 保持自定义文字颜色
+		input.SetPlaceholderTextColor(textColor) // 保持自定义文字颜色
+		input.SetBackgroundColor(tcell.ColorBlue)
+		input.SetFieldStyle(tcell.StyleDefault.
+			Background(tcell.ColorBlue).
+			Foreground(textColor)) // 保持自定义文字颜色
+	})
+	
+	// 失去焦点时：恢复自定义背景和文字颜色
+	input.SetBlurFunc(func() {
+		applyCustomStyle()
+	})
+	
+	// 设置Done回调来处理ESC键
+	input.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			// ESC键时恢复自定义样式
+			applyCustomStyle()
+		}
+		// ESC或Enter键时都可以触发失去焦点的行为
+	})
+	
+	return input
+}
+
+func makeLightTextInput(placeholder string) *tview.InputField {
+	// 对于 New Project 和 New Task，使用自定义样式
+	if placeholder == "+[New Project]" || placeholder == "+[New Task]" {
+		return makeCustomStyledInput(placeholder)
+	}
+	
+	// 其他输入框使用原来的样式
+	input := tview.NewInputField()
+	input.SetPlaceholder(placeholder)
+	
+	// 默认状态：深灰色背景 + 绿色文字
+	input.SetPlaceholderTextColor(tcell.ColorGreen)
+	input.SetFieldTextColor(tcell.ColorGreen)
+	input.SetFieldBackgroundColor(tcell.NewHexColor(0x404040))
+	input.SetBackgroundColor(tcell.NewHexColor(0x404040))
+	
+	// 强制设置默认样式
+	input.SetFieldStyle(tcell.StyleDefault.
+		Background(tcell.NewHexColor(0x404040)).
+		Foreground(tcell.ColorGreen))
+	
+	// 聚焦时：蓝色背景 + 白色文字
+	input.SetFocusFunc(func() {
+		input.SetFieldBackgroundColor(tcell.ColorBlue)
+		input.SetFieldTextColor(tcell.ColorWhite)
+		input.SetPlaceholderTextColor(tcell.ColorWhite)
+		// 强制设置聚焦样式
+		input.SetFieldStyle(tcell.StyleDefault.
+			Background(tcell.ColorBlue).
+			Foreground(tcell.ColorWhite))
+	})
+	
+	// 失去焦点时：深灰色背景 + 绿色文字
+	input.SetBlurFunc(func() {
+		input.SetFieldBackgroundColor(tcell.NewHexColor(0x404040))
+		input.SetFieldTextColor(tcell.ColorGreen)
+		input.SetPlaceholderTextColor(tcell.ColorGreen)
+		// 强制设置失焦样式
+		input.SetFieldStyle(tcell.StyleDefault.
+			Background(tcell.NewHexColor(0x404040)).
+			Foreground(tcell.ColorGreen))
+	})
+	
+	return input
+}
+
+// If input text is a valid date, parse it. Or get current date
+func parseDateInputOrCurrent(inputText string) time.Time {
+	if dateTime, err := time.Parse(dateLayoutISO, inputText); err == nil {
+		return toDate(dateTime)
+	}
+
+	return toDate(time.Now())
+}
+
+// parseDueInput parses due date with an optional time of day, like 2006-01-02 15:04.
+// Date falls back to current date like parseDateInputOrCurrent, time is empty if not given or invalid.
+func parseDueInput(inputText string) (time.Time, string) {
+	fields := strings.Fields(inputText)
+	if len(fields) == 0 {
+		return parseDateInputOrCurrent(""), ""
+	}
+
+	clock := ""
+	if len(fields) > 1 {
+		clock, _ = repository.ParseDueTime(fields[1])
+	}
+
+	return parseDateInputOrCurrent(fields[0]), clock
+}
+
+func toDate(dateTime time.Time) time.Time {
+	return time.Date(dateTime.Year(), dateTime.Month(), dateTime.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func makeButton(label string, handler func()) *tview.Button {
+	btn := tview.NewButton(label)
+	btn.SetSelectedFunc(handler)
+	btn.SetLabelColor(tcell.ColorWhite)
+	btn.SetBackgroundColor(tcell.ColorCornflowerBlue)  // 保持蓝色用于普通按钮
+	btn.SetBorder(false)
+
+	return btn
+}
+
+func ignoreKeyEvt() bool {
+	// 检查项目输入框
+	if projectPane != nil && projectPane.newProject != nil && projectPane.newProject.HasFocus() {
+		return true
+	}
+	
+	// 检查项目重命名输入框
+	if projectPane != nil && projectPane.renameInput != nil && projectPane.renameInput.HasFocus() {
+		return true
+	}
+	
+	// 检查任务输入框
+	if taskPane != nil && taskPane.newTask != nil && taskPane.newTask.HasFocus() {
+		return true
+	}
+	
+	// 检查重命名输入框
+	if taskDetailPane != nil && taskDetailPane.header != nil && 
+	   taskDetailPane.header.renameText != nil && taskDetailPane.header.renameText.HasFocus() {
+		return true
+	}
+	
+	// 检查日期输入框
+	if taskDetailPane != nil && taskDetailPane.taskDate != nil && taskDetailPane.taskDate.HasFocus() {
+		return true
+	}
+
+	// 检查重复规则输入框
+	if taskDetailPane != nil && taskDetailPane.taskStart != nil && taskDetailPane.taskStart.HasFocus() {
+		return true
+	}
+	if taskDetailPane != nil && taskDetailPane.taskReminder != nil && taskDetailPane.taskReminder.HasFocus() {
+		return true
+	}
+	if taskDetailPane != nil && taskDetailPane.taskRepeat != nil && taskDetailPane.taskRepeat.HasFocus() {
+		return true
+	}
+	if taskDetailPane != nil && taskDetailPane.taskEstimate != nil && taskDetailPane.taskEstimate.HasFocus() {
+		return true
+	}
+	if taskDetailPane != nil && taskDetailPane.taskSpent != nil && taskDetailPane.taskSpent.HasFocus() {
+		return true
+	}
+
+	// 检查标签输入框
+	if taskDetailPane != nil && taskDetailPane.taskTags != nil && taskDetailPane.taskTags.HasFocus() {
+		return true
+	}
+
+	// 检查前置任务输入框
+	if taskDetailPane != nil && taskDetailPane.taskBlockedBy != nil && taskDetailPane.taskBlockedBy.HasFocus() {
+		return true
+	}
+
+	// 检查上级项目输入框
+	if projectDetailPane != nil && projectDetailPane.parentInput != nil && projectDetailPane.parentInput.HasFocus() {
+		return true
+	}
+	
+	// 检查femto编辑器
+	focused := app.GetFocus()
+	if focused != nil {
+		if _, ok := focused.(*femto.View); ok {
+			return true
+		}
+	}
+	
+	return false
+}
+
+// yetToImplement - to use as callback for unimplemented features
+// `yetToImplement` is unused (deadcode)
+// func yetToImplement(feature string) func() {
+// 	message := fmt.Sprintf("[yellow]%s is yet to implement. Please Check in next version.", feature)
+// 	return func() { statusBar.showForSeconds(message, 5) }
+// }
+
+func removeThirdCol() {
+	contents.RemoveItem(taskDetailPane)
+	contents.RemoveItem(projectDetailPane)
+}
+
+// priorityColors holds listing colors of pending tasks, by priority
+var priorityColors = map[model.Priority]string{
+	model.PriorityNone:   "#51AD00",
+	model.PriorityLow:    "#5FAFD7",
+	model.PriorityMedium: "#E5C07B",
+	model.PriorityHigh:   "#FF9F40",
+	model.PriorityUrgent: "#FF5F5F",
+}
+
+func getTaskTitleColor(task model.Task) string {
+	if task.Completed {
+		// 已完成任务使用 #3C8100
+		return "#3C8100"
+	}
+
+	// 未完成任务按优先级着色，不受截止日期影响
+	if color, ok := priorityColors[task.Priority]; ok {
+		return color
+	}
+	return priorityColors[model.PriorityNone]
+}
+
+// makeTaskListingTitle makes the listing text of a task. progress of subtasks (e,g, "3/5") is optional.
+// A task having open blockers (titles in blockedBy) is dimmed, with names of the blockers.
+func makeTaskListingTitle(task model.Task, progress string, blockedBy []string) string {
+	checkbox := "[ []"
+	if task.Completed {
+		checkbox = "[x[]"
+	}
+
+	color := getTaskTitleColor(task)
+	blocked := len(blockedBy) > 0 && !task.Completed
+	if blocked {
+		color = "gray::d"
+	}
+
+	title := fmt.Sprintf("[%s]%s %s", color, checkbox, task.Title)
+	if task.Recurrence != "" {
+		title += " ↻"
+	}
+	if task.DueTime != "" {
+		title += " [::d]@" + task.DueTime + "[::-]"
+	}
+	if task.StartDate > time.Now().Unix() {
+		title += " [gray::d](starts " + time.Unix(task.StartDate, 0).Format("02 Jan") + ")[-::-]"
+	}
+	if progress != "" {
+		title += " [::d]" + progress + "[::-]"
+	}
+	if len(task.Tags) > 0 {
+		title += " [#5FAFD7::d]" + model.FormatTags(task.Tags)
+	}
+	if blocked {
+		title += " [gray::d](blocked by " + strings.Join(blockedBy, ", ") + ")"
+	}
+
+	return title
+}
+
+// joinTaskTitles lists titles of tasks, separated by comma
+func joinTaskTitles(tasks []model.Task) string {
+	titles := make([]string, len(tasks))
+	for i, task := range tasks {
+		titles[i] = task.Title
+	}
+
+	return strings.Join(titles, ", ")
+}
+
+// excludeTask removes the task of given ID from a list of tasks
+func excludeTask(tasks []model.Task, id int64) []model.Task {
+	var rest []model.Task
+	for _, task := range tasks {
+		if task.ID != id {
+			rest = append(rest, task)
+		}
+	}
+
+	return rest
+}
+
+// matchTaskTitle picks the task having given title from a list of tasks.
+// Without an exact (case-insensitive) match, title must be a part of exactly one task's title.
+func matchTaskTitle(tasks []model.Task, title string) (model.Task, error) {
+	var matches []model.Task
+	for _, task := range tasks {
+		if strings.EqualFold(task.Title, title) {
+			return task, nil
+		}
+		if strings.Contains(strings.ToLower(task.Title), strings.ToLower(title)) {
+			matches = append(matches, task)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return model.Task{}, fmt.Errorf("no task matches %q", title)
+	case 1:
+		return matches[0], nil
+	default:
+		return model.Task{}, fmt.Errorf("%d tasks match %q, be more specific", len(matches), title)
+	}
+}
+
+// `findProjectByID` is unused (deadcode)
+// func findProjectByID(id int64) *model.Project {
+// 	for i := range projectPane.projects {
+// 		if projectPane.projects[i].ID == id {
+// 			return &projectPane.projects[i]
+// 		}
+// 	}
+
+// 	return nil
+// }
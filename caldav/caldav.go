@@ -0,0 +1,614 @@
+// Package caldav synchronizes projects and tasks with a CalDAV server, both ways.
+//
+// Every project is a calendar collection under the calendar home of the user, named by the project UUID,
+// and every task is a VTODO resource in the collection of its project. Collections made by other clients
+// become new projects. The sync state of a project pairs it with its collection, whatever the collection is named.
+// A project whose collection was deleted on server is moved to trash with its tasks, unless the project or any of its tasks
+// was changed here since the last sync. Then its collection is made again, taking the changed tasks.
+// Title, notes, due date and time and completion of tasks are synced.
+//
+// The sync state of a task keeps the ETag of its resource and a hash of its synced fields as of the last sync,
+// so that a sync only transfers what was changed on either side since. A task changed on both sides is a conflict,
+// won by the latest change. A task deleted on one side is deleted on the other too, unless it was changed there meanwhile.
+package caldav
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// Syncer synchronizes a database with a CalDAV server
+type Syncer struct {
+	client     *Client
+	transactor repository.Transactor
+}
+
+// Result describes what a synchronization did
+type Result struct {
+	Uploaded, Downloaded, Deleted int
+	Conflicts                     []Conflict
+}
+
+// Conflict is a task changed both locally and on server since the last sync
+type Conflict struct {
+	Title     string
+	RemoteWon bool
+}
+
+func (c Conflict) String() string {
+	kept := "the version here"
+	if c.RemoteWon {
+		kept = "the version on server"
+	}
+
+	return fmt.Sprintf("task %q was changed both here and on server, kept %s", c.Title, kept)
+}
+
+// New makes a Syncer of the database behind transactor
+func New(client *Client, transactor repository.Transactor) *Syncer {
+	return &Syncer{client: client, transactor: transactor}
+}
+
+// fetched is a calendar object downloaded from server
+type fetched struct {
+	Resource
+	calendar *component
+}
+
+// run is the state of a single synchronization
+type run struct {
+	*Syncer
+	now    time.Time
+	result Result
+
+	tasks        []model.Task
+	states       map[int64]model.SyncState // By task ID
+	pairs        map[int64]model.SyncState // Sync states of projects, by project ID
+	projects     map[int64]model.Project
+	collectionOf map[int64]string    // Collection href of projects, by project ID
+	projectIn    map[string]int64    // Project ID of collections, by href
+	remote       map[string]Resource // Resources on server, by href
+	linked       map[int64]*fetched  // Resources not known by sync state, found to be of existing tasks by their UID
+}
+
+// Sync uploads the tasks changed locally and downloads the ones changed on server since the last sync.
+// Changes are saved task by task along with their sync state, so an interrupted sync continues where it stopped.
+func (s *Syncer) Sync(now time.Time) (Result, error) {
+	r := &run{
+		Syncer: s, now: now,
+		states: make(map[int64]model.SyncState), pairs: make(map[int64]model.SyncState), projects: make(map[int64]model.Project),
+		collectionOf: make(map[int64]string), projectIn: make(map[string]int64),
+		remote: make(map[string]Resource), linked: make(map[int64]*fetched),
+	}
+
+	steps := []func() error{r.load, r.syncCollections, r.listRemote, r.fetchUnknown, r.deleteOrphans, r.syncTasks}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return r.result, err
+		}
+	}
+
+	return r.result, nil
+}
+
+// load reads all projects and tasks, including the ones in trash, along with sync states
+func (r *run) load() error {
+	return r.transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		active, err := projects.GetAll()
+		if err != nil {
+			return err
+		}
+		trashed, err := projects.GetTrashed()
+		if err != nil {
+			return err
+		}
+		for _, project := range append(active, trashed...) {
+			r.projects[project.ID] = project
+		}
+
+		if r.tasks, err = tasks.Find(repository.TaskQuery{Trash: repository.TrashedOrNot}); err != nil {
+			return err
+		}
+		states, err := tasks.GetSyncStates()
+		for _, state := range states {
+			if state.TaskID != 0 {
+				r.states[state.TaskID] = state
+			} else {
+				r.pairs[state.ProjectID] = state
+			}
+		}
+
+		return err
+	})
+}
+
+// syncCollections pairs projects with collections, making the collections of new projects and the projects of new collections.
+// Projects in trash do not get new collections.
+func (r *run) syncCollections() error {
+	collections, err := r.client.Collections()
+	if err != nil {
+		return err
+	}
+
+	pairedIn := make(map[string]int64)
+	for projectID, state := range r.pairs {
+		pairedIn[state.Href] = projectID
+	}
+	byUUID := make(map[string]model.Project)
+	for _, project := range r.projects {
+		byUUID[project.UUID] = project
+	}
+
+	for _, collection := range collections {
+		project, ok := r.projects[pairedIn[collection.Href]]
+		if !ok {
+			// Collections made by geek-life elsewhere are named by the UUID of their project
+			project, ok = byUUID[path.Base(collection.Href)]
+			_, paired := r.pairs[project.ID]
+			ok = ok && !paired
+		}
+		if !ok {
+			title := collection.Name
+			if title == "" {
+				title = path.Base(collection.Href)
+			}
+			err := r.transactor.Transaction(func(projects repository.ProjectRepository, _ repository.TaskRepository) error {
+				project, err = projects.Create(title, "")
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			r.projects[project.ID] = project
+		}
+		r.collectionOf[project.ID], r.projectIn[collection.Href] = collection.Href, project.ID
+	}
+
+	// Pairs of projects deleted here, or of collections deleted on server, are dropped
+	for projectID, state := range r.pairs {
+		if r.projectIn[state.Href] == projectID {
+			continue
+		}
+		if project, ok := r.projects[projectID]; ok && project.DeletedAt == 0 && !r.changedSince(project, state) {
+			if err := r.trashProject(project); err != nil {
+				return err
+			}
+		}
+		if err := r.saveState(state, true); err != nil {
+			return err
+		}
+		delete(r.pairs, projectID)
+	}
+
+	for _, project := range r.projects {
+		if _, ok := r.collectionOf[project.ID]; ok || project.DeletedAt != 0 || project.UUID == "" {
+			continue
+		}
+
+		href := r.client.HomePath() + project.UUID + "/"
+		if err := r.client.MakeCollection(href, project.Title); err != nil {
+			return err
+		}
+		r.collectionOf[project.ID], r.projectIn[href] = href, project.ID
+	}
+
+	for projectID, href := range r.collectionOf {
+		hash := hashProject(r.projects[projectID])
+		if pair := r.pairs[projectID]; pair.Href != href || pair.Hash != hash {
+			if err := r.saveState(model.SyncState{ProjectID: projectID, Href: href, Hash: hash}, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// changedSince tells if a project or any of its tasks was changed here after the sync that saved its pair state
+func (r *run) changedSince(project model.Project, pair model.SyncState) bool {
+	if hashProject(project) != pair.Hash {
+		return true
+	}
+	for _, task := range r.tasks {
+		if task.ProjectID != project.ID || task.DeletedAt != 0 {
+			continue
+		}
+		if state, synced := r.states[task.ID]; !synced || hashTask(task) != state.Hash {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trashProject moves a project whose collection was deleted on server to trash, along with its tasks.
+// Sub-projects are left alone, as they have collections of their own.
+func (r *run) trashProject(project model.Project) error {
+	var trashed []int64
+	err := r.transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		projectTasks, err := tasks.Find(repository.TaskQuery{ProjectID: project.ID})
+		if err != nil {
+			return err
+		}
+		if trashed, err = repository.TrashTrees(tasks, projectTasks); err != nil {
+			return err
+		}
+
+		return projects.UpdateField(&project, "DeletedAt", r.now.Unix())
+	})
+	if err != nil {
+		return err
+	}
+
+	project.DeletedAt = r.now.Unix()
+	r.projects[project.ID] = project
+	r.markTrashed(trashed)
+	r.result.Deleted++
+
+	return nil
+}
+
+// listRemote finds the resources of all collections with their ETags
+func (r *run) listRemote() error {
+	for href := range r.projectIn {
+		resources, err := r.client.Todos(href)
+		if err != nil {
+			return err
+		}
+		for _, resource := range resources {
+			r.remote[resource.Href] = resource
+		}
+	}
+
+	return nil
+}
+
+// fetchUnknown downloads the resources not known by sync state. The ones with UID of an existing task are linked to it,
+// like a task moved to another collection by another client. Others are created as new tasks.
+func (r *run) fetchUnknown() error {
+	known := make(map[string]bool)
+	for _, state := range r.states {
+		known[state.Href] = true
+	}
+	taskOf := make(map[string]model.Task)
+	for _, task := range r.tasks {
+		taskOf[task.UUID] = task
+	}
+
+	for _, href := range sortedHrefs(r.remote) {
+		if known[href] {
+			continue
+		}
+
+		f, err := r.fetch(r.remote[href])
+		if err != nil {
+			return err
+		}
+		if f == nil {
+			continue
+		}
+
+		UID := textOf(f.calendar.child("VTODO"), "UID")
+		if task, ok := taskOf[UID]; ok && UID != "" {
+			r.linked[task.ID] = f
+			continue
+		}
+
+		task := model.Task{ProjectID: r.projectIn[parentCollection(href)]}
+		if r.projects[task.ProjectID].DeletedAt != 0 {
+			// Not brought into a project in trash
+			continue
+		}
+		readTodo(f.calendar.child("VTODO"), &task, r.now)
+		err = r.transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+			created, err := tasks.Create(r.projects[task.ProjectID], task.Title, task.Details, UID, task.DueDate)
+			if err != nil {
+				return err
+			}
+			created.DueTime, created.Completed, created.CompletedAt = task.DueTime, task.Completed, task.CompletedAt
+			if err := tasks.Update(&created); err != nil {
+				return err
+			}
+			if err := repository.ScheduleReminder(tasks, &created, r.now); err != nil {
+				return err
+			}
+
+			return tasks.SaveSyncState(&model.SyncState{TaskID: created.ID, Href: href, ETag: f.ETag, Hash: hashTask(created)})
+		})
+		if err != nil {
+			return err
+		}
+		r.result.Downloaded++
+	}
+
+	return nil
+}
+
+// deleteOrphans deletes from server the tasks deleted permanently here, unless they were changed on server meanwhile
+func (r *run) deleteOrphans() error {
+	exists := make(map[int64]bool)
+	for _, task := range r.tasks {
+		exists[task.ID] = true
+	}
+
+	for _, state := range r.states {
+		if exists[state.TaskID] {
+			continue
+		}
+
+		if resource, ok := r.remote[state.Href]; ok && resource.ETag == state.ETag {
+			if err := r.client.Delete(state.Href, state.ETag); err != nil && !errors.Is(err, ErrPreconditionFailed) {
+				return err
+			}
+			r.result.Deleted++
+		}
+		if err := r.saveState(model.SyncState{TaskID: state.TaskID}, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *run) syncTasks() error {
+	for _, task := range r.tasks {
+		if err := r.syncTask(task); err != nil {
+			return fmt.Errorf("could not sync task %q: %w", task.Title, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *run) syncTask(task model.Task) error {
+	state, synced := r.states[task.ID]
+	linked := r.linked[task.ID]
+
+	resource, onServer := r.remote[state.Href]
+	if linked != nil {
+		resource, onServer = linked.Resource, true
+	} else if !synced {
+		onServer = false
+	}
+
+	switch {
+	case task.DeletedAt != 0:
+		return r.deleteRemote(task, state, resource, onServer)
+	case !synced && !onServer:
+		return r.upload(task, nil)
+	case !onServer:
+		// Deleted on server. Local changes since bring it back.
+		if hashTask(task) != state.Hash {
+			return r.upload(task, nil)
+		}
+		return r.trash(task)
+	}
+
+	localChanged := !synced || hashTask(task) != state.Hash
+	remoteChanged := linked != nil || resource.ETag != state.ETag
+	switch {
+	case localChanged && remoteChanged:
+		return r.resolve(task, resource, linked)
+	case localChanged:
+		err := r.upload(task, &fetched{Resource: resource})
+		if errors.Is(err, ErrPreconditionFailed) {
+			// Changed on server since it was listed
+			return r.resolve(task, resource, nil)
+		}
+		return err
+	case remoteChanged:
+		return r.download(task, &fetched{Resource: resource})
+	}
+
+	return nil
+}
+
+// resolve merges a task changed on both sides. Unless they were changed the same way, the latest change wins.
+func (r *run) resolve(task model.Task, resource Resource, f *fetched) error {
+	var err error
+	if f == nil {
+		if f, err = r.fetch(resource); err != nil || f == nil {
+			return err
+		}
+	}
+
+	todo := f.calendar.child("VTODO")
+	remote := r.remoteTask(task, f)
+	if hashTask(remote) == hashTask(task) {
+		return r.saveState(model.SyncState{TaskID: task.ID, Href: f.Href, ETag: f.ETag, Hash: hashTask(task)}, false)
+	}
+
+	conflict := Conflict{Title: task.Title, RemoteWon: modifiedAt(todo) > task.UpdatedAt}
+	r.result.Conflicts = append(r.result.Conflicts, conflict)
+	if conflict.RemoteWon {
+		return r.download(task, f)
+	}
+	return r.upload(task, f)
+}
+
+// upload puts a task to the collection of its project, updating the fetched resource or making a new one if f is nil.
+// Properties of the resource set by other clients are kept. A task moved to another project moves to its collection.
+func (r *run) upload(task model.Task, f *fetched) error {
+	collection, ok := r.collectionOf[task.ProjectID]
+	if f == nil && !ok {
+		// Project is in trash, or the task has none
+		return nil
+	}
+
+	var err error
+	calendar := newCalendar()
+	if f != nil {
+		if f.calendar == nil {
+			if f, err = r.fetch(f.Resource); err != nil || f == nil {
+				return err
+			}
+		}
+		calendar = f.calendar
+	}
+	writeTodo(calendar, task, r.now)
+	data := calendar.String()
+
+	var href, etag string
+	switch {
+	case f == nil:
+		href = collection + task.UUID + ".ics"
+		etag, err = r.client.Put(href, data, "")
+	case ok && parentCollection(f.Href) != collection:
+		href = collection + path.Base(f.Href)
+		if etag, err = r.client.Put(href, data, ""); err == nil {
+			err = r.client.Delete(f.Href, f.ETag)
+		}
+	default:
+		href = f.Href
+		etag, err = r.client.Put(href, data, f.ETag)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.result.Uploaded++
+	return r.saveState(model.SyncState{TaskID: task.ID, Href: href, ETag: etag, Hash: hashTask(task)}, false)
+}
+
+// download applies a resource changed on server to its task, moving the task to the project of its collection
+func (r *run) download(task model.Task, f *fetched) error {
+	var err error
+	if f.calendar == nil {
+		if f, err = r.fetch(f.Resource); err != nil || f == nil {
+			return err
+		}
+	}
+
+	task = r.remoteTask(task, f)
+	err = r.transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+		if err := tasks.Update(&task); err != nil {
+			return err
+		}
+		if err := repository.ScheduleReminder(tasks, &task, r.now); err != nil {
+			return err
+		}
+
+		return tasks.SaveSyncState(&model.SyncState{TaskID: task.ID, Href: f.Href, ETag: f.ETag, Hash: hashTask(task)})
+	})
+	if err == nil {
+		r.result.Downloaded++
+	}
+
+	return err
+}
+
+// deleteRemote deletes the resource of a task moved to trash, unless it was changed on server meanwhile
+func (r *run) deleteRemote(task model.Task, state model.SyncState, resource Resource, onServer bool) error {
+	if onServer && (r.linked[task.ID] != nil || resource.ETag == state.ETag) {
+		if err := r.client.Delete(resource.Href, resource.ETag); err != nil && !errors.Is(err, ErrPreconditionFailed) {
+			return err
+		}
+		r.result.Deleted++
+	}
+	if state.TaskID == 0 {
+		return nil
+	}
+
+	return r.saveState(state, true)
+}
+
+// trash moves a task deleted on server to trash, along with its subtasks.
+// Subtasks are marked trashed in the run too, so that their resources are deleted by the same sync.
+func (r *run) trash(task model.Task) error {
+	var trashed []int64
+	err := r.transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+		var err error
+		if trashed, err = repository.TrashTree(tasks, task); err != nil {
+			return err
+		}
+
+		return tasks.DeleteSyncState(model.SyncState{TaskID: task.ID})
+	})
+	if err != nil {
+		return err
+	}
+
+	r.result.Deleted++
+	r.markTrashed(trashed)
+
+	return nil
+}
+
+// markTrashed notes in the run that tasks were moved to trash
+func (r *run) markTrashed(IDs []int64) {
+	for _, id := range IDs {
+		for i := range r.tasks {
+			if r.tasks[i].ID == id && r.tasks[i].DeletedAt == 0 {
+				r.tasks[i].DeletedAt = r.now.Unix()
+			}
+		}
+	}
+}
+
+// remoteTask is a task as it is on server, in the project of its collection
+func (r *run) remoteTask(task model.Task, f *fetched) model.Task {
+	readTodo(f.calendar.child("VTODO"), &task, r.now)
+	if projectID, ok := r.projectIn[parentCollection(f.Href)]; ok {
+		task.ProjectID = projectID
+	}
+
+	return task
+}
+
+// fetch downloads a resource, returning nil if it is not a task
+func (r *run) fetch(resource Resource) (*fetched, error) {
+	data, etag, err := r.client.Get(resource.Href)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar, err := parseCalendar(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", resource.Href, err)
+	}
+	if calendar.child("VTODO") == nil {
+		return nil, nil
+	}
+	if etag != "" {
+		resource.ETag = etag
+	}
+
+	return &fetched{Resource: resource, calendar: calendar}, nil
+}
+
+// saveState saves or deletes the sync state of a task or project
+func (r *run) saveState(state model.SyncState, remove bool) error {
+	return r.transactor.Transaction(func(_ repository.ProjectRepository, tasks repository.TaskRepository) error {
+		if remove {
+			err := tasks.DeleteSyncState(state)
+			if errors.Is(err, repository.ErrNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		return tasks.SaveSyncState(&state)
+	})
+}
+
+// parentCollection finds the collection href of a resource href
+func parentCollection(href string) string {
+	return strings.TrimSuffix(href, path.Base(href))
+}
+
+func sortedHrefs(resources map[string]Resource) []string {
+	hrefs := make([]string, 0, len(resources))
+	for href := range resources {
+		hrefs = append(hrefs, href)
+	}
+	sort.Strings(hrefs)
+
+	return hrefs
+}
@@ -0,0 +1,462 @@
+package caldav_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ajaxray/geek-life/caldav"
+	"github.com/ajaxray/geek-life/caldav/caldavtest"
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+	"github.com/ajaxray/geek-life/repository/memory"
+)
+
+const home = "/calendars/me/"
+
+// env is a database synchronizing with a stand-in server, where tests act as another client
+type env struct {
+	projects repository.ProjectRepository
+	tasks    repository.TaskRepository
+	server   *caldavtest.Server
+	client   *caldav.Client
+	syncer   *caldav.Syncer
+
+	// beforeRequest, if set, is called before the server handles a request
+	beforeRequest func(r *http.Request)
+}
+
+func newEnv(t *testing.T) *env {
+	server := caldavtest.NewServer(home)
+	server.User, server.Password = "me", "secret"
+
+	return newEnvOf(t, server)
+}
+
+// newEnvOf makes an empty database synchronizing with a server, like another device of the same user
+func newEnvOf(t *testing.T, server *caldavtest.Server) *env {
+	e := &env{server: server}
+
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e.beforeRequest != nil {
+			e.beforeRequest(r)
+		}
+		e.server.ServeHTTP(w, r)
+	}))
+	t.Cleanup(httpServer.Close)
+
+	var err error
+	if e.client, err = caldav.NewClient(httpServer.URL+home, "me", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	store := memory.NewStore()
+	e.projects, e.tasks = memory.NewProjectRepository(store), memory.NewTaskRepository(store)
+	e.syncer = caldav.New(e.client, memory.NewTransactor(store))
+
+	return e
+}
+
+func (e *env) sync(t *testing.T) caldav.Result {
+	t.Helper()
+
+	result, err := e.syncer.Sync(time.Now())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	return result
+}
+
+// settled checks that nothing is left to transfer
+func (e *env) settled(t *testing.T) {
+	t.Helper()
+
+	if result := e.sync(t); result.Uploaded+result.Downloaded+result.Deleted+len(result.Conflicts) != 0 {
+		t.Errorf("sync after sync = %+v, want nothing to do", result)
+	}
+}
+
+func (e *env) task(t *testing.T, id int64) model.Task {
+	t.Helper()
+
+	task, err := e.tasks.GetByID(strconv.FormatInt(id, 10))
+	if err != nil {
+		t.Fatalf("task %d: %v", id, err)
+	}
+	return task
+}
+
+// object reads a resource from server, failing the test if it is not there
+func (e *env) object(t *testing.T, href string) string {
+	t.Helper()
+
+	data, ok := e.server.Get(href)
+	if !ok {
+		t.Fatalf("%s is not on server, have %v", href, e.server.Collections())
+	}
+	return data
+}
+
+// pushed makes a project with a task and syncs them to server. Returns href of the task resource.
+func (e *env) pushed(t *testing.T) (model.Project, model.Task, string) {
+	t.Helper()
+
+	project, _ := e.projects.Create("Work", "")
+	task, _ := e.tasks.Create(project, "Write report", "", "", 0)
+	e.sync(t)
+
+	return project, task, home + project.UUID + "/" + task.UUID + ".ics"
+}
+
+// edit changes a property of a VTODO as another client would, marking it modified at a time
+func edit(data, name, value string, modified time.Time) string {
+	lines := strings.Split(data, "\r\n")
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, name+":"):
+			lines[i] = name + ":" + value
+		case strings.HasPrefix(line, "LAST-MODIFIED:"):
+			lines[i] = "LAST-MODIFIED:" + modified.UTC().Format("20060102T150405Z")
+		}
+	}
+
+	return strings.Join(lines, "\r\n")
+}
+
+func TestPush(t *testing.T) {
+	e := newEnv(t)
+
+	project, _ := e.projects.Create("Work, \"etc\"", "")
+	due := time.Date(2026, 10, 20, 0, 0, 0, 0, time.Local)
+	report, _ := e.tasks.Create(project, "Write report; send it", "Line one\nLine two", "", repository.RoundDueDate(due))
+	_ = e.tasks.UpdateField(&report, "DueTime", "15:30")
+	review, _ := e.tasks.Create(project, "Review", "", "", 0)
+	_ = e.tasks.UpdateField(&review, "Completed", true)
+	_ = e.tasks.UpdateField(&review, "CompletedAt", time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC).Unix())
+
+	if result := e.sync(t); result.Uploaded != 2 {
+		t.Fatalf("Sync = %+v, want 2 uploaded", result)
+	}
+
+	collection := home + project.UUID + "/"
+	if got := e.server.Collections(); len(got) != 1 || got[0] != collection {
+		t.Errorf("collections = %v, want %s", got, collection)
+	}
+	data := e.object(t, collection+report.UUID+".ics")
+	for _, want := range []string{"UID:" + report.UUID, `SUMMARY:Write report\; send it`, `DESCRIPTION:Line one\nLine two`,
+		"DUE:20261020T153000", "STATUS:NEEDS-ACTION"} {
+		if !strings.Contains(data, want+"\r\n") {
+			t.Errorf("resource of task lacks %q:\n%s", want, data)
+		}
+	}
+	data = e.object(t, collection+review.UUID+".ics")
+	for _, want := range []string{"STATUS:COMPLETED", "COMPLETED:20261017T100000Z"} {
+		if !strings.Contains(data, want+"\r\n") {
+			t.Errorf("resource of completed task lacks %q:\n%s", want, data)
+		}
+	}
+
+	e.settled(t)
+}
+
+func TestPull(t *testing.T) {
+	e := newEnv(t)
+
+	e.server.AddCollection(home+"errands/", "Errands")
+	e.server.Put(home+"errands/milk.ics", "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTODO\r\nUID:milk-uid\r\n"+
+		"SUMMARY:Buy milk\r\nDESCRIPTION:Oat\\, if they have\r\nDUE;VALUE=DATE:20261101\r\nEND:VTODO\r\nEND:VCALENDAR\r\n")
+	e.server.Put(home+"errands/event.ics", "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:event-uid\r\n"+
+		"SUMMARY:Not a task\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+
+	if result := e.sync(t); result.Downloaded != 1 {
+		t.Fatalf("Sync = %+v, want 1 downloaded", result)
+	}
+
+	task, err := e.tasks.GetByUUID("milk-uid")
+	if err != nil {
+		t.Fatalf("pulled task: %v", err)
+	}
+	due := repository.RoundDueDate(time.Date(2026, 11, 1, 0, 0, 0, 0, time.Local))
+	if task.Title != "Buy milk" || task.Details != "Oat, if they have" || task.DueDate != due || task.DueTime != "" {
+		t.Errorf("pulled task = %+v", task)
+	}
+	project, _ := e.projects.GetByID(task.ProjectID)
+	if _, err := uuid.Parse(project.UUID); err != nil || project.Title != "Errands" {
+		t.Errorf("project of pulled task = %+v, want Errands with a UUID", project)
+	}
+
+	e.settled(t)
+	if projects, _ := e.projects.GetAll(); len(projects) != 1 {
+		t.Errorf("projects after syncing again = %+v, want the one paired with collection", projects)
+	}
+	if got := e.server.Collections(); len(got) != 1 {
+		t.Errorf("collections after syncing again = %v, want the one of other client", got)
+	}
+
+	// Changes are put back to the same collection
+	task.Title = "Buy oat milk"
+	_ = e.tasks.Update(&task)
+	if result := e.sync(t); result.Uploaded != 1 {
+		t.Fatalf("Sync = %+v, want 1 uploaded", result)
+	}
+	if data := e.object(t, home+"errands/milk.ics"); !strings.Contains(data, "SUMMARY:Buy oat milk\r\n") {
+		t.Errorf("resource after local edit:\n%s", data)
+	}
+}
+
+// Collections named alike by other clients, on different servers or accounts, become different projects
+func TestCollectionsOfOtherClients(t *testing.T) {
+	var UUIDs []string
+	for i := 0; i < 2; i++ {
+		e := newEnv(t)
+		e.server.AddCollection(home+"home/", "Home")
+		e.sync(t)
+
+		projects, _ := e.projects.GetAll()
+		if len(projects) != 1 {
+			t.Fatalf("projects = %+v, want the one of collection", projects)
+		}
+		UUIDs = append(UUIDs, projects[0].UUID)
+	}
+
+	if UUIDs[0] == UUIDs[1] || UUIDs[0] == "home" {
+		t.Errorf("UUIDs of projects = %v, want distinct UUIDs", UUIDs)
+	}
+}
+
+// A project synced to another device finds its collection by UUID
+func TestCollectionOfSameProject(t *testing.T) {
+	e := newEnv(t)
+	project, task, _ := e.pushed(t)
+
+	other := newEnvOf(t, e.server)
+	copied, _ := other.projects.Create(project.Title, project.UUID)
+	if result := other.sync(t); result.Downloaded != 1 {
+		t.Fatalf("Sync = %+v, want the task downloaded", result)
+	}
+
+	if got := e.server.Collections(); len(got) != 1 {
+		t.Errorf("collections = %v, want the one of project", got)
+	}
+	if projects, _ := other.projects.GetAll(); len(projects) != 1 {
+		t.Errorf("projects = %+v, want the one synced before", projects)
+	}
+	if got, err := other.tasks.GetByUUID(task.UUID); err != nil || got.ProjectID != copied.ID {
+		t.Errorf("task = %+v, %v, want it in project %d", got, err, copied.ID)
+	}
+	other.settled(t)
+}
+
+func TestRemoteEdit(t *testing.T) {
+	e := newEnv(t)
+	_, task, href := e.pushed(t)
+
+	data := edit(e.object(t, href), "SUMMARY", "Write final report", time.Now())
+	data = strings.Replace(data, "STATUS:NEEDS-ACTION", "STATUS:COMPLETED\r\nCOMPLETED:20261017T100000Z\r\nX-CLIENT:kept", 1)
+	e.server.Put(href, data)
+
+	if result := e.sync(t); result.Downloaded != 1 || len(result.Conflicts) != 0 {
+		t.Fatalf("Sync = %+v, want 1 downloaded", result)
+	}
+	got := e.task(t, task.ID)
+	if got.Title != "Write final report" || !got.Completed || got.CompletedAt != time.Date(2026, 10, 17, 10, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("task after remote edit = %+v", got)
+	}
+	e.settled(t)
+
+	// Local changes are put on top, keeping properties of other clients
+	_ = e.tasks.UpdateField(&got, "Details", "Two pages")
+	if result := e.sync(t); result.Uploaded != 1 {
+		t.Fatalf("Sync = %+v, want 1 uploaded", result)
+	}
+	data = e.object(t, href)
+	if !strings.Contains(data, "DESCRIPTION:Two pages\r\n") || !strings.Contains(data, "X-CLIENT:kept\r\n") {
+		t.Errorf("resource after local edit:\n%s", data)
+	}
+	e.settled(t)
+}
+
+func TestRemoteDelete(t *testing.T) {
+	e := newEnv(t)
+	project, task, href := e.pushed(t)
+	subtask, _ := e.tasks.Create(project, "Attach charts", "", "", 0)
+	_ = e.tasks.UpdateField(&subtask, "ParentID", task.ID)
+	e.sync(t)
+
+	// The task is trashed here, and its subtask trashed along is deleted from server
+	e.server.Delete(href)
+	if result := e.sync(t); result.Deleted != 2 {
+		t.Fatalf("Sync = %+v, want 2 deleted", result)
+	}
+	if got := e.task(t, task.ID); got.DeletedAt == 0 {
+		t.Error("task deleted on server is not in trash")
+	}
+	if got := e.task(t, subtask.ID); got.DeletedAt == 0 {
+		t.Error("subtask of task deleted on server is not in trash")
+	}
+	if _, ok := e.server.Get(home + project.UUID + "/" + subtask.UUID + ".ics"); ok {
+		t.Error("subtask trashed along is still on server")
+	}
+
+	e.settled(t)
+	states, _ := e.tasks.GetSyncStates()
+	for _, state := range states {
+		if state.TaskID != 0 {
+			t.Errorf("sync state of task left = %+v", state)
+		}
+	}
+}
+
+func TestRemoteDeleteOfChangedTask(t *testing.T) {
+	e := newEnv(t)
+	_, task, href := e.pushed(t)
+
+	e.server.Delete(href)
+	_ = e.tasks.UpdateField(&task, "Title", "Write report again")
+
+	if result := e.sync(t); result.Uploaded != 1 {
+		t.Fatalf("Sync = %+v, want the changed task uploaded again", result)
+	}
+	if data := e.object(t, href); !strings.Contains(data, "SUMMARY:Write report again\r\n") {
+		t.Errorf("resource brought back:\n%s", data)
+	}
+	e.settled(t)
+}
+
+func TestRemoteCollectionDelete(t *testing.T) {
+	e := newEnv(t)
+	project, task, _ := e.pushed(t)
+
+	e.server.DeleteCollection(home + project.UUID + "/")
+	if result := e.sync(t); result.Deleted != 1 {
+		t.Fatalf("Sync = %+v, want the project deleted", result)
+	}
+	if got, _ := e.projects.GetByID(project.ID); got.DeletedAt == 0 {
+		t.Error("project of collection deleted on server is not in trash")
+	}
+	if got := e.task(t, task.ID); got.DeletedAt == 0 {
+		t.Error("task of collection deleted on server is not in trash")
+	}
+	if collections := e.server.Collections(); len(collections) != 0 {
+		t.Errorf("collections = %v, want the deleted one not made again", collections)
+	}
+
+	e.settled(t)
+	if states, _ := e.tasks.GetSyncStates(); len(states) != 0 {
+		t.Errorf("sync states left = %+v", states)
+	}
+}
+
+func TestRemoteCollectionDeleteOfChangedProject(t *testing.T) {
+	e := newEnv(t)
+	project, task, href := e.pushed(t)
+
+	e.server.DeleteCollection(home + project.UUID + "/")
+	_ = e.tasks.UpdateField(&task, "Title", "Write report again")
+
+	if result := e.sync(t); result.Uploaded != 1 || result.Deleted != 0 {
+		t.Fatalf("Sync = %+v, want the changed task uploaded again", result)
+	}
+	if got, _ := e.projects.GetByID(project.ID); got.DeletedAt != 0 {
+		t.Error("project with changes is in trash")
+	}
+	if data := e.object(t, href); !strings.Contains(data, "SUMMARY:Write report again\r\n") {
+		t.Errorf("resource brought back:\n%s", data)
+	}
+	e.settled(t)
+}
+
+func TestLocalDelete(t *testing.T) {
+	e := newEnv(t)
+	project, task, href := e.pushed(t)
+	other, _ := e.tasks.Create(project, "Review", "", "", 0)
+	e.sync(t)
+
+	if _, err := repository.TrashTree(e.tasks, task); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.tasks.Delete(&other); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := e.sync(t); result.Deleted != 2 {
+		t.Fatalf("Sync = %+v, want 2 deleted", result)
+	}
+	if objects := e.server.Objects(home + project.UUID + "/"); len(objects) != 0 {
+		t.Errorf("resources left on server = %v, after deleting %s", objects, href)
+	}
+	e.settled(t)
+}
+
+func TestConflict(t *testing.T) {
+	e := newEnv(t)
+	_, task, href := e.pushed(t)
+
+	_ = e.tasks.UpdateField(&task, "Title", "Local title")
+	e.server.Put(href, edit(e.object(t, href), "SUMMARY", "Remote title", time.Now().Add(time.Hour)))
+
+	result := e.sync(t)
+	if len(result.Conflicts) != 1 || !result.Conflicts[0].RemoteWon {
+		t.Fatalf("conflicts = %v, want the later change on server won", result.Conflicts)
+	}
+	if got := e.task(t, task.ID); got.Title != "Remote title" {
+		t.Errorf("title = %q, want Remote title", got.Title)
+	}
+	e.settled(t)
+
+	// A later local change wins
+	task = e.task(t, task.ID)
+	_ = e.tasks.UpdateField(&task, "Title", "Local title")
+	e.server.Put(href, edit(e.object(t, href), "SUMMARY", "Old remote title", time.Now().Add(-time.Hour)))
+
+	result = e.sync(t)
+	if len(result.Conflicts) != 1 || result.Conflicts[0].RemoteWon {
+		t.Fatalf("conflicts = %v, want the later change here won", result.Conflicts)
+	}
+	if data := e.object(t, href); !strings.Contains(data, "SUMMARY:Local title\r\n") {
+		t.Errorf("resource after conflict:\n%s", data)
+	}
+	e.settled(t)
+}
+
+func TestPreconditionFailed(t *testing.T) {
+	e := newEnv(t)
+	_, task, href := e.pushed(t)
+
+	data, etag, err := e.client.Get(href)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.server.Put(href, data)
+	if _, err := e.client.Put(href, data, etag); !errors.Is(err, caldav.ErrPreconditionFailed) {
+		t.Errorf("Put with a stale ETag error = %v, want ErrPreconditionFailed", err)
+	}
+	if err := e.client.Delete(href, etag); !errors.Is(err, caldav.ErrPreconditionFailed) {
+		t.Errorf("Delete with a stale ETag error = %v, want ErrPreconditionFailed", err)
+	}
+	e.sync(t)
+
+	// Another client changes the task right before it is uploaded
+	_ = e.tasks.UpdateField(&task, "Title", "Local title")
+	e.beforeRequest = func(r *http.Request) {
+		if r.Method == http.MethodPut && r.Header.Get("If-Match") != "" {
+			e.beforeRequest = nil
+			e.server.Put(href, edit(e.object(t, href), "SUMMARY", "Remote title", time.Now().Add(time.Hour)))
+		}
+	}
+
+	result := e.sync(t)
+	if len(result.Conflicts) != 1 || !result.Conflicts[0].RemoteWon {
+		t.Fatalf("Sync = %+v, want a conflict won by the change on server", result)
+	}
+	if got := e.task(t, task.ID); got.Title != "Remote title" {
+		t.Errorf("title = %q, want Remote title", got.Title)
+	}
+	e.settled(t)
+}
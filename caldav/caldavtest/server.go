@@ -0,0 +1,320 @@
+// Package caldavtest provides a stand-in CalDAV server keeping calendars in memory, to try sync without a real server.
+// It speaks just enough CalDAV for package caldav: listing, making and querying collections, and reading and writing resources
+// with ETag preconditions. Tests can act as another client through its methods.
+//
+// Usage:
+//
+//	stand := caldavtest.NewServer("/calendars/me/")
+//	server := httptest.NewServer(stand)
+//	client, _ := caldav.NewClient(server.URL+"/calendars/me/", "", "")
+package caldavtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Server is an http.Handler serving the calendar home of a single user
+type Server struct {
+	Home           string // Path of calendar home, ending with a slash
+	User, Password string // Credentials required by basic authentication, if User is set
+
+	mu          sync.Mutex
+	collections map[string]*collection
+	lastETag    int
+}
+
+type collection struct {
+	name    string
+	objects map[string]object
+}
+
+type object struct {
+	data, etag string
+}
+
+// NewServer makes a server with an empty calendar home at home path
+func NewServer(home string) *Server {
+	if !strings.HasSuffix(home, "/") {
+		home += "/"
+	}
+
+	return &Server{Home: home, collections: make(map[string]*collection)}
+}
+
+// AddCollection makes a calendar collection for tasks, as another client would
+func (s *Server) AddCollection(href, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collections[withSlash(href)] = &collection{name: name, objects: make(map[string]object)}
+}
+
+// Collections lists hrefs of the collections, sorted
+func (s *Server) Collections() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.collectionHrefs()
+}
+
+// Objects lists hrefs of the resources of a collection, sorted
+func (s *Server) Objects(collectionHref string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c := s.collections[withSlash(collectionHref)]; c != nil {
+		return c.objectHrefs()
+	}
+	return nil
+}
+
+// Get reads a resource, as another client would
+func (s *Server) Get(href string) (data string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, obj := s.find(href)
+	if c == nil || obj.etag == "" {
+		return "", false
+	}
+	return obj.data, true
+}
+
+// Put writes a resource to an existing collection, as another client would. Returns its new ETag.
+func (s *Server) Put(href, data string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, _ := s.find(href)
+	if c == nil {
+		panic("caldavtest: no collection for " + href)
+	}
+	return s.store(c, href, data)
+}
+
+// Delete removes a resource, as another client would
+func (s *Server) Delete(href string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c, _ := s.find(href); c != nil {
+		delete(c.objects, href)
+	}
+}
+
+// DeleteCollection removes a collection along with its resources, as another client would
+func (s *Server) DeleteCollection(href string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.collections, withSlash(href))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.User != "" {
+		if user, password, ok := r.BasicAuth(); !ok || user != s.User || password != s.Password {
+			w.Header().Set("WWW-Authenticate", `Basic realm="caldavtest"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	href := r.URL.Path
+	switch r.Method {
+	case "PROPFIND":
+		s.propfind(w, href)
+	case "MKCALENDAR":
+		s.mkcalendar(w, href, body)
+	case "REPORT":
+		s.report(w, href)
+	case http.MethodGet:
+		s.get(w, href)
+	case http.MethodPut:
+		s.put(w, r, href, string(body))
+	case http.MethodDelete:
+		s.delete(w, r, href)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) propfind(w http.ResponseWriter, href string) {
+	if withSlash(href) != s.Home {
+		http.Error(w, "Only calendar home can be listed", http.StatusNotFound)
+		return
+	}
+
+	responses := []string{response(s.Home, "<d:resourcetype><d:collection/></d:resourcetype>")}
+	for _, collectionHref := range s.collectionHrefs() {
+		c := s.collections[collectionHref]
+		responses = append(responses, response(collectionHref,
+			"<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>"+
+				"<d:displayname>"+html.EscapeString(c.name)+"</d:displayname>"+
+				`<c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>`))
+	}
+	writeMultistatus(w, responses)
+}
+
+func (s *Server) mkcalendar(w http.ResponseWriter, href string, body []byte) {
+	href = withSlash(href)
+	if !strings.HasPrefix(href, s.Home) || href == s.Home {
+		http.Error(w, "Collections can only be made in calendar home", http.StatusForbidden)
+		return
+	}
+	if s.collections[href] != nil {
+		http.Error(w, "Collection exists", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var request struct {
+		Name string `xml:"set>prop>displayname"`
+	}
+	_ = xml.Unmarshal(body, &request)
+	s.collections[href] = &collection{name: request.Name, objects: make(map[string]object)}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// report answers calendar queries with the ETags of all VTODO resources of a collection
+func (s *Server) report(w http.ResponseWriter, href string) {
+	c := s.collections[withSlash(href)]
+	if c == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var responses []string
+	for _, objectHref := range c.objectHrefs() {
+		if obj := c.objects[objectHref]; strings.Contains(obj.data, "BEGIN:VTODO") {
+			responses = append(responses, response(objectHref, "<d:getetag>"+html.EscapeString(obj.etag)+"</d:getetag>"))
+		}
+	}
+	writeMultistatus(w, responses)
+}
+
+func (s *Server) get(w http.ResponseWriter, href string) {
+	_, obj := s.find(href)
+	if obj.etag == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("ETag", obj.etag)
+	_, _ = io.WriteString(w, obj.data)
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, href, data string) {
+	c, obj := s.find(href)
+	switch {
+	case c == nil:
+		http.Error(w, "Collection not found", http.StatusConflict)
+		return
+	case !s.preconditionsMet(r, obj):
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+		return
+	}
+
+	status := http.StatusNoContent
+	if obj.etag == "" {
+		status = http.StatusCreated
+	}
+	w.Header().Set("ETag", s.store(c, href, data))
+	w.WriteHeader(status)
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, href string) {
+	c, obj := s.find(href)
+	switch {
+	case obj.etag == "":
+		http.Error(w, "Not found", http.StatusNotFound)
+	case !s.preconditionsMet(r, obj):
+		http.Error(w, "Precondition failed", http.StatusPreconditionFailed)
+	default:
+		delete(c.objects, href)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// preconditionsMet checks If-Match and If-None-Match headers against the current version of a resource
+func (s *Server) preconditionsMet(r *http.Request, obj object) bool {
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" && match != obj.etag {
+		return false
+	}
+	if r.Header.Get("If-Match") == "*" && obj.etag == "" {
+		return false
+	}
+
+	return r.Header.Get("If-None-Match") != "*" || obj.etag == ""
+}
+
+// find looks up the collection of a resource href, and the resource in it. Caller must hold the lock.
+func (s *Server) find(href string) (*collection, object) {
+	c := s.collections[path.Dir(href)+"/"]
+	if c == nil {
+		return nil, object{}
+	}
+
+	return c, c.objects[href]
+}
+
+// store saves a resource with a new ETag. Caller must hold the lock.
+func (s *Server) store(c *collection, href, data string) string {
+	s.lastETag++
+	etag := fmt.Sprintf(`"%d"`, s.lastETag)
+	c.objects[href] = object{data: data, etag: etag}
+
+	return etag
+}
+
+func response(href, props string) string {
+	return "<d:response><d:href>" + html.EscapeString(href) + "</d:href><d:propstat><d:prop>" + props +
+		"</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>"
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>`+"\n"+
+		`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`+strings.Join(responses, "")+"</d:multistatus>")
+}
+
+func withSlash(href string) string {
+	if strings.HasSuffix(href, "/") {
+		return href
+	}
+
+	return href + "/"
+}
+
+// collectionHrefs lists hrefs of collections, sorted. Caller must hold the lock.
+func (s *Server) collectionHrefs() []string {
+	hrefs := make([]string, 0, len(s.collections))
+	for href := range s.collections {
+		hrefs = append(hrefs, href)
+	}
+	sort.Strings(hrefs)
+
+	return hrefs
+}
+
+func (c *collection) objectHrefs() []string {
+	hrefs := make([]string, 0, len(c.objects))
+	for href := range c.objects {
+		hrefs = append(hrefs, href)
+	}
+	sort.Strings(hrefs)
+
+	return hrefs
+}
@@ -0,0 +1,265 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrPreconditionFailed is returned when a resource was changed on server since its ETag was taken
+var ErrPreconditionFailed = errors.New("resource was changed on server meanwhile")
+
+// Namespaces of WebDAV and CalDAV elements
+const (
+	nsDAV    = "DAV:"
+	nsCalDAV = "urn:ietf:params:xml:ns:caldav"
+)
+
+// Client talks to a CalDAV server, within the calendar home collection of a user
+type Client struct {
+	HTTP     *http.Client
+	home     *url.URL
+	user     string
+	password string
+}
+
+// Collection is a calendar collection on server, holding VTODO resources
+type Collection struct {
+	Href string
+	Name string
+}
+
+// Resource is a calendar object on server, identified by its path
+type Resource struct {
+	Href string
+	ETag string
+}
+
+// NewClient makes a client of the calendar home collection at homeURL, like https://dav.example.com/calendars/me/.
+// Password is sent by basic authentication, if user is given.
+func NewClient(homeURL, user, password string) (*Client, error) {
+	home, err := url.Parse(homeURL)
+	if err != nil {
+		return nil, err
+	}
+	if home.Scheme != "http" && home.Scheme != "https" {
+		return nil, errors.New("CalDAV URL should start with http:// or https://")
+	}
+	if !strings.HasSuffix(home.Path, "/") {
+		home.Path += "/"
+	}
+
+	return &Client{HTTP: http.DefaultClient, home: home, user: user, password: password}, nil
+}
+
+// HomePath is the path of calendar home collection, under which new collections are made
+func (c *Client) HomePath() string {
+	return c.home.Path
+}
+
+// multistatus is the response of PROPFIND and REPORT requests
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				DisplayName  string `xml:"DAV: displayname"`
+				ETag         string `xml:"DAV: getetag"`
+				ResourceType struct {
+					Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+				} `xml:"DAV: resourcetype"`
+				Components struct {
+					Comps []struct {
+						Name string `xml:"name,attr"`
+					} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+				} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// Collections lists the calendar collections in home that can hold tasks
+func (c *Client) Collections() ([]Collection, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:resourcetype/><d:displayname/><c:supported-calendar-component-set/></d:prop>
+</d:propfind>`
+	ms, err := c.multistatus("PROPFIND", c.home.Path, "1", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var collections []Collection
+	for _, resp := range ms.Responses {
+		for _, ps := range resp.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") || ps.Prop.ResourceType.Calendar == nil {
+				continue
+			}
+
+			// Calendars without a component set take any component
+			todos := len(ps.Prop.Components.Comps) == 0
+			for _, comp := range ps.Prop.Components.Comps {
+				todos = todos || strings.EqualFold(comp.Name, "VTODO")
+			}
+			if todos {
+				collections = append(collections, Collection{Href: c.pathOf(resp.Href), Name: ps.Prop.DisplayName})
+			}
+		}
+	}
+
+	return collections, nil
+}
+
+// MakeCollection creates a calendar collection for tasks
+func (c *Client) MakeCollection(href, name string) error {
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(name))
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<c:mkcalendar xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:set><d:prop>
+    <d:displayname>` + escaped.String() + `</d:displayname>
+    <c:supported-calendar-component-set><c:comp name="VTODO"/></c:supported-calendar-component-set>
+  </d:prop></d:set>
+</c:mkcalendar>`
+
+	resp, err := c.do("MKCALENDAR", href, map[string]string{"Content-Type": "application/xml; charset=utf-8"}, body)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Todos lists the VTODO resources of a collection, with their ETags
+func (c *Client) Todos(collection string) ([]Resource, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO"/></c:comp-filter></c:filter>
+</c:calendar-query>`
+	ms, err := c.multistatus("REPORT", collection, "1", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources []Resource
+	for _, resp := range ms.Responses {
+		for _, ps := range resp.Propstat {
+			if strings.Contains(ps.Status, " 200 ") && ps.Prop.ETag != "" {
+				resources = append(resources, Resource{Href: c.pathOf(resp.Href), ETag: ps.Prop.ETag})
+			}
+		}
+	}
+
+	return resources, nil
+}
+
+// Get downloads a calendar object along with its ETag
+func (c *Client) Get(href string) (data, etag string, err error) {
+	resp, err := c.do(http.MethodGet, href, nil, "")
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := io.ReadAll(resp.Body)
+	return string(content), resp.Header.Get("ETag"), err
+}
+
+// Put uploads a calendar object, replacing the version with etag, or creating a new one if etag is empty.
+// Returns the new ETag, which is empty if the server did not tell.
+func (c *Client) Put(href, data, etag string) (string, error) {
+	headers := map[string]string{"Content-Type": "text/calendar; charset=utf-8", "If-None-Match": "*"}
+	if etag != "" {
+		delete(headers, "If-None-Match")
+		headers["If-Match"] = etag
+	}
+
+	resp, err := c.do(http.MethodPut, href, headers, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	return resp.Header.Get("ETag"), nil
+}
+
+// Delete removes a calendar object, unless it was changed since its version with etag
+func (c *Client) Delete(href, etag string) error {
+	resp, err := c.do(http.MethodDelete, href, map[string]string{"If-Match": etag}, "")
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+func (c *Client) multistatus(method, href, depth, body string) (multistatus, error) {
+	var ms multistatus
+	resp, err := c.do(method, href, map[string]string{"Depth": depth, "Content-Type": "application/xml; charset=utf-8"}, body)
+	if err != nil {
+		return ms, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMultiStatus {
+		return ms, fmt.Errorf("%s %s: unexpected status %s", method, href, resp.Status)
+	}
+	return ms, xml.NewDecoder(resp.Body).Decode(&ms)
+}
+
+// do sends a request to a path on server. Responses other than 2xx are returned as errors.
+func (c *Client) do(method, href string, headers map[string]string, body string) (*http.Response, error) {
+	target, err := c.home.Parse(href)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, target.String(), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.password)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusPreconditionFailed {
+			return nil, ErrPreconditionFailed
+		}
+		return nil, fmt.Errorf("%s %s: %s", method, href, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// pathOf turns an href of a response into a clean path, as servers may answer with full URLs
+func (c *Client) pathOf(href string) string {
+	u, err := c.home.Parse(href)
+	if err != nil {
+		return href
+	}
+
+	cleaned := path.Clean(u.Path)
+	if strings.HasSuffix(u.Path, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
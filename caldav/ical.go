@@ -0,0 +1,198 @@
+package caldav
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// property is a content line of iCalendar, like DUE;VALUE=DATE:20210310.
+// Params are kept as they are found, starting with ';', to write unknown ones back untouched.
+type property struct {
+	Name   string
+	Params string
+	Value  string
+}
+
+// component is a block of iCalendar between BEGIN and END lines, like VCALENDAR or VTODO
+type component struct {
+	Name       string
+	Props      []property
+	Components []*component
+}
+
+// parseCalendar reads an iCalendar object. Only the structure is parsed, values are read by the users of properties.
+func parseCalendar(data string) (*component, error) {
+	var stack []*component
+	var root *component
+
+	for _, line := range unfold(data) {
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, err
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			c := &component{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, c)
+			} else if root == nil {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, errors.New("unexpected END:" + prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, errors.New("property outside of a component: " + prop.Name)
+			}
+			c := stack[len(stack)-1]
+			c.Props = append(c.Props, prop)
+		}
+	}
+
+	if root == nil || len(stack) > 0 {
+		return nil, errors.New("incomplete iCalendar object")
+	}
+	return root, nil
+}
+
+// unfold joins the lines folded by a leading space or tab, skipping empty ones
+func unfold(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+		} else if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
+
+// parseLine splits a content line at the first colon that is not in a quoted param value
+func parseLine(line string) (property, error) {
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ':' && !quoted:
+			head := line[:i]
+			name, params := head, ""
+			if semi := strings.IndexByte(head, ';'); semi >= 0 {
+				name, params = head[:semi], head[semi:]
+			}
+			return property{Name: strings.ToUpper(name), Params: params, Value: line[i+1:]}, nil
+		}
+	}
+
+	return property{}, errors.New("invalid iCalendar line: " + line)
+}
+
+// String writes the component in iCalendar format, folding long lines
+func (c *component) String() string {
+	var b strings.Builder
+	c.write(&b)
+	return b.String()
+}
+
+func (c *component) write(b *strings.Builder) {
+	writeLine(b, "BEGIN:"+c.Name)
+	for _, prop := range c.Props {
+		writeLine(b, prop.Name+prop.Params+":"+prop.Value)
+	}
+	for _, child := range c.Components {
+		child.write(b)
+	}
+	writeLine(b, "END:"+c.Name)
+}
+
+// writeLine folds a content line at 75 octets, never breaking a multibyte character
+func writeLine(b *strings.Builder, line string) {
+	for len(line) > 75 {
+		cut := 75
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// child finds the first sub-component with a name
+func (c *component) child(name string) *component {
+	for _, child := range c.Components {
+		if child.Name == name {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// get finds a property by name, nil if the component does not have it
+func (c *component) get(name string) *property {
+	for i := range c.Props {
+		if c.Props[i].Name == name {
+			return &c.Props[i]
+		}
+	}
+
+	return nil
+}
+
+// set replaces a property, or adds it if the component does not have it
+func (c *component) set(name, params, value string) {
+	if prop := c.get(name); prop != nil {
+		prop.Params, prop.Value = params, value
+		return
+	}
+
+	c.Props = append(c.Props, property{Name: name, Params: params, Value: value})
+}
+
+// remove drops every property with a name
+func (c *component) remove(name string) {
+	props := c.Props[:0]
+	for _, prop := range c.Props {
+		if prop.Name != name {
+			props = append(props, prop)
+		}
+	}
+	c.Props = props
+}
+
+// param finds the value of a param of the property, without quotes
+func (p property) param(name string) string {
+	for _, param := range strings.Split(p.Params, ";") {
+		key, value, ok := strings.Cut(param, "=")
+		if ok && strings.EqualFold(key, name) {
+			return strings.Trim(value, `"`)
+		}
+	}
+
+	return ""
+}
+
+var (
+	textEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+// escapeText encodes a TEXT value, like SUMMARY or DESCRIPTION
+func escapeText(text string) string {
+	return textEscaper.Replace(strings.ReplaceAll(text, "\r\n", "\n"))
+}
+
+// unescapeText decodes a TEXT value
+func unescapeText(value string) string {
+	return textUnescaper.Replace(value)
+}
@@ -0,0 +1,160 @@
+package caldav
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// Layouts of DATE and DATE-TIME values of iCalendar
+const (
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+)
+
+// newCalendar makes an empty calendar object holding a VTODO
+func newCalendar() *component {
+	return &component{
+		Name:       "VCALENDAR",
+		Props:      []property{{Name: "VERSION", Value: "2.0"}, {Name: "PRODID", Value: "-//ajaxray//geek-life//EN"}},
+		Components: []*component{{Name: "VTODO"}},
+	}
+}
+
+// writeTodo sets the synced fields of a task to the VTODO of a calendar object.
+// Other properties, set by other clients, are kept as they are.
+func writeTodo(calendar *component, task model.Task, now time.Time) {
+	todo := calendar.child("VTODO")
+	if todo.get("UID") == nil {
+		todo.set("UID", "", task.UUID)
+	}
+	stamp := now.UTC().Format(dateTimeLayout) + "Z"
+	todo.set("DTSTAMP", "", stamp)
+	todo.set("LAST-MODIFIED", "", stamp)
+
+	todo.set("SUMMARY", "", escapeText(task.Title))
+	if task.Details != "" {
+		todo.set("DESCRIPTION", "", escapeText(task.Details))
+	} else {
+		todo.remove("DESCRIPTION")
+	}
+
+	switch {
+	case task.DueDate == 0:
+		todo.remove("DUE")
+	case task.DueTime == "":
+		todo.set("DUE", ";VALUE=DATE", time.Unix(task.DueDate, 0).Format(dateLayout))
+	default:
+		// Floating time, as due time of tasks is a time of day wherever the user is
+		todo.set("DUE", "", repository.DueAt(task).Format(dateTimeLayout))
+	}
+
+	if task.Completed {
+		todo.set("STATUS", "", "COMPLETED")
+		todo.set("COMPLETED", "", time.Unix(task.CompletedAt, 0).UTC().Format(dateTimeLayout)+"Z")
+		todo.set("PERCENT-COMPLETE", "", "100")
+	} else {
+		todo.set("STATUS", "", "NEEDS-ACTION")
+		todo.remove("COMPLETED")
+		todo.remove("PERCENT-COMPLETE")
+	}
+}
+
+// readTodo sets the synced fields of a task from a VTODO.
+// A VTODO completed without completion time is taken as completed now.
+func readTodo(todo *component, task *model.Task, now time.Time) {
+	task.Title, task.Details = textOf(todo, "SUMMARY"), textOf(todo, "DESCRIPTION")
+
+	task.DueDate, task.DueTime = 0, ""
+	if due := todo.get("DUE"); due != nil {
+		if at, dateOnly, err := parseTime(*due); err == nil {
+			task.DueDate = repository.RoundDueDate(at)
+			if !dateOnly {
+				task.DueTime = at.Format(repository.DueTimeLayout)
+			}
+		}
+	}
+
+	status, completed := todo.get("STATUS"), todo.get("COMPLETED")
+	task.Completed = completed != nil
+	if status != nil {
+		task.Completed = strings.EqualFold(status.Value, "COMPLETED")
+	}
+
+	switch {
+	case !task.Completed:
+		task.CompletedAt = 0
+	case completed != nil:
+		if at, _, err := parseTime(*completed); err == nil {
+			task.CompletedAt = at.Unix()
+		}
+	}
+	if task.Completed && task.CompletedAt == 0 {
+		task.CompletedAt = now.Unix()
+	}
+}
+
+// modifiedAt tells when a VTODO was changed last, 0 if it does not say
+func modifiedAt(todo *component) int64 {
+	for _, name := range []string{"LAST-MODIFIED", "DTSTAMP"} {
+		if prop := todo.get(name); prop != nil {
+			if at, _, err := parseTime(*prop); err == nil {
+				return at.Unix()
+			}
+		}
+	}
+
+	return 0
+}
+
+// textOf provides the decoded value of a TEXT property, empty if the component does not have it
+func textOf(c *component, name string) string {
+	if prop := c.get(name); prop != nil {
+		return unescapeText(prop.Value)
+	}
+
+	return ""
+}
+
+// parseTime reads a DATE or DATE-TIME value. Floating times and unknown time zones are taken as local time.
+func parseTime(prop property) (at time.Time, dateOnly bool, err error) {
+	if len(prop.Value) == len(dateLayout) {
+		at, err = time.ParseInLocation(dateLayout, prop.Value, time.Local)
+		return at, true, err
+	}
+
+	if strings.HasSuffix(prop.Value, "Z") {
+		at, err = time.Parse(dateTimeLayout, strings.TrimSuffix(prop.Value, "Z"))
+		return at.Local(), false, err
+	}
+
+	location := time.Local
+	if tzid := prop.param("TZID"); tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	at, err = time.ParseInLocation(dateTimeLayout, prop.Value, location)
+
+	return at.Local(), false, err
+}
+
+// hashTask fingerprints the synced fields of a task, including its project, to tell if they changed since last sync
+func hashTask(task model.Task) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%d\x00%s\x00%s\x00%d\x00%s\x00%t\x00%d",
+		task.ProjectID, task.Title, task.Details, task.DueDate, task.DueTime, task.Completed, task.CompletedAt)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// hashProject fingerprints the fields of a project edited by the user, to tell if it changed since last sync
+func hashProject(project model.Project) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s\x00%s\x00%d\x00%t", project.Title, project.Description, project.ParentID, project.Archived)))
+
+	return hex.EncodeToString(sum[:])
+}
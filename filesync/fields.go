@@ -0,0 +1,131 @@
+package filesync
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// Kinds of synchronized records
+const (
+	kindProject = "project"
+	kindTask    = "task"
+)
+
+// purgedField is the pseudo field of changes deleting a record permanently
+const purgedField = "-"
+
+// Synchronized fields of records. Working project, reminder state and time entries are kept local.
+var (
+	projectFields = []string{"Title", "ParentID", "Archived", "Position", "Description", "DeletedAt"}
+	taskFields    = []string{"ProjectID", "ParentID", "Title", "Details", "Completed", "CompletedAt", "DueDate", "DueTime",
+		"StartDate", "Priority", "Tags", "Recurrence", "BlockedBy", "Estimate", "Spent", "Pomodoros", "RemindBefore",
+		"Position", "DeletedAt"}
+)
+
+// refs translates references between records, which are IDs in a database and UUIDs across replicas
+type refs struct {
+	projectUUID, taskUUID map[int64]string
+	projectID, taskID     map[string]int64
+}
+
+func newRefs(projects []model.Project, tasks []model.Task) *refs {
+	r := &refs{
+		projectUUID: make(map[int64]string), taskUUID: make(map[int64]string),
+		projectID: make(map[string]int64), taskID: make(map[string]int64),
+	}
+	for _, project := range projects {
+		r.addProject(project)
+	}
+	for _, task := range tasks {
+		r.addTask(task)
+	}
+
+	return r
+}
+
+func (r *refs) addProject(project model.Project) {
+	r.projectUUID[project.ID], r.projectID[project.UUID] = project.UUID, project.ID
+}
+
+func (r *refs) addTask(task model.Task) {
+	r.taskUUID[task.ID], r.taskID[task.UUID] = task.UUID, task.ID
+}
+
+// encodeFields makes values of synchronized fields of a record, with references as UUIDs
+func (r *refs) encodeFields(kind string, record interface{}) map[string]json.RawMessage {
+	names := projectFields
+	if kind == kindTask {
+		names = taskFields
+	}
+
+	fields := make(map[string]json.RawMessage, len(names))
+	v := reflect.ValueOf(record)
+	for _, name := range names {
+		value := v.FieldByName(name).Interface()
+		switch {
+		case name == "ProjectID":
+			value = r.projectUUID[value.(int64)]
+		case name == "ParentID" && kind == kindProject:
+			value = r.projectUUID[value.(int64)]
+		case name == "ParentID":
+			value = r.taskUUID[value.(int64)]
+		case name == "BlockedBy":
+			var blockers []string
+			for _, id := range value.([]int64) {
+				if UUID, ok := r.taskUUID[id]; ok {
+					blockers = append(blockers, UUID)
+				}
+			}
+			value = blockers
+		}
+
+		// Empty slices are stored as nil by some backends, so both are encoded the same
+		if rv := reflect.ValueOf(value); rv.Kind() == reflect.Slice && rv.Len() == 0 {
+			value = nil
+		}
+		fields[name], _ = json.Marshal(value)
+	}
+
+	return fields
+}
+
+// decodeField sets a synchronized field of a record, translating references back to IDs.
+// References to unknown records are dropped.
+func (r *refs) decodeField(kind string, record interface{}, name string, value json.RawMessage) error {
+	field := reflect.ValueOf(record).Elem().FieldByName(name)
+
+	var UUIDs []string
+	var UUID string
+	switch name {
+	case "ProjectID", "ParentID":
+		if err := json.Unmarshal(value, &UUID); err != nil {
+			return err
+		}
+		ids := r.taskID
+		if name == "ProjectID" || kind == kindProject {
+			ids = r.projectID
+		}
+		field.SetInt(ids[UUID])
+	case "BlockedBy":
+		if err := json.Unmarshal(value, &UUIDs); err != nil {
+			return err
+		}
+		var blockers []int64
+		for _, UUID := range UUIDs {
+			if id, ok := r.taskID[UUID]; ok {
+				blockers = append(blockers, id)
+			}
+		}
+		field.Set(reflect.ValueOf(blockers))
+	default:
+		decoded := reflect.New(field.Type())
+		if err := json.Unmarshal(value, decoded.Interface()); err != nil {
+			return err
+		}
+		field.Set(decoded.Elem())
+	}
+
+	return nil
+}
@@ -0,0 +1,494 @@
+// Package filesync synchronizes projects and tasks between replicas of geek-life through a shared directory,
+// e.g. a folder kept in sync by Syncthing or a network drive.
+//
+// Every replica exports its changes to a log file in the directory, setting fields of records identified by UUID.
+// Each value is versioned by the time and replica it was set on, and remembers the version it replaced.
+// Changes of other replicas are merged field by field: a value set on top of the local one replaces it,
+// while a field changed on both sides since they last synchronized is a conflict, won by the latest change.
+// Conflicts are reported, so that the losing value can be restored by hand if needed.
+//
+// What a replica knows about synchronization is kept in a state file next to its database.
+// Working project, pending reminders and time entries are not synchronized.
+package filesync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// Syncer synchronizes a database with the replicas sharing a directory
+type Syncer struct {
+	transactor repository.Transactor
+	dir        string
+	statePath  string
+	host       string
+}
+
+// Result describes what a synchronization did
+type Result struct {
+	Replica   string
+	Exported  int // Local changes added to the log
+	Imported  int // Changes of other replicas applied locally
+	Conflicts []Conflict
+}
+
+// Conflict is a field changed both locally and on another replica since they last synchronized
+type Conflict struct {
+	Kind, Title, Field string
+	Local, Remote      string
+	Host               string // Host of the other replica
+	RemoteWon          bool
+}
+
+func (c Conflict) String() string {
+	kept, lost := c.Local, c.Remote
+	if c.RemoteWon {
+		kept, lost = c.Remote, c.Local
+	}
+
+	return fmt.Sprintf("%s %q: %s changed here to %s and on %s to %s. Kept %s, lost %s",
+		c.Kind, c.Title, c.Field, c.Local, c.Host, c.Remote, kept, lost)
+}
+
+// New makes a Syncer of the database behind transactor, sharing changes through dir and keeping its state at statePath
+func New(transactor repository.Transactor, dir, statePath string) *Syncer {
+	host, _ := os.Hostname()
+	return &Syncer{transactor: transactor, dir: dir, statePath: statePath, host: host}
+}
+
+// Sync exports local changes to the log of this replica, then merges the changes logged by other replicas.
+// Database is changed in a single transaction, and the state is saved only if everything succeeds.
+func (s *Syncer) Sync(now time.Time) (Result, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return Result{}, err
+	}
+	state, err := loadState(s.statePath, s.host)
+	if err != nil {
+		return Result{}, err
+	}
+	logs, err := readLogs(s.dir, state.Replica)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Replica: state.Replica}
+	err = s.transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		m := &merger{state: state, projects: projects, tasks: tasks, now: now.Unix(), result: &result}
+		if err := m.load(); err != nil {
+			return err
+		}
+		if err := m.export(); err != nil {
+			return err
+		}
+
+		return m.importLogs(logs)
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if err := state.writeLog(s.dir); err != nil {
+		return result, err
+	}
+
+	return result, state.save(s.statePath)
+}
+
+// merger merges changes into a database, within a transaction
+type merger struct {
+	state    *State
+	projects repository.ProjectRepository
+	tasks    repository.TaskRepository
+	now      int64
+	result   *Result
+
+	projectOf map[string]*model.Project // Local projects by UUID
+	taskOf    map[string]*model.Task    // Local tasks by UUID
+	refs      *refs
+}
+
+// load reads all local records, including the ones in trash
+func (m *merger) load() error {
+	projects, err := m.projects.GetAll()
+	if err != nil {
+		return err
+	}
+	trashed, err := m.projects.GetTrashed()
+	if err != nil {
+		return err
+	}
+	tasks, err := m.tasks.Find(repository.TaskQuery{Trash: repository.TrashedOrNot})
+	if err != nil {
+		return err
+	}
+
+	projects = append(projects, trashed...)
+	m.projectOf, m.taskOf = make(map[string]*model.Project), make(map[string]*model.Task)
+	for i := range projects {
+		if projects[i].UUID != "" {
+			m.projectOf[projects[i].UUID] = &projects[i]
+		}
+	}
+	for i := range tasks {
+		if tasks[i].UUID != "" {
+			m.taskOf[tasks[i].UUID] = &tasks[i]
+		}
+	}
+	m.refs = newRefs(projects, tasks)
+
+	return nil
+}
+
+// export logs the fields changed locally since the last synchronization, and the records deleted permanently
+func (m *merger) export() error {
+	for UUID, project := range m.projectOf {
+		m.exportRecord(kindProject, UUID, m.refs.encodeFields(kindProject, *project), m.projectChangedAt(*project))
+	}
+	for UUID, task := range m.taskOf {
+		m.exportRecord(kindTask, UUID, m.refs.encodeFields(kindTask, *task), m.taskChangedAt(*task))
+	}
+
+	for key := range m.state.Records {
+		kind, UUID, _ := strings.Cut(key, ":")
+		if (kind == kindProject && m.projectOf[UUID] == nil) || (kind == kindTask && m.taskOf[UUID] == nil) {
+			version := Version{At: m.now, Replica: m.state.Replica}
+			m.state.addChange(Change{Kind: kind, UUID: UUID, Field: purgedField, FieldState: FieldState{Version: version}})
+			m.state.Purged[key] = version
+			delete(m.state.Records, key)
+			m.result.Exported++
+		}
+	}
+
+	return nil
+}
+
+func (m *merger) exportRecord(kind, UUID string, fields map[string]json.RawMessage, changedAt func(field string) int64) {
+	key := recordKey(kind, UUID)
+	known := m.state.Records[key]
+	if known == nil {
+		known = make(map[string]FieldState)
+		m.state.Records[key] = known
+	}
+
+	for _, name := range fieldNames(kind) {
+		old, ok := known[name]
+		if ok && sameValue(old.Value, fields[name]) {
+			continue
+		}
+
+		state := FieldState{Value: fields[name], Version: Version{At: changedAt(name), Replica: m.state.Replica}, Base: old.Version}
+		if ok && old.Version.Replica == m.state.Replica {
+			// Consecutive local changes are based on the version they started from
+			state.Base = old.Base
+		}
+		if ok && state.Version.At <= old.Version.At {
+			state.Version.At = old.Version.At + 1
+		}
+
+		known[name] = state
+		m.state.addChange(Change{Kind: kind, UUID: UUID, Field: name, FieldState: state})
+		m.result.Exported++
+	}
+}
+
+// projectChangedAt takes the time of last update of a project as the change time of its fields, as projects have no history
+func (m *merger) projectChangedAt(project model.Project) func(field string) int64 {
+	return func(string) int64 {
+		if project.UpdatedAt != 0 {
+			return project.UpdatedAt
+		}
+		return m.now
+	}
+}
+
+// taskChangedAt finds when fields of a task were changed last, by its history. Fields not in history take the time of last update.
+func (m *merger) taskChangedAt(task model.Task) func(field string) int64 {
+	var changedAt map[string]int64
+	return func(field string) int64 {
+		if changedAt == nil {
+			changedAt = make(map[string]int64)
+			history, _ := m.tasks.GetHistory(task)
+			for _, change := range history {
+				if change.ChangedAt > changedAt[change.Field] {
+					changedAt[change.Field] = change.ChangedAt
+				}
+			}
+		}
+
+		if at, ok := changedAt[field]; ok {
+			return at
+		}
+		if task.UpdatedAt != 0 {
+			return task.UpdatedAt
+		}
+		return m.now
+	}
+}
+
+// importLogs merges changes of other replicas logged since the last synchronization.
+// Missing records are created first, so that references among them are resolved regardless of the order of logs.
+func (m *merger) importLogs(logs []LogFile) error {
+	pending := make([][]Change, len(logs))
+	for i, log := range logs {
+		for _, change := range log.Changes {
+			if change.Seq > m.state.Imported[log.Replica] {
+				pending[i] = append(pending[i], change)
+			}
+		}
+	}
+
+	for _, kind := range []string{kindProject, kindTask} {
+		for _, changes := range pending {
+			for _, change := range changes {
+				if change.Kind == kind {
+					if err := m.createMissing(change); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	for i, log := range logs {
+		for _, kind := range []string{kindProject, kindTask} {
+			if err := m.importChanges(log, kind, pending[i]); err != nil {
+				return err
+			}
+		}
+		for _, change := range pending[i] {
+			m.state.Imported[log.Replica] = change.Seq
+		}
+	}
+
+	return nil
+}
+
+// createMissing creates a record set by change if it is not known locally, except the ones deleted permanently
+func (m *merger) createMissing(change Change) error {
+	key := recordKey(change.Kind, change.UUID)
+	if change.Field == purgedField || m.state.Records[key] != nil || !m.isKnownField(change.Kind, change.Field) {
+		return nil
+	}
+	if _, purged := m.state.Purged[key]; purged {
+		return nil
+	}
+
+	var title string
+	if change.Field == "Title" {
+		_ = json.Unmarshal(change.Value, &title)
+	}
+
+	switch {
+	case change.Kind == kindProject && m.projectOf[change.UUID] == nil:
+		project, err := m.projects.Create(title, change.UUID)
+		if err != nil {
+			return err
+		}
+		m.projectOf[change.UUID] = &project
+		m.refs.addProject(project)
+	case change.Kind == kindTask && m.taskOf[change.UUID] == nil:
+		task, err := m.tasks.Create(model.Project{}, title, "", change.UUID, 0)
+		if err != nil {
+			return err
+		}
+		m.taskOf[change.UUID] = &task
+		m.refs.addTask(task)
+	}
+
+	return nil
+}
+
+// importChanges merges changes of a kind of records logged by another replica, saving every changed record once
+func (m *merger) importChanges(log LogFile, kind string, changes []Change) error {
+	var order []string
+	byRecord := make(map[string][]Change)
+	for _, change := range changes {
+		if change.Kind != kind {
+			continue
+		}
+		if byRecord[change.UUID] == nil {
+			order = append(order, change.UUID)
+		}
+		byRecord[change.UUID] = append(byRecord[change.UUID], change)
+	}
+
+	for _, UUID := range order {
+		if err := m.importRecord(log, kind, UUID, byRecord[UUID]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *merger) importRecord(log LogFile, kind, UUID string, changes []Change) error {
+	key := recordKey(kind, UUID)
+	if _, purged := m.state.Purged[key]; purged {
+		return nil
+	}
+
+	var record interface{}
+	var title string
+	if kind == kindProject && m.projectOf[UUID] != nil {
+		record, title = m.projectOf[UUID], m.projectOf[UUID].Title
+	} else if kind == kindTask && m.taskOf[UUID] != nil {
+		record, title = m.taskOf[UUID], m.taskOf[UUID].Title
+	}
+
+	for _, change := range changes {
+		if change.Field == purgedField {
+			return m.purge(kind, UUID, change.Version)
+		}
+	}
+	if record == nil {
+		return nil
+	}
+
+	known := m.state.Records[key]
+	if known == nil {
+		known = make(map[string]FieldState)
+		m.state.Records[key] = known
+	}
+
+	changed := false
+	for _, change := range changes {
+		if !m.isKnownField(kind, change.Field) {
+			continue
+		}
+
+		local, ok := known[change.Field]
+		remote := change.FieldState
+		if ok && local.Version == remote.Version {
+			continue
+		}
+
+		take := true
+		if ok {
+			switch {
+			case remote.Base == local.Version:
+				// Changed on top of local value
+			case local.Base == remote.Version:
+				// Local value was changed on top of it
+				take = false
+			case local.Version.Replica != m.state.Replica || sameValue(local.Value, remote.Value):
+				// Not changed locally since, or changed the same way on both sides
+				take = remote.Version.newerThan(local.Version)
+			default:
+				take = remote.Version.newerThan(local.Version)
+				m.result.Conflicts = append(m.result.Conflicts, Conflict{
+					Kind: kind, Title: title, Field: change.Field, Host: log.Host, RemoteWon: take,
+					Local: formatValue(change.Field, local.Value), Remote: formatValue(change.Field, remote.Value),
+				})
+			}
+		}
+		if !take {
+			continue
+		}
+
+		known[change.Field] = remote
+		if ok && sameValue(local.Value, remote.Value) {
+			continue
+		}
+		if err := m.refs.decodeField(kind, record, change.Field, remote.Value); err != nil {
+			return err
+		}
+		changed = true
+		m.result.Imported++
+	}
+
+	if !changed {
+		return nil
+	}
+	if project, ok := record.(*model.Project); ok {
+		return m.projects.Update(project)
+	}
+
+	task := record.(*model.Task)
+	if err := m.tasks.Update(task); err != nil {
+		return err
+	}
+	return repository.ScheduleReminder(m.tasks, task, time.Unix(m.now, 0))
+}
+
+// purge deletes a record permanently deleted by another replica
+func (m *merger) purge(kind, UUID string, version Version) error {
+	key := recordKey(kind, UUID)
+	m.state.Purged[key] = version
+	delete(m.state.Records, key)
+
+	var err error
+	if project := m.projectOf[UUID]; kind == kindProject && project != nil {
+		err = m.projects.Delete(project)
+		delete(m.projectOf, UUID)
+	} else if task := m.taskOf[UUID]; kind == kindTask && task != nil {
+		err = m.tasks.Delete(task)
+		delete(m.taskOf, UUID)
+	} else {
+		return nil
+	}
+
+	if err == nil {
+		m.result.Imported++
+	}
+	return err
+}
+
+// isKnownField tells if a field is synchronized, ignoring the ones added by newer versions of geek-life
+func (m *merger) isKnownField(kind, field string) bool {
+	for _, name := range fieldNames(kind) {
+		if name == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fieldNames(kind string) []string {
+	if kind == kindTask {
+		return taskFields
+	}
+
+	return projectFields
+}
+
+// sameValue tells if two encoded values are equal, regardless of how they are indented in files
+func sameValue(a, b json.RawMessage) bool {
+	var x, y bytes.Buffer
+	if json.Compact(&x, a) != nil || json.Compact(&y, b) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return bytes.Equal(x.Bytes(), y.Bytes())
+}
+
+// formatValue makes a readable text of a field value for conflict reports
+func formatValue(field string, value json.RawMessage) string {
+	if strings.HasSuffix(field, "Date") || strings.HasSuffix(field, "At") {
+		if unix, err := strconv.ParseInt(string(value), 10, 64); err == nil && unix != 0 {
+			return time.Unix(unix, 0).Format("2006-01-02 15:04")
+		}
+	}
+
+	return string(value)
+}
+
+// Report lists conflicts of a synchronization, one per line
+func Report(conflicts []Conflict) string {
+	lines := make([]string, len(conflicts))
+	for i, conflict := range conflicts {
+		lines[i] = conflict.String()
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n")
+}
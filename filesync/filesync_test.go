@@ -0,0 +1,263 @@
+package filesync_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajaxray/geek-life/filesync"
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+	"github.com/ajaxray/geek-life/repository/memory"
+)
+
+// replica is a database synchronizing through a shared directory
+type replica struct {
+	projects repository.ProjectRepository
+	tasks    repository.TaskRepository
+	syncer   *filesync.Syncer
+}
+
+func newReplica(t *testing.T, dir string) *replica {
+	store := memory.NewStore()
+	return &replica{
+		projects: memory.NewProjectRepository(store),
+		tasks:    memory.NewTaskRepository(store),
+		syncer:   filesync.New(memory.NewTransactor(store), dir, filepath.Join(t.TempDir(), "state.json")),
+	}
+}
+
+func (r *replica) sync(t *testing.T) filesync.Result {
+	t.Helper()
+
+	result, err := r.syncer.Sync(time.Now())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	return result
+}
+
+func (r *replica) project(t *testing.T, UUID string) model.Project {
+	t.Helper()
+
+	project, err := r.projects.GetByUUID(UUID)
+	if err != nil {
+		t.Fatalf("project %s: %v", UUID, err)
+	}
+	return project
+}
+
+func (r *replica) task(t *testing.T, UUID string) model.Task {
+	t.Helper()
+
+	task, err := r.tasks.GetByUUID(UUID)
+	if err != nil {
+		t.Fatalf("task %s: %v", UUID, err)
+	}
+	return task
+}
+
+// shared makes two replicas having the same project with a task
+func shared(t *testing.T) (a, b *replica, project model.Project, task model.Task) {
+	dir := t.TempDir()
+	a, b = newReplica(t, dir), newReplica(t, dir)
+
+	project, _ = a.projects.Create("Home", "")
+	task, _ = a.tasks.Create(project, "Buy milk", "", "", 0)
+	a.sync(t)
+	b.sync(t)
+
+	return a, b, project, task
+}
+
+// nextSecond waits for the clock to tick, as change times are kept in seconds.
+// Changes to compare by time are made in different seconds, and after the second records were shared in.
+func nextSecond() {
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+}
+
+func TestSyncCreatesRecords(t *testing.T) {
+	dir := t.TempDir()
+	a, b := newReplica(t, dir), newReplica(t, dir)
+
+	project, _ := a.projects.Create("Home", "")
+	_ = a.projects.UpdateField(&project, "Description", "Chores")
+	parent, _ := a.tasks.Create(project, "Clean", "", "", repository.RoundDueDate(time.Now()))
+	child, _ := a.tasks.Create(project, "Kitchen", "", "", 0)
+	_ = a.tasks.UpdateField(&child, "ParentID", parent.ID)
+	_ = a.tasks.UpdateField(&child, "Tags", []string{"home"})
+	_ = a.tasks.UpdateField(&child, "BlockedBy", []int64{parent.ID})
+
+	if result := a.sync(t); result.Exported == 0 || result.Imported != 0 {
+		t.Errorf("first sync of a = %+v, want exported changes only", result)
+	}
+	if result := b.sync(t); result.Imported == 0 || result.Exported != 0 || len(result.Conflicts) != 0 {
+		t.Errorf("first sync of b = %+v, want imported changes only", result)
+	}
+
+	got := b.project(t, project.UUID)
+	if got.Title != "Home" || got.Description != "Chores" {
+		t.Errorf("synced project = %+v", got)
+	}
+	gotParent, gotChild := b.task(t, parent.UUID), b.task(t, child.UUID)
+	if gotParent.ProjectID != got.ID || gotParent.DueDate != parent.DueDate {
+		t.Errorf("synced parent task = %+v", gotParent)
+	}
+	if gotChild.ProjectID != got.ID || gotChild.ParentID != gotParent.ID || strings.Join(gotChild.Tags, ",") != "home" ||
+		len(gotChild.BlockedBy) != 1 || gotChild.BlockedBy[0] != gotParent.ID {
+		t.Errorf("synced subtask = %+v", gotChild)
+	}
+
+	// Nothing left to exchange
+	if result := b.sync(t); result.Exported != 0 || result.Imported != 0 {
+		t.Errorf("second sync of b = %+v", result)
+	}
+	if result := a.sync(t); result.Exported != 0 || result.Imported != 0 {
+		t.Errorf("second sync of a = %+v", result)
+	}
+}
+
+func TestSyncMergesFields(t *testing.T) {
+	a, b, project, task := shared(t)
+
+	localTask := a.task(t, task.UUID)
+	_ = a.tasks.UpdateField(&localTask, "Title", "Buy oat milk")
+	remoteTask := b.task(t, task.UUID)
+	_ = b.tasks.UpdateField(&remoteTask, "Details", "From the corner shop")
+	remoteProject := b.project(t, project.UUID)
+	_ = b.projects.UpdateField(&remoteProject, "Archived", true)
+
+	a.sync(t)
+	if result := b.sync(t); len(result.Conflicts) != 0 {
+		t.Errorf("conflicts = %v, want none for different fields", result.Conflicts)
+	}
+	a.sync(t)
+
+	for name, r := range map[string]*replica{"a": a, "b": b} {
+		if got := r.task(t, task.UUID); got.Title != "Buy oat milk" || got.Details != "From the corner shop" {
+			t.Errorf("task on %s = %q, %q", name, got.Title, got.Details)
+		}
+		if got := r.project(t, project.UUID); !got.Archived {
+			t.Errorf("project on %s is not archived", name)
+		}
+	}
+}
+
+func TestSyncConflict(t *testing.T) {
+	a, b, _, task := shared(t)
+	nextSecond()
+
+	localTask := a.task(t, task.UUID)
+	_ = a.tasks.UpdateField(&localTask, "Title", "Buy oat milk")
+	nextSecond()
+	remoteTask := b.task(t, task.UUID)
+	_ = b.tasks.UpdateField(&remoteTask, "Title", "Buy soy milk")
+
+	a.sync(t)
+	result := b.sync(t)
+	if len(result.Conflicts) != 1 {
+		t.Fatalf("conflicts on b = %v, want 1", result.Conflicts)
+	}
+	conflict := result.Conflicts[0]
+	if conflict.Field != "Title" || conflict.Local != `"Buy soy milk"` || conflict.Remote != `"Buy oat milk"` || conflict.RemoteWon {
+		t.Errorf("conflict on b = %+v, want local later change kept", conflict)
+	}
+
+	result = a.sync(t)
+	if len(result.Conflicts) != 1 || !result.Conflicts[0].RemoteWon {
+		t.Errorf("conflicts on a = %+v, want remote later change taken", result.Conflicts)
+	}
+
+	for name, r := range map[string]*replica{"a": a, "b": b} {
+		if got := r.task(t, task.UUID); got.Title != "Buy soy milk" {
+			t.Errorf("title on %s = %q, want the later change", name, got.Title)
+		}
+	}
+}
+
+// Project fields carry the time they were changed, not the time they were synchronized
+func TestSyncProjectChangeTime(t *testing.T) {
+	a, b, project, _ := shared(t)
+	nextSecond()
+
+	early := a.project(t, project.UUID)
+	_ = a.projects.UpdateField(&early, "Title", "House")
+	nextSecond()
+	late := b.project(t, project.UUID)
+	_ = b.projects.UpdateField(&late, "Title", "Home sweet home")
+
+	// The earlier change is synchronized an hour later
+	b.sync(t)
+	result, err := a.syncer.Sync(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(result.Conflicts) != 1 || !result.Conflicts[0].RemoteWon {
+		t.Errorf("conflicts on a = %+v, want the later change of b taken", result.Conflicts)
+	}
+	b.sync(t)
+
+	for name, r := range map[string]*replica{"a": a, "b": b} {
+		if got := r.project(t, project.UUID); got.Title != "Home sweet home" {
+			t.Errorf("project on %s = %q, want the later change", name, got.Title)
+		}
+	}
+}
+
+func TestSyncPurge(t *testing.T) {
+	a, b, _, task := shared(t)
+
+	localTask := a.task(t, task.UUID)
+	if err := a.tasks.Delete(&localTask); err != nil {
+		t.Fatal(err)
+	}
+	a.sync(t)
+	b.sync(t)
+
+	if _, err := b.tasks.GetByUUID(task.UUID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("task deleted on a is still on b: %v", err)
+	}
+
+	// Changes made meanwhile do not bring it back
+	if result := a.sync(t); result.Imported != 0 {
+		t.Errorf("sync after purge = %+v", result)
+	}
+	if _, err := a.tasks.GetByUUID(task.UUID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("purged task came back on a: %v", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	due := time.Date(2024, 5, 20, 9, 30, 0, 0, time.Local).Unix()
+	conflicts := []filesync.Conflict{
+		{Kind: "task", Title: "Buy milk", Field: "DueDate", Local: "0", Remote: strconv.FormatInt(due, 10), Host: "laptop"},
+		{Kind: "project", Title: "Home", Field: "Title", Local: `"House"`, Remote: `"Flat"`, Host: "desktop", RemoteWon: true},
+	}
+
+	want := `project "Home": Title changed here to "House" and on desktop to "Flat". Kept "Flat", lost "House"` + "\n" +
+		`task "Buy milk": DueDate changed here to 0 and on laptop to ` + strconv.FormatInt(due, 10) + `. Kept 0, lost ` + strconv.FormatInt(due, 10)
+	if got := filesync.Report(conflicts); got != want {
+		t.Errorf("Report =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReportFormatsTimes(t *testing.T) {
+	a, b, _, task := shared(t)
+	nextSecond()
+
+	due := time.Date(2024, 5, 20, 0, 0, 0, 0, time.Local).Unix()
+	localTask := a.task(t, task.UUID)
+	_ = a.tasks.UpdateField(&localTask, "DueDate", due)
+	nextSecond()
+	remoteTask := b.task(t, task.UUID)
+	_ = b.tasks.UpdateField(&remoteTask, "DueDate", due+86400)
+
+	a.sync(t)
+	report := filesync.Report(b.sync(t).Conflicts)
+	if !strings.Contains(report, "DueDate changed here to 2024-05-21 00:00 and on ") || !strings.Contains(report, "to 2024-05-20 00:00") {
+		t.Errorf("report = %s, want readable dates", report)
+	}
+}
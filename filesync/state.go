@@ -0,0 +1,185 @@
+package filesync
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// logPrefix and logSuffix wrap replica ID in the name of its change log in shared directory
+const (
+	logPrefix = "geek-life-"
+	logSuffix = ".json"
+)
+
+// Version identifies a value of a field, by when and on which replica it was set
+type Version struct {
+	At      int64  `json:"at"`
+	Replica string `json:"replica"`
+}
+
+// newerThan tells if v wins over other, breaking ties of time by replica ID so that every replica picks the same
+func (v Version) newerThan(other Version) bool {
+	if v.At != other.At {
+		return v.At > other.At
+	}
+
+	return v.Replica > other.Replica
+}
+
+// FieldState is a value of a field, along with its version and the version it replaced.
+// Base of a value set by a series of local changes is the version the series started from.
+type FieldState struct {
+	Value   json.RawMessage `json:"value"`
+	Version Version         `json:"version"`
+	Base    Version         `json:"base"`
+}
+
+// Change is an entry of change log, setting a field of a project or task.
+// Field purgedField means the record was deleted permanently.
+type Change struct {
+	Seq   int64  `json:"seq"`
+	Kind  string `json:"kind"`
+	UUID  string `json:"uuid"`
+	Field string `json:"field"`
+	FieldState
+}
+
+// LogFile is the change log a replica exports to shared directory. It is compacted to the last change of every field.
+type LogFile struct {
+	Replica string   `json:"replica"`
+	Host    string   `json:"host"`
+	Changes []Change `json:"changes"`
+}
+
+// State is what a replica knows about synchronization, kept next to its database
+type State struct {
+	Replica  string                           `json:"replica"`
+	Host     string                           `json:"host"`
+	Seq      int64                            `json:"seq"`
+	Records  map[string]map[string]FieldState `json:"records"`  // Last synchronized fields of records, by recordKey
+	Purged   map[string]Version               `json:"purged"`   // Permanently deleted records, by recordKey
+	Imported map[string]int64                 `json:"imported"` // Last imported Seq of other replicas
+	Log      []Change                         `json:"log"`
+}
+
+// recordKey identifies a project or task among all records
+func recordKey(kind, UUID string) string {
+	return kind + ":" + UUID
+}
+
+// loadState reads state of the replica, or starts a new replica if there is no state yet.
+// A state copied from another host (along with the database) starts a new replica too, keeping the synchronized fields.
+func loadState(path, host string) (*State, error) {
+	state := &State{}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = json.Unmarshal(data, state)
+	} else if errors.Is(err, os.ErrNotExist) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Replica == "" || state.Host != host {
+		state.Replica, state.Host, state.Seq = newReplicaID(), host, 0
+		state.Imported, state.Log = nil, nil
+	}
+	if state.Records == nil {
+		state.Records = make(map[string]map[string]FieldState)
+	}
+	if state.Purged == nil {
+		state.Purged = make(map[string]Version)
+	}
+	if state.Imported == nil {
+		state.Imported = make(map[string]int64)
+	}
+
+	return state, nil
+}
+
+// save writes state of the replica
+func (state *State) save(path string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// addChange appends a change to the log, dropping the earlier changes it overrides
+func (state *State) addChange(change Change) {
+	state.Seq++
+	change.Seq = state.Seq
+
+	log := state.Log[:0]
+	for _, c := range state.Log {
+		if c.Kind != change.Kind || c.UUID != change.UUID || (c.Field != change.Field && change.Field != purgedField) {
+			log = append(log, c)
+		}
+	}
+	state.Log = append(log, change)
+}
+
+// writeLog exports the change log of the replica to shared directory
+func (state *State) writeLog(dir string) error {
+	data, err := json.MarshalIndent(LogFile{Replica: state.Replica, Host: state.Host, Changes: state.Log}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(dir, logPrefix+state.Replica+logSuffix), data)
+}
+
+// readLogs reads change logs of other replicas from shared directory, ordered by their replica IDs
+func readLogs(dir, replica string) ([]LogFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []LogFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, logPrefix) || !strings.HasSuffix(name, logSuffix) ||
+			name == logPrefix+replica+logSuffix {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		var log LogFile
+		if err := json.Unmarshal(data, &log); err != nil {
+			return nil, errors.New("could not read " + name + ": " + err.Error())
+		}
+		sort.Slice(log.Changes, func(i, j int) bool { return log.Changes[i].Seq < log.Changes[j].Seq })
+		logs = append(logs, log)
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].Replica < logs[j].Replica })
+
+	return logs, nil
+}
+
+// writeFileAtomic replaces a file through a temporary one, so that readers never see it half written
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// newReplicaID identifies a new replica among the ones sharing a directory
+func newReplicaID() string {
+	return repository.NewUUID()
+}
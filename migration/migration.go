@@ -0,0 +1,126 @@
+// Package migration applies ordered, versioned changes to a database schema and its data.
+//
+// Every storage backend provides its own list of steps and a Store that remembers
+// the version a database is at. Step versions are shared by all backends, so version N
+// means the same data model regardless of where it is stored.
+package migration
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNewerSchema is returned when a database was migrated by a newer version of the app
+var ErrNewerSchema = errors.New("database schema is newer than this version of geek-life understands")
+
+// Step is a single change of schema or data, bringing the database to Version
+type Step struct {
+	Version     int
+	Description string
+	Apply       func() error
+}
+
+// Store keeps track of the schema version of a database
+type Store interface {
+	// SchemaVersion returns the version of last applied step, 0 if never migrated
+	SchemaVersion() (int, error)
+	// SetSchemaVersion records that steps up to version are applied
+	SetSchemaVersion(version int) error
+	// IsNew tells if the database has no data yet, so there is nothing to back up
+	IsNew() (bool, error)
+	// Backup copies the database file aside and returns the path of copy
+	Backup(version int) (string, error)
+}
+
+// Result describes what a migration run did, or would do in case of a dry run
+type Result struct {
+	FromVersion int
+	ToVersion   int
+	Steps       []Step
+	BackupPath  string
+	DryRun      bool
+}
+
+// Migrator applies pending steps on a Store
+type Migrator struct {
+	store Store
+	steps []Step
+}
+
+// New creates a Migrator. Steps must be numbered 1, 2, 3... in order.
+func New(store Store, steps ...Step) *Migrator {
+	for i, step := range steps {
+		if step.Version != i+1 {
+			panic(fmt.Sprintf("migration step %q has version %d, expected %d", step.Description, step.Version, i+1))
+		}
+	}
+
+	return &Migrator{store: store, steps: steps}
+}
+
+// LatestVersion is the schema version this build of the app understands
+func (m *Migrator) LatestVersion() int {
+	return len(m.steps)
+}
+
+// Pending lists the steps not applied yet.
+// It fails with ErrNewerSchema if the database is ahead of the app.
+func (m *Migrator) Pending() ([]Step, error) {
+	current, err := m.store.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if current > m.LatestVersion() {
+		return nil, fmt.Errorf("%w (database: v%d, app: v%d)", ErrNewerSchema, current, m.LatestVersion())
+	}
+
+	return m.steps[current:], nil
+}
+
+// Migrate applies all pending steps, after taking a backup of a non-empty database.
+// With dryRun, nothing is changed and the Result lists the steps that would be applied.
+func (m *Migrator) Migrate(dryRun bool) (Result, error) {
+	result := Result{DryRun: dryRun}
+
+	pending, err := m.Pending()
+	if err != nil {
+		return result, err
+	}
+
+	result.FromVersion = m.LatestVersion() - len(pending)
+	result.ToVersion = result.FromVersion
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	if dryRun {
+		result.Steps = pending
+		result.ToVersion = m.LatestVersion()
+		return result, nil
+	}
+
+	isNew, err := m.store.IsNew()
+	if err != nil {
+		return result, err
+	}
+	if !isNew {
+		if result.BackupPath, err = m.store.Backup(result.FromVersion); err != nil {
+			return result, fmt.Errorf("could not back up database before migrating: %w", err)
+		}
+	}
+
+	for _, step := range pending {
+		if err := step.Apply(); err != nil {
+			return result, fmt.Errorf("migration to v%d (%s) failed: %w", step.Version, step.Description, err)
+		}
+		if err := m.store.SetSchemaVersion(step.Version); err != nil {
+			return result, err
+		}
+
+		result.Steps = append(result.Steps, step)
+		result.ToVersion = step.Version
+	}
+
+	return result, nil
+}
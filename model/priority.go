@@ -0,0 +1,23 @@
+package model
+
+// Priority indicates how important a task is
+type Priority int
+
+// Priority levels, from lowest to highest
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"None", "Low", "Medium", "High", "Urgent"}
+
+func (p Priority) String() string {
+	if p < PriorityNone || p > PriorityUrgent {
+		return "Unknown"
+	}
+
+	return priorityNames[p]
+}
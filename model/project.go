@@ -1,9 +1,15 @@
-package model
-
-// Project represent a collection of related tasks (tags of Habitica)
-type Project struct {
-	ID      int64  `storm:"id,increment",json:"id"`
-	Title   string `storm:"index",json:"title"`
-	UUID    string `storm:"unique",json:"uuid,omitempty"`
-	Working bool   `json:"working"` // 标记是否正在工作中
-}
+package model
+
+// Project represent a collection of related tasks (tags of Habitica)
+type Project struct {
+	ID          int64  `storm:"id,increment"`
+	Title       string `storm:"index"`
+	UUID        string `storm:"unique"`
+	Working     bool   `json:"working"` // 标记是否正在工作中
+	DeletedAt   int64  `storm:"index"`  // When the project was moved to trash, 0 if it is not trashed
+	Archived    bool   // Archived projects are kept out of the way, read-only
+	Position    int64  // Manual order of the project in lists
+	ParentID    int64  // Parent project in the hierarchy, 0 for a top level project
+	Description string // Notes about the project, in markdown
+	UpdatedAt   int64  // When the project was changed last, 0 if it was not changed since this was recorded
+}
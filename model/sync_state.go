@@ -0,0 +1,12 @@
+package model
+
+// SyncState is what CalDAV sync knows about a task, to sync only what changed since the last run.
+// The sync state of a project, having no TaskID, pairs the project with its collection on server.
+type SyncState struct {
+	ID        int64  `storm:"id,increment"`
+	TaskID    int64  `storm:"unique"`
+	ProjectID int64  `storm:"unique"` // Project of a collection, 0 in sync states of tasks
+	Href      string // Path of the VTODO resource of the task, or of the collection of the project, on server
+	ETag      string // ETag of the resource when it was last synced
+	Hash      string // Hash of synced fields of the task when it was last synced
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// NormalizeTag converts a tag to its stored form: lowercase, without leading '#', spaces, commas and brackets
+func NormalizeTag(tag string) string {
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+
+	return strings.ToLower(strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || strings.ContainsRune(",[]", r) {
+			return -1
+		}
+		return r
+	}, tag))
+}
+
+// NormalizeTags normalizes a list of tags, dropping empty and duplicate ones. Result is sorted.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+
+	for _, tag := range tags {
+		if tag = NormalizeTag(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	sort.Strings(normalized)
+
+	return normalized
+}
+
+// ParseTags extracts #tags from a text, e,g, "Buy milk #home #errand".
+// Returns the text without tags and the normalized list of tags.
+func ParseTags(text string) (string, []string) {
+	var words, tags []string
+
+	for _, word := range strings.Fields(text) {
+		if len(word) > 1 && word[0] == '#' {
+			tags = append(tags, word)
+		} else {
+			words = append(words, word)
+		}
+	}
+
+	return strings.Join(words, " "), NormalizeTags(tags)
+}
+
+// FormatTags renders tags as they are typed, e,g, "#errand #home"
+func FormatTags(tags []string) string {
+	formatted := make([]string, len(tags))
+	for i, tag := range tags {
+		formatted[i] = "#" + tag
+	}
+
+	return strings.Join(formatted, " ")
+}
+
+// HasTag checks if a task is labeled with the tag
+func (t Task) HasTag(tag string) bool {
+	tag = NormalizeTag(tag)
+	for _, own := range t.Tags {
+		if own == tag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -2,12 +2,32 @@ package model
 
 // Task represent a task - the building block of the TaskManager app
 type Task struct {
-	ID          int64  `storm:"id,increment",json:"id"`
-	ProjectID   int64  `storm:"index",json:"project_id"`
-	UUID        string `storm:"unique",json:"uuid,omitempty"`
+	ID          int64  `storm:"id,increment"`
+	ProjectID   int64  `storm:"index"`
+	ParentID    int64  `storm:"index"`
+	UUID        string `storm:"unique"`
 	Title       string `json:"text"`
 	Details     string `json:"notes"`
-	Completed   bool   `storm:"index",json:"completed"`
-	CompletedAt int64  `storm:"index",json:"completed_at,omitempty"`
-	DueDate     int64  `storm:"index",json:"due_date,omitempty"`
+	Completed   bool   `storm:"index"`
+	CompletedAt int64  `storm:"index"`
+	DueDate     int64  `storm:"index"`
+	DueTime     string // Time of day the task is due at, like 15:04. Empty if it is due any time of the day
+	StartDate   int64  `storm:"index"` // Task is deferred until this date, 0 if it can be started any time
+	Priority    Priority
+	Tags        []string
+	Recurrence  string  // RRULE of a repeating task, see package recurrence
+	BlockedBy   []int64 // IDs of tasks that must be completed before this one
+	CreatedAt   int64
+	UpdatedAt   int64
+	DeletedAt   int64 `storm:"index"` // When the task was moved to trash, 0 if it is not trashed
+	Position    int64 // Manual order of the task, breaking ties of every other ordering
+	Pomodoros   int   // Number of completed pomodoros (focus sessions) on the task
+	Estimate    int   // Estimated effort in minutes, 0 if not estimated
+	Spent       int   // Minutes spent on the task as entered manually, besides the time tracked by timer
+
+	RemindBefore int   // Minutes before the due time to remind of the task, NoReminder to not remind
+	RemindAt     int64 `storm:"index"` // When the reminder of the task pops up next, 0 if none is pending
 }
+
+// NoReminder is the RemindBefore value of tasks that should not be reminded
+const NoReminder = -1
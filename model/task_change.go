@@ -0,0 +1,11 @@
+package model
+
+// TaskChange records a change of one field of a task. Values are stored as text, see repository.FormatFieldValue.
+type TaskChange struct {
+	ID        int64 `storm:"id,increment"`
+	TaskID    int64 `storm:"index"`
+	Field     string
+	OldValue  string
+	NewValue  string
+	ChangedAt int64
+}
@@ -0,0 +1,9 @@
+package model
+
+// TimeEntry records a period of time spent on a task. End is 0 while its timer is running.
+type TimeEntry struct {
+	ID     int64 `storm:"id,increment"`
+	TaskID int64 `storm:"index"`
+	Start  int64
+	End    int64
+}
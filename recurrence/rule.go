@@ -0,0 +1,414 @@
+// Package recurrence parses RFC 5545 style recurrence rules (RRULE) of repeating tasks
+// and calculates the dates they occur on.
+//
+// Supported parts are FREQ (DAILY, WEEKLY, MONTHLY or YEARLY), INTERVAL, BYDAY and BYMONTHDAY.
+// Yearly rules repeat in the month of their start, on BYMONTHDAY if it is given.
+// The non-standard X-FROM=COMPLETION part repeats a task counting from the day it is completed,
+// instead of keeping a fixed schedule from its due date.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule is returned when a rule can not be parsed
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// Frequency is the kind of period a rule repeats in
+type Frequency int
+
+// Possible values of Rule.Freq
+const (
+	Daily Frequency = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencyNames = map[Frequency]string{Daily: "DAILY", Weekly: "WEEKLY", Monthly: "MONTHLY", Yearly: "YEARLY"}
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// Day is a weekday of BYDAY. In monthly rules, a non-zero Ordinal selects one of them in month,
+// e,g, 1MO is the first Monday and -1FR is the last Friday.
+type Day struct {
+	Ordinal int
+	Weekday time.Weekday
+}
+
+// Rule tells when a task repeats
+type Rule struct {
+	Freq           Frequency
+	Interval       int   // Repeat every Interval periods. 0 is treated as 1
+	ByDay          []Day // Weekdays of weekly rules, or (ordinal) weekdays of monthly rules
+	ByMonthDay     int   // Day of month of monthly and yearly rules, negative counts from the end of month
+	FromCompletion bool  // Count next occurrence from completion, instead of due date
+}
+
+// Parse reads a rule like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH".
+// A frequency alone, e,g, "weekly", is accepted as a shorthand.
+func Parse(text string) (Rule, error) {
+	text = strings.ToUpper(strings.TrimSpace(text))
+	text = strings.TrimPrefix(text, "RRULE:")
+	if !strings.Contains(text, "=") {
+		text = "FREQ=" + text
+	}
+
+	rule := Rule{Interval: 1}
+	for _, part := range strings.Split(text, ";") {
+		if part == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("%w: %q is not a NAME=VALUE part", ErrInvalidRule, part)
+		}
+
+		var err error
+		switch name {
+		case "FREQ":
+			err = rule.parseFrequency(value)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("must be a positive number")
+			}
+		case "BYDAY":
+			rule.ByDay, err = parseDays(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = strconv.Atoi(value)
+			if err == nil && (rule.ByMonthDay == 0 || rule.ByMonthDay < -31 || rule.ByMonthDay > 31) {
+				err = errors.New("must be between 1 and 31, or -31 and -1")
+			}
+		case "X-FROM":
+			switch value {
+			case "COMPLETION":
+				rule.FromCompletion = true
+			case "DUE":
+				rule.FromCompletion = false
+			default:
+				err = errors.New("must be COMPLETION or DUE")
+			}
+		default:
+			err = errors.New("is not supported")
+		}
+
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %s %v", ErrInvalidRule, name, err)
+		}
+	}
+
+	return rule, rule.validate()
+}
+
+func (r *Rule) parseFrequency(value string) error {
+	for freq, name := range frequencyNames {
+		if name == value {
+			r.Freq = freq
+			return nil
+		}
+	}
+
+	return errors.New("must be DAILY, WEEKLY, MONTHLY or YEARLY")
+}
+
+func parseDays(value string) ([]Day, error) {
+	var days []Day
+	for _, code := range strings.Split(value, ",") {
+		if len(code) < 2 {
+			return nil, fmt.Errorf("%q is not a weekday", code)
+		}
+
+		day := Day{Weekday: -1}
+		for weekday, weekdayCode := range weekdayCodes {
+			if code[len(code)-2:] == weekdayCode {
+				day.Weekday = time.Weekday(weekday)
+			}
+		}
+		if day.Weekday == -1 {
+			return nil, fmt.Errorf("%q is not a weekday", code)
+		}
+
+		if ordinal := code[:len(code)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("%q has invalid ordinal", code)
+			}
+			day.Ordinal = n
+		}
+
+		days = append(days, day)
+	}
+
+	return days, nil
+}
+
+func (r Rule) validate() error {
+	switch {
+	case r.Freq == 0:
+		return fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	case len(r.ByDay) > 0 && r.Freq != Weekly && r.Freq != Monthly:
+		return fmt.Errorf("%w: BYDAY is only supported in WEEKLY and MONTHLY rules", ErrInvalidRule)
+	case r.ByMonthDay != 0 && r.Freq != Monthly && r.Freq != Yearly:
+		return fmt.Errorf("%w: BYMONTHDAY is only supported in MONTHLY and YEARLY rules", ErrInvalidRule)
+	case r.ByMonthDay != 0 && len(r.ByDay) > 0:
+		return fmt.Errorf("%w: BYDAY and BYMONTHDAY can not be combined", ErrInvalidRule)
+	}
+
+	for _, day := range r.ByDay {
+		if day.Ordinal != 0 && r.Freq != Monthly {
+			return fmt.Errorf("%w: ordinal weekdays are only supported in MONTHLY rules", ErrInvalidRule)
+		}
+	}
+
+	return nil
+}
+
+// String formats the rule in RRULE syntax, to be stored and parsed back
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + frequencyNames[r.Freq]}
+	if r.interval() > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = weekdayCodes[day.Weekday]
+			if day.Ordinal != 0 {
+				codes[i] = strconv.Itoa(day.Ordinal) + codes[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.ByMonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.ByMonthDay))
+	}
+	if r.FromCompletion {
+		parts = append(parts, "X-FROM=COMPLETION")
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Describe explains the rule in plain English, e,g, "every 2 weeks on Mon, Thu"
+func (r Rule) Describe() string {
+	units := map[Frequency]string{Daily: "day", Weekly: "week", Monthly: "month", Yearly: "year"}
+
+	desc := "every " + units[r.Freq]
+	if r.interval() > 1 {
+		desc = fmt.Sprintf("every %d %ss", r.Interval, units[r.Freq])
+	}
+
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = day.Weekday.String()[:3]
+			if day.Ordinal != 0 {
+				days[i] = ordinalName(day.Ordinal) + " " + days[i]
+			}
+		}
+		desc += " on " + strings.Join(days, ", ")
+	}
+
+	switch {
+	case r.ByMonthDay == -1:
+		desc += " on the last day"
+	case r.ByMonthDay < 0:
+		desc += fmt.Sprintf(" on day %d from the end", -r.ByMonthDay)
+	case r.ByMonthDay > 0:
+		desc += fmt.Sprintf(" on day %d", r.ByMonthDay)
+	}
+
+	if r.FromCompletion {
+		desc += " after completion"
+	}
+
+	return desc
+}
+
+func ordinalName(n int) string {
+	names := map[int]string{1: "first", 2: "second", 3: "third", 4: "fourth", 5: "fifth", -1: "last", -2: "second last"}
+	if name, ok := names[n]; ok {
+		return name
+	}
+
+	return strconv.Itoa(n)
+}
+
+func (r Rule) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+
+	return r.Interval
+}
+
+// NextDueDate calculates due date of the occurrence that follows a task due and completed on the given dates.
+// With a fixed schedule, occurrences that passed before completion are skipped.
+// A task without due date repeats from its completion.
+func (r Rule) NextDueDate(due, completed time.Time) time.Time {
+	if r.FromCompletion || due.IsZero() {
+		return r.Next(completed, completed)
+	}
+
+	after := due
+	if completed.After(due) {
+		after = completed
+	}
+
+	return r.Next(due, after)
+}
+
+// Next finds the first occurrence after the date `after`, in the schedule that starts on date `start`.
+// start also provides the weekday, day of month etc. that the rule does not mention.
+// Only dates are compared, so the result is always a midnight in the location of start.
+// Returns zero time if no occurrence is found in a reasonable range.
+func (r Rule) Next(start, after time.Time) time.Time {
+	start, after = dateOf(start, start.Location()), dateOf(after, start.Location())
+	interval := r.interval()
+
+	period := 0
+	if after.After(start) {
+		period = r.periodsBetween(start, after) / interval * interval
+	}
+
+	for tries := 0; tries < 1000; tries, period = tries+1, period+interval {
+		for _, date := range r.occurrencesIn(start, period) {
+			if date.After(after) && !date.Before(start) {
+				return date
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+// periodsBetween counts days, weeks, months or years from the period of start to the period of date
+func (r Rule) periodsBetween(start, date time.Time) int {
+	switch r.Freq {
+	case Weekly:
+		return daysBetween(weekStart(start), weekStart(date)) / 7
+	case Monthly:
+		return (date.Year()-start.Year())*12 + int(date.Month()-start.Month())
+	case Yearly:
+		return date.Year() - start.Year()
+	default:
+		return daysBetween(start, date)
+	}
+}
+
+// occurrencesIn lists dates of the rule in a period (counted from the period of start), in order
+func (r Rule) occurrencesIn(start time.Time, period int) []time.Time {
+	y, m, d := start.Date()
+	loc := start.Location()
+
+	var dates []time.Time
+	switch r.Freq {
+	case Daily:
+		dates = append(dates, time.Date(y, m, d+period, 0, 0, 0, 0, loc))
+
+	case Weekly:
+		monday := weekStart(start).AddDate(0, 0, 7*period)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []Day{{Weekday: start.Weekday()}}
+		}
+		for _, day := range days {
+			dates = append(dates, monday.AddDate(0, 0, (int(day.Weekday)+6)%7))
+		}
+
+	case Monthly:
+		first := time.Date(y, m+time.Month(period), 1, 0, 0, 0, 0, loc)
+		switch {
+		case len(r.ByDay) > 0:
+			for _, day := range r.ByDay {
+				dates = append(dates, weekdaysOfMonth(first, day)...)
+			}
+		case r.ByMonthDay != 0:
+			dates = append(dates, dayOfMonth(first, r.ByMonthDay))
+		default:
+			dates = append(dates, dayOfMonth(first, d))
+		}
+
+	case Yearly:
+		if r.ByMonthDay != 0 {
+			d = r.ByMonthDay
+		}
+		dates = append(dates, dayOfMonth(time.Date(y+period, m, 1, 0, 0, 0, 0, loc), d))
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+
+	return dates
+}
+
+// weekdaysOfMonth lists days of month matching a weekday, or only the one selected by its ordinal
+func weekdaysOfMonth(first time.Time, day Day) []time.Time {
+	var matching []time.Time
+	for date := first.AddDate(0, 0, (int(day.Weekday)-int(first.Weekday())+7)%7); date.Month() == first.Month(); date = date.AddDate(0, 0, 7) {
+		matching = append(matching, date)
+	}
+
+	switch {
+	case day.Ordinal > 0 && day.Ordinal <= len(matching):
+		return matching[day.Ordinal-1 : day.Ordinal]
+	case day.Ordinal < 0 && -day.Ordinal <= len(matching):
+		return matching[len(matching)+day.Ordinal : len(matching)+day.Ordinal+1]
+	case day.Ordinal == 0:
+		return matching
+	}
+
+	return nil
+}
+
+// dayOfMonth provides the nth day of month, counting from the end if n is negative.
+// Days beyond the length of month are moved to its last day, e,g, 31st of April is April 30.
+func dayOfMonth(first time.Time, n int) time.Time {
+	last := first.AddDate(0, 1, -1).Day()
+	if n < 0 {
+		n = last + n + 1
+	}
+	if n < 1 {
+		n = 1
+	}
+	if n > last {
+		n = last
+	}
+
+	return first.AddDate(0, 0, n-1)
+}
+
+func dateOf(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+func weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
+
+// daysBetween counts calendar days, unaffected by daylight saving changes
+func daysBetween(from, to time.Time) int {
+	y1, m1, d1 := from.Date()
+	y2, m2, d2 := to.Date()
+
+	return int(time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC).Sub(time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+}
+
+// WithDefaults fills the day a monthly or yearly rule repeats on from the date of first occurrence.
+// Otherwise each occurrence would start from the previous one, and a task due on 31st
+// would stay on 28th after February (or on Feb 28 after a leap year).
+// Rules repeating from completion are left as they are, as they follow the day of completion.
+func (r Rule) WithDefaults(start time.Time) Rule {
+	if (r.Freq == Monthly || r.Freq == Yearly) && len(r.ByDay) == 0 && r.ByMonthDay == 0 && !r.FromCompletion && !start.IsZero() {
+		r.ByMonthDay = start.Day()
+	}
+
+	return r
+}
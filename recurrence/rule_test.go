@@ -0,0 +1,187 @@
+package recurrence_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ajaxray/geek-life/recurrence"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want string // String of the parsed rule, empty if it is invalid
+	}{
+		{"weekly", "FREQ=WEEKLY"},
+		{" RRULE:freq=daily;interval=1 ", "FREQ=DAILY"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR", "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR"},
+		{"FREQ=YEARLY;BYMONTHDAY=29", "FREQ=YEARLY;BYMONTHDAY=29"},
+		{"FREQ=MONTHLY;X-FROM=COMPLETION", "FREQ=MONTHLY;X-FROM=COMPLETION"},
+		{"FREQ=DAILY;X-FROM=DUE", "FREQ=DAILY"},
+
+		{"", ""},
+		{"INTERVAL=2", ""},
+		{"FREQ=HOURLY", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=DAILY;INTERVAL=x", ""},
+		{"FREQ=DAILY;COUNT=3", ""},
+		{"FREQ=DAILY;BYDAY=MO", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"FREQ=WEEKLY;BYDAY=XX", ""},
+		{"FREQ=MONTHLY;BYDAY=6MO", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ""},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=1;BYDAY=MO", ""},
+		{"FREQ=DAILY;X-FROM=START", ""},
+		{"FREQ", ""},
+	}
+
+	for _, tt := range tests {
+		rule, err := recurrence.Parse(tt.text)
+		switch {
+		case tt.want == "" && !errors.Is(err, recurrence.ErrInvalidRule):
+			t.Errorf("Parse(%q) = %v, %v, want ErrInvalidRule", tt.text, rule, err)
+		case tt.want != "" && (err != nil || rule.String() != tt.want):
+			t.Errorf("Parse(%q) = %v, %v, want %s", tt.text, rule, err, tt.want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"FREQ=DAILY", "every day"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", "every 2 weeks on Mon, Thu"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "every month on last Fri"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", "every month on the last day"},
+		{"FREQ=MONTHLY;BYMONTHDAY=15;X-FROM=COMPLETION", "every month on day 15 after completion"},
+	}
+
+	for _, tt := range tests {
+		rule, err := recurrence.Parse(tt.text)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.text, err)
+		}
+		if got := rule.Describe(); got != tt.want {
+			t.Errorf("Describe(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNextDueDate(t *testing.T) {
+	tests := []struct {
+		name           string
+		rule           string
+		due, completed string
+		want           string
+	}{
+		{"daily", "FREQ=DAILY", "2021-03-10", "2021-03-10", "2021-03-11"},
+		{"daily skips passed occurrences", "FREQ=DAILY;INTERVAL=2", "2021-03-10", "2021-03-15", "2021-03-16"},
+		{"completed early keeps schedule", "FREQ=WEEKLY", "2021-03-10", "2021-03-01", "2021-03-17"},
+		{"weekly on weekdays", "FREQ=WEEKLY;BYDAY=MO,TH", "2021-03-08", "2021-03-08", "2021-03-11"},
+		{"weekly next week", "FREQ=WEEKLY;BYDAY=MO,TH", "2021-03-11", "2021-03-11", "2021-03-15"},
+		{"every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "2021-03-08", "2021-03-08", "2021-03-22"},
+		{"without due date", "FREQ=WEEKLY", "", "2021-03-10", "2021-03-17"},
+
+		{"monthly", "FREQ=MONTHLY", "2021-01-15", "2021-01-15", "2021-02-15"},
+		{"monthly on 31st clamped", "FREQ=MONTHLY;BYMONTHDAY=31", "2021-01-31", "2021-01-31", "2021-02-28"},
+		{"monthly on 31st after february", "FREQ=MONTHLY;BYMONTHDAY=31", "2021-02-28", "2021-02-28", "2021-03-31"},
+		{"monthly on 29th in leap year", "FREQ=MONTHLY;BYMONTHDAY=29", "2024-01-29", "2024-01-29", "2024-02-29"},
+		{"monthly on last day", "FREQ=MONTHLY;BYMONTHDAY=-1", "2021-01-31", "2021-01-31", "2021-02-28"},
+		{"monthly on last day in leap year", "FREQ=MONTHLY;BYMONTHDAY=-1", "2024-01-31", "2024-01-31", "2024-02-29"},
+		{"monthly last friday", "FREQ=MONTHLY;BYDAY=-1FR", "2021-03-26", "2021-03-26", "2021-04-30"},
+		{"monthly first monday", "FREQ=MONTHLY;BYDAY=1MO", "2021-03-01", "2021-03-01", "2021-04-05"},
+		{"every other month", "FREQ=MONTHLY;INTERVAL=2", "2021-01-15", "2021-01-20", "2021-03-15"},
+
+		{"yearly", "FREQ=YEARLY", "2021-03-10", "2021-03-10", "2022-03-10"},
+		{"yearly from leap day", "FREQ=YEARLY;BYMONTHDAY=29", "2024-02-29", "2024-02-29", "2025-02-28"},
+		{"yearly back to leap day", "FREQ=YEARLY;BYMONTHDAY=29", "2027-02-28", "2027-02-28", "2028-02-29"},
+
+		{"from completion", "FREQ=DAILY;INTERVAL=3;X-FROM=COMPLETION", "2021-03-10", "2021-03-12", "2021-03-15"},
+		{"monthly from early completion", "FREQ=MONTHLY;X-FROM=COMPLETION", "2021-01-15", "2021-01-10", "2021-02-10"},
+		{"monthly from late completion", "FREQ=MONTHLY;X-FROM=COMPLETION", "2021-01-15", "2021-02-03", "2021-03-03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := recurrence.Parse(tt.rule)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+
+			got := rule.NextDueDate(date(t, tt.due), date(t, tt.completed))
+			if want := date(t, tt.want); !got.Equal(want) {
+				t.Errorf("NextDueDate = %v, want %v", got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDefaults(t *testing.T) {
+	tests := []struct {
+		rule, start, want string
+	}{
+		{"FREQ=MONTHLY", "2021-01-31", "FREQ=MONTHLY;BYMONTHDAY=31"},
+		{"FREQ=YEARLY", "2024-02-29", "FREQ=YEARLY;BYMONTHDAY=29"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1", "2021-01-31", "FREQ=MONTHLY;BYMONTHDAY=1"},
+		{"FREQ=MONTHLY;BYDAY=1MO", "2021-01-31", "FREQ=MONTHLY;BYDAY=1MO"},
+		{"FREQ=MONTHLY;X-FROM=COMPLETION", "2021-01-15", "FREQ=MONTHLY;X-FROM=COMPLETION"},
+		{"FREQ=WEEKLY", "2021-01-15", "FREQ=WEEKLY"},
+		{"FREQ=MONTHLY", "", "FREQ=MONTHLY"},
+	}
+
+	for _, tt := range tests {
+		rule, err := recurrence.Parse(tt.rule)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.rule, err)
+		}
+		if got := rule.WithDefaults(date(t, tt.start)).String(); got != tt.want {
+			t.Errorf("WithDefaults(%s, %s) = %s, want %s", tt.rule, tt.start, got, tt.want)
+		}
+	}
+}
+
+// A monthly task due on 31st keeps its day across months, once the day is filled by WithDefaults
+func TestMonthEndSeries(t *testing.T) {
+	rule, _ := recurrence.Parse("FREQ=MONTHLY")
+	rule = rule.WithDefaults(date(t, "2021-01-31"))
+
+	due := date(t, "2021-01-31")
+	var got []string
+	for i := 0; i < 4; i++ {
+		due = rule.NextDueDate(due, due)
+		got = append(got, due.Format("01-02"))
+	}
+
+	if want := "[02-28 03-31 04-30 05-31]"; fmtList(got) != want {
+		t.Errorf("series = %v, want %v", got, want)
+	}
+}
+
+func fmtList(items []string) string {
+	list := "["
+	for i, item := range items {
+		if i > 0 {
+			list += " "
+		}
+		list += item
+	}
+	return list + "]"
+}
+
+func date(t *testing.T, text string) time.Time {
+	if text == "" {
+		return time.Time{}
+	}
+
+	d, err := time.ParseInLocation("2006-01-02", text, time.Local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"math"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// SetCompleted marks a task as completed at the given time, or as pending again if completed is false.
+// Run it in a transaction, as status and completion time are saved separately.
+func SetCompleted(repo TaskRepository, task *model.Task, completed bool, at int64) error {
+	if !completed {
+		at = 0
+	}
+
+	if err := repo.UpdateField(task, "Completed", completed); err != nil {
+		return err
+	}
+	if err := repo.UpdateField(task, "CompletedAt", at); err != nil {
+		return err
+	}
+	task.Completed, task.CompletedAt = completed, at
+
+	return nil
+}
+
+// CompleteDescendants marks pending subtasks of a completed task (in every depth) as completed along with it.
+// Returns the subtasks it completed.
+func CompleteDescendants(repo TaskRepository, task model.Task) ([]model.Task, error) {
+	descendants, err := Descendants(repo, task)
+	if err != nil {
+		return nil, err
+	}
+
+	var completed []model.Task
+	for _, sub := range descendants {
+		if sub.Completed {
+			continue
+		}
+		if err := SetCompleted(repo, &sub, true, task.CompletedAt); err != nil {
+			return completed, err
+		}
+		completed = append(completed, sub)
+	}
+
+	return completed, nil
+}
+
+// CreateOccurrence creates the next occurrence of a repeating task, due on next.
+// The rule moves to the new task, so that completing the old one again would not repeat it twice.
+func CreateOccurrence(repo TaskRepository, task *model.Task, next, now time.Time) (model.Task, error) {
+	occurrence, err := repo.Create(model.Project{ID: task.ProjectID}, task.Title, task.Details, "", RoundDueDate(next))
+	if err != nil {
+		return occurrence, err
+	}
+
+	occurrence.ParentID, occurrence.Priority = task.ParentID, task.Priority
+	occurrence.Tags, occurrence.Recurrence = task.Tags, task.Recurrence
+	occurrence.DueTime, occurrence.RemindBefore = task.DueTime, task.RemindBefore
+	occurrence.Estimate = task.Estimate
+	if task.StartDate != 0 && task.DueDate != 0 {
+		// Keep the same number of days between start and due date
+		lead := math.Round(time.Unix(task.DueDate, 0).Sub(time.Unix(task.StartDate, 0)).Hours() / 24)
+		occurrence.StartDate = RoundDueDate(next.AddDate(0, 0, -int(lead)))
+	}
+	if err := repo.Update(&occurrence); err != nil {
+		return occurrence, err
+	}
+	if err := ScheduleReminder(repo, &occurrence, now); err != nil {
+		return occurrence, err
+	}
+
+	if err := repo.UpdateField(task, "Recurrence", ""); err != nil {
+		return occurrence, err
+	}
+	task.Recurrence = ""
+
+	return occurrence, nil
+}
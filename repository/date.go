@@ -0,0 +1,18 @@
+package repository
+
+import "time"
+
+// RoundDueDate converts a time to the due date timestamp of its day, i,e, midnight in time's location.
+// Zero time means "no due date" and is stored as 0.
+func RoundDueDate(date time.Time) int64 {
+	if date.IsZero() {
+		return 0
+	}
+
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location()).Unix()
+}
+
+// EndOfDay provides the last second of the day of given time
+func EndOfDay(date time.Time) int64 {
+	return time.Date(date.Year(), date.Month(), date.Day(), 23, 59, 59, 0, date.Location()).Unix()
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"strconv"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// Blockers loads the tasks blocking a task.
+// Blockers which have been deleted or trashed do not block anymore, so they are skipped.
+func Blockers(repo TaskRepository, task model.Task) ([]model.Task, error) {
+	var blockers []model.Task
+	for _, id := range task.BlockedBy {
+		blocker, err := repo.GetByID(strconv.FormatInt(id, 10))
+		if err == ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if blocker.DeletedAt == 0 {
+			blockers = append(blockers, blocker)
+		}
+	}
+
+	return blockers, nil
+}
+
+// OpenBlockers finds the blockers of a task which are not completed yet
+func OpenBlockers(repo TaskRepository, task model.Task) ([]model.Task, error) {
+	blockers, err := Blockers(repo, task)
+	if err != nil {
+		return nil, err
+	}
+
+	var open []model.Task
+	for _, blocker := range blockers {
+		if !blocker.Completed {
+			open = append(open, blocker)
+		}
+	}
+
+	return open, nil
+}
+
+// AddBlocker makes task blocked by another task, possibly of another project.
+// Returns ErrDependencyCycle if the blocker is the task itself or already depends on it.
+func AddBlocker(repo TaskRepository, task *model.Task, blocker model.Task) error {
+	for _, id := range task.BlockedBy {
+		if id == blocker.ID {
+			return nil
+		}
+	}
+
+	if dependsOn, err := isBlockedBy(repo, blocker, task.ID); err != nil {
+		return err
+	} else if dependsOn {
+		return ErrDependencyCycle
+	}
+
+	blockers := append(append([]int64(nil), task.BlockedBy...), blocker.ID)
+	if err := repo.UpdateField(task, "BlockedBy", blockers); err != nil {
+		return err
+	}
+	task.BlockedBy = blockers
+
+	return nil
+}
+
+// RemoveBlocker unlinks a blocker from task
+func RemoveBlocker(repo TaskRepository, task *model.Task, blockerID int64) error {
+	var blockers []int64
+	for _, id := range task.BlockedBy {
+		if id != blockerID {
+			blockers = append(blockers, id)
+		}
+	}
+
+	if err := repo.UpdateField(task, "BlockedBy", blockers); err != nil {
+		return err
+	}
+	task.BlockedBy = blockers
+
+	return nil
+}
+
+// ReadyTasks finds pending tasks which are not blocked by any other pending task
+func ReadyTasks(repo TaskRepository) ([]model.Task, error) {
+	pending, err := repo.Find(TaskQuery{Status: StatusPending, OrderBy: "ProjectID", PriorityFirst: true})
+	if err != nil {
+		return nil, err
+	}
+
+	isPending := make(map[int64]bool, len(pending))
+	for _, task := range pending {
+		isPending[task.ID] = true
+	}
+
+	var ready []model.Task
+	for _, task := range pending {
+		blocked := false
+		for _, id := range task.BlockedBy {
+			blocked = blocked || isPending[id]
+		}
+
+		if !blocked {
+			ready = append(ready, task)
+		}
+	}
+
+	return ready, nil
+}
+
+// isBlockedBy tells if task is (directly or through other blockers) blocked by the task of given ID
+func isBlockedBy(repo TaskRepository, task model.Task, id int64) (bool, error) {
+	visited := make(map[int64]bool)
+
+	for queue := []model.Task{task}; len(queue) > 0; queue = queue[1:] {
+		current := queue[0]
+		if current.ID == id {
+			return true, nil
+		}
+		visited[current.ID] = true
+
+		for _, blockerID := range current.BlockedBy {
+			if visited[blockerID] {
+				continue
+			}
+
+			blocker, err := repo.GetByID(strconv.FormatInt(blockerID, 10))
+			if err == ErrNotFound {
+				continue
+			} else if err != nil {
+				return false, err
+			}
+			queue = append(queue, blocker)
+		}
+	}
+
+	return false, nil
+}
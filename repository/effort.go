@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// ParseEffort parses an amount of effort in minutes, like 45, 45m, 2h or 1h30m. Empty text means no effort.
+func ParseEffort(text string) (int, error) {
+	text = strings.ToLower(strings.ReplaceAll(text, " ", ""))
+	if text == "" {
+		return 0, nil
+	}
+	if minutes, err := strconv.Atoi(text); err == nil && minutes >= 0 {
+		return minutes, nil
+	}
+
+	d, err := time.ParseDuration(text)
+	if err != nil || d < 0 || d%time.Minute != 0 {
+		return 0, ErrInvalidEffort
+	}
+
+	return int(d.Minutes()), nil
+}
+
+// FormatEffort formats minutes of effort like ParseEffort accepts, e,g, 1h30m
+func FormatEffort(minutes int) string {
+	switch {
+	case minutes < 60:
+		return strconv.Itoa(minutes) + "m"
+	case minutes%60 == 0:
+		return strconv.Itoa(minutes/60) + "h"
+	}
+
+	return strconv.Itoa(minutes/60) + "h" + strconv.Itoa(minutes%60) + "m"
+}
+
+// TasksEffort sums up estimated and actual effort of tasks.
+// Actual effort of a task is the time entered as spent on it plus the time tracked on it, counting running timers up to now.
+func TasksEffort(repo TaskRepository, tasks []model.Task, now time.Time) (estimate, actual time.Duration, err error) {
+	tracked, err := TasksTrackedTime(repo, tasks, now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, task := range tasks {
+		estimate += time.Duration(task.Estimate) * time.Minute
+		actual += time.Duration(task.Spent) * time.Minute
+	}
+
+	return estimate, actual + tracked, nil
+}
+
+// DailyEstimates sums up estimated effort of open tasks by the date they are due on
+func DailyEstimates(tasks []model.Task) map[int64]int {
+	estimates := make(map[int64]int)
+	for _, task := range tasks {
+		if !task.Completed && task.DueDate != 0 && task.Estimate > 0 {
+			estimates[RoundDueDate(time.Unix(task.DueDate, 0))] += task.Estimate
+		}
+	}
+
+	return estimates
+}
@@ -0,0 +1,24 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrNotFound is returned when a requested record does not exist.
+	// Methods returning lists never return it, they return an empty list instead.
+	ErrNotFound = errors.New("record not found")
+
+	// ErrUnknownOrderField is returned when a TaskQuery is ordered by a field that can not be sorted
+	ErrUnknownOrderField = errors.New("unknown task field for ordering")
+
+	// ErrDependencyCycle is returned when a blocker would (even indirectly) be blocked by the task it blocks
+	ErrDependencyCycle = errors.New("task can not be blocked by itself or its dependents")
+
+	// ErrProjectCycle is returned when a project would be placed under itself or one of its sub-projects
+	ErrProjectCycle = errors.New("project can not be placed under itself or its sub-projects")
+
+	// ErrInvalidReminder is returned when a reminder offset can not be parsed
+	ErrInvalidReminder = errors.New("reminder should be like 15m, 2h, 1d or none")
+
+	// ErrInvalidEffort is returned when an effort estimate or time spent can not be parsed
+	ErrInvalidEffort = errors.New("effort should be like 45m, 2h or 1h30m")
+)
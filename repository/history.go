@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// untrackedFields are the Task fields which are not recorded in history
+var untrackedFields = map[string]bool{"ID": true, "CreatedAt": true, "UpdatedAt": true, "Position": true, "Pomodoros": true, "RemindAt": true}
+
+// DiffTask lists the changes made to the tracked fields of a task, in the order of Task fields
+func DiffTask(old, new model.Task, at int64) []model.TaskChange {
+	var changes []model.TaskChange
+
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i).Name
+		if untrackedFields[field] {
+			continue
+		}
+
+		before, after := FormatFieldValue(oldValue.Field(i).Interface()), FormatFieldValue(newValue.Field(i).Interface())
+		if before != after {
+			changes = append(changes, model.TaskChange{TaskID: old.ID, Field: field, OldValue: before, NewValue: after, ChangedAt: at})
+		}
+	}
+
+	return changes
+}
+
+// FieldChange makes the change of setting a field of task to value.
+// Returns false if the field is not tracked or value is the same as the current one.
+func FieldChange(task model.Task, field string, value interface{}, at int64) (model.TaskChange, bool) {
+	current := reflect.ValueOf(task).FieldByName(field)
+	if !current.IsValid() || untrackedFields[field] {
+		return model.TaskChange{}, false
+	}
+
+	before, after := FormatFieldValue(current.Interface()), FormatFieldValue(value)
+	if before == after {
+		return model.TaskChange{}, false
+	}
+
+	return model.TaskChange{TaskID: task.ID, Field: field, OldValue: before, NewValue: after, ChangedAt: at}, true
+}
+
+// FormatFieldValue makes the text of a field value to be stored in history.
+// Numbers are kept raw (not using String methods, e,g, of Priority) and slices are comma separated.
+func FormatFieldValue(value interface{}) string {
+	v := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.String:
+		return v.String()
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = FormatFieldValue(v.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
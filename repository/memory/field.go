@@ -0,0 +1,22 @@
+package memory
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// setField sets a named field of record, accepting only values of the same kind as storm's UpdateField does
+func setField(record interface{}, field string, value interface{}) error {
+	f := reflect.ValueOf(record).Elem().FieldByName(field)
+	if !f.IsValid() || !f.CanSet() {
+		return fmt.Errorf("unknown field %q", field)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != f.Kind() {
+		return fmt.Errorf("incompatible value for field %q", field)
+	}
+	f.Set(v.Convert(f.Type()))
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/ajaxray/geek-life/repository"
+	"github.com/ajaxray/geek-life/repository/memory"
+	"github.com/ajaxray/geek-life/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository) {
+		store := memory.NewStore()
+		return memory.NewProjectRepository(store), memory.NewTaskRepository(store)
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	repotest.RunTransactions(t, func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository, repository.Transactor) {
+		store := memory.NewStore()
+		return memory.NewProjectRepository(store), memory.NewTaskRepository(store), memory.NewTransactor(store)
+	})
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+type projectRepository struct {
+	store *Store
+}
+
+// NewProjectRepository will create an object that represent the repository.Project interface
+func NewProjectRepository(store *Store) repository.ProjectRepository {
+	return &projectRepository{store}
+}
+
+func (repo *projectRepository) GetAll() ([]model.Project, error) {
+	projects := repo.filter(func(p model.Project) bool { return p.DeletedAt == 0 })
+	repository.SortProjects(projects)
+
+	return projects, nil
+}
+
+// GetTrashed lists projects in trash
+func (repo *projectRepository) GetTrashed() ([]model.Project, error) {
+	projects := repo.filter(func(p model.Project) bool { return p.DeletedAt != 0 })
+	repository.SortProjects(projects)
+
+	return projects, nil
+}
+
+func (repo *projectRepository) GetByID(id int64) (model.Project, error) {
+	return repo.getOne(func(p model.Project) bool { return p.ID == id })
+}
+
+func (repo *projectRepository) GetByTitle(title string) (model.Project, error) {
+	return repo.getOne(func(p model.Project) bool { return p.Title == title && p.DeletedAt == 0 })
+}
+
+func (repo *projectRepository) GetByUUID(UUID string) (model.Project, error) {
+	return repo.getOne(func(p model.Project) bool { return UUID != "" && p.UUID == UUID })
+}
+
+func (repo *projectRepository) Create(title, UUID string) (model.Project, error) {
+	if UUID == "" {
+		UUID = repository.NewUUID()
+	}
+
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	repo.store.lastProjectID++
+	project := model.Project{
+		ID:        repo.store.lastProjectID,
+		Title:     title,
+		UUID:      UUID,
+		Position:  repo.store.lastProjectID,
+		UpdatedAt: time.Now().Unix(),
+	}
+	repo.store.projects[project.ID] = project
+
+	return project, nil
+}
+
+func (repo *projectRepository) Update(project *model.Project) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.projects[project.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	project.UpdatedAt = time.Now().Unix()
+	repo.store.projects[project.ID] = *project
+
+	return nil
+}
+
+func (repo *projectRepository) UpdateField(project *model.Project, field string, value interface{}) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	current, ok := repo.store.projects[project.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if err := setField(&current, field, value); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+	current.UpdatedAt, project.UpdatedAt = now, now
+	repo.store.projects[project.ID] = current
+
+	return nil
+}
+
+func (repo *projectRepository) Delete(project *model.Project) error {
+	repo.store.mu.Lock()
+	defer repo.store.mu.Unlock()
+
+	if _, ok := repo.store.projects[project.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(repo.store.projects, project.ID)
+
+	return nil
+}
+
+// filter lists projects satisfying the condition, ordered by ID
+func (repo *projectRepository) filter(match func(p model.Project) bool) []model.Project {
+	repo.store.mu.RLock()
+	defer repo.store.mu.RUnlock()
+
+	var projects []model.Project
+	for _, project := range repo.store.projects {
+		if match(project) {
+			projects = append(projects, project)
+		}
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].ID < projects[j].ID })
+
+	return projects
+}
+
+// getOne finds the project with lowest ID satisfying the condition
+func (repo *projectRepository) getOne(match func(p model.Project) bool) (model.Project, error) {
+	for _, project := range repo.filter(match) {
+		if match(project) {
+			return project, nil
+		}
+	}
+
+	return model.Project{}, repository.ErrNotFound
+}
@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// Store keeps projects and tasks in memory. Nothing is persisted, so it is meant for tests and demos.
+type Store struct {
+	mu            sync.RWMutex
+	projects      map[int64]model.Project
+	tasks         map[int64]model.Task
+	history       []model.TaskChange
+	timeEntries   []model.TimeEntry
+	syncStates    []model.SyncState
+	lastProjectID int64
+	lastTaskID    int64
+	lastChangeID  int64
+	lastEntryID   int64
+
+	lastSyncStateID int64
+}
+
+// NewStore creates an empty in-memory Store
+func NewStore() *Store {
+	return &Store{
+		projects: make(map[int64]model.Project),
+		tasks:    make(map[int64]model.Task),
+	}
+}
@@ -0,0 +1,332 @@
+package memory
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+type taskRepository struct {
+	store *Store
+}
+
+// NewTaskRepository will create an object that represent the repository.Task interface
+func NewTaskRepository(store *Store) repository.TaskRepository {
+	return &taskRepository{store}
+}
+
+func (t *taskRepository) GetAll() ([]model.Task, error) {
+	return t.Find(repository.TaskQuery{})
+}
+
+func (t *taskRepository) Find(query repository.TaskQuery) ([]model.Task, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	tasks := make([]model.Task, 0, len(t.store.tasks))
+	for _, task := range t.store.tasks {
+		tasks = append(tasks, task)
+	}
+
+	return query.Apply(tasks), nil
+}
+
+func (t *taskRepository) GetAllByProject(project model.Project) ([]model.Task, error) {
+	return t.Find(repository.TaskQuery{ProjectID: project.ID})
+}
+
+func (t *taskRepository) GetAllByDate(date time.Time) ([]model.Task, error) {
+	if date.IsZero() {
+		return t.Find(repository.TaskQuery{Unscheduled: true, OrderBy: "ProjectID"})
+	}
+
+	return t.Find(repository.TaskQuery{DueFrom: date, DueTo: date})
+}
+
+func (t *taskRepository) GetAllByDateRange(from, to time.Time) ([]model.Task, error) {
+	return t.Find(repository.TaskQuery{DueFrom: from, DueTo: to, OrderBy: "DueDate"})
+}
+
+func (t *taskRepository) GetAllCompletedByDate(date time.Time) ([]model.Task, error) {
+	completed, err := t.Find(repository.TaskQuery{Status: repository.StatusCompleted, OrderBy: "CompletedAt"})
+	if err != nil {
+		return nil, err
+	}
+
+	startOfDay, endOfDay := repository.RoundDueDate(date), repository.EndOfDay(date)
+	tasks := completed[:0]
+	for _, task := range completed {
+		if task.CompletedAt >= startOfDay && task.CompletedAt <= endOfDay {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks, nil
+}
+
+// GetAllTags lists tags in use with number of tasks labeled by each
+func (t *taskRepository) GetAllTags() ([]repository.TagCount, error) {
+	tasks, err := t.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.CountTags(tasks), nil
+}
+
+func (t *taskRepository) GetByID(ID string) (model.Task, error) {
+	id, err := strconv.ParseInt(ID, 10, 64)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	if task, ok := t.store.tasks[id]; ok {
+		return task, nil
+	}
+
+	return model.Task{}, repository.ErrNotFound
+}
+
+func (t *taskRepository) GetByUUID(UUID string) (model.Task, error) {
+	tasks, _ := t.Find(repository.TaskQuery{Trash: repository.TrashedOrNot})
+	for _, task := range tasks {
+		if UUID != "" && task.UUID == UUID {
+			return task, nil
+		}
+	}
+
+	return model.Task{}, repository.ErrNotFound
+}
+
+func (t *taskRepository) Create(project model.Project, title, details, UUID string, dueDate int64) (model.Task, error) {
+	if UUID == "" {
+		UUID = repository.NewUUID()
+	}
+
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	t.store.lastTaskID++
+	now := time.Now().Unix()
+	task := model.Task{
+		ID:        t.store.lastTaskID,
+		ProjectID: project.ID,
+		Title:     title,
+		Details:   details,
+		UUID:      UUID,
+		DueDate:   dueDate,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Position:  t.store.lastTaskID,
+	}
+	t.store.tasks[task.ID] = task
+
+	return task, nil
+}
+
+func (t *taskRepository) Update(task *model.Task) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	existing, ok := t.store.tasks[task.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now().Unix()
+	task.CreatedAt, task.UpdatedAt = existing.CreatedAt, now
+	t.store.tasks[task.ID] = cloneTask(*task)
+	t.record(repository.DiffTask(existing, *task, now)...)
+
+	return nil
+}
+
+func (t *taskRepository) UpdateField(task *model.Task, field string, value interface{}) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	current, ok := t.store.tasks[task.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	existing := current
+	if err := setField(&current, field, value); err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	current.UpdatedAt, task.UpdatedAt = now, now
+	t.store.tasks[task.ID] = cloneTask(current)
+	if change, ok := repository.FieldChange(existing, field, value, now); ok {
+		t.record(change)
+	}
+
+	return nil
+}
+
+func (t *taskRepository) Delete(task *model.Task) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	if _, ok := t.store.tasks[task.ID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(t.store.tasks, task.ID)
+
+	history := t.store.history[:0]
+	for _, change := range t.store.history {
+		if change.TaskID != task.ID {
+			history = append(history, change)
+		}
+	}
+	t.store.history = history
+
+	entries := t.store.timeEntries[:0]
+	for _, entry := range t.store.timeEntries {
+		if entry.TaskID != task.ID {
+			entries = append(entries, entry)
+		}
+	}
+	t.store.timeEntries = entries
+
+	return nil
+}
+
+// GetHistory lists changes of a task, oldest first
+func (t *taskRepository) GetHistory(task model.Task) ([]model.TaskChange, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	changes := []model.TaskChange{}
+	for _, change := range t.store.history {
+		if change.TaskID == task.ID {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// GetTimeEntries lists time entries of a task, oldest first
+func (t *taskRepository) GetTimeEntries(task model.Task) ([]model.TimeEntry, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	entries := []model.TimeEntry{}
+	for _, entry := range t.store.timeEntries {
+		if entry.TaskID == task.ID {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// GetRunningTimeEntry finds the time entry which is not ended yet
+func (t *taskRepository) GetRunningTimeEntry() (model.TimeEntry, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	for _, entry := range t.store.timeEntries {
+		if entry.End == 0 {
+			return entry, nil
+		}
+	}
+
+	return model.TimeEntry{}, repository.ErrNotFound
+}
+
+// SaveTimeEntry creates a time entry if it has no ID, or replaces the existing one
+func (t *taskRepository) SaveTimeEntry(entry *model.TimeEntry) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	if entry.ID == 0 {
+		t.store.lastEntryID++
+		entry.ID = t.store.lastEntryID
+		t.store.timeEntries = append(t.store.timeEntries, *entry)
+		return nil
+	}
+
+	for i := range t.store.timeEntries {
+		if t.store.timeEntries[i].ID == entry.ID {
+			t.store.timeEntries[i] = *entry
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+// GetSyncStates lists sync states of all tasks and projects, including the deleted ones
+func (t *taskRepository) GetSyncStates() ([]model.SyncState, error) {
+	t.store.mu.RLock()
+	defer t.store.mu.RUnlock()
+
+	return append([]model.SyncState{}, t.store.syncStates...), nil
+}
+
+// SaveSyncState creates the sync state of a task or project, or replaces the existing one
+func (t *taskRepository) SaveSyncState(state *model.SyncState) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	for i := range t.store.syncStates {
+		if sameSyncTarget(t.store.syncStates[i], *state) {
+			state.ID = t.store.syncStates[i].ID
+			t.store.syncStates[i] = *state
+			return nil
+		}
+	}
+
+	t.store.lastSyncStateID++
+	state.ID = t.store.lastSyncStateID
+	t.store.syncStates = append(t.store.syncStates, *state)
+
+	return nil
+}
+
+func (t *taskRepository) DeleteSyncState(state model.SyncState) error {
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	for i := range t.store.syncStates {
+		if sameSyncTarget(t.store.syncStates[i], state) {
+			t.store.syncStates = append(t.store.syncStates[:i:i], t.store.syncStates[i+1:]...)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+// sameSyncTarget tells if two sync states are of the same task or project
+func sameSyncTarget(a, b model.SyncState) bool {
+	return a.TaskID == b.TaskID && a.ProjectID == b.ProjectID
+}
+
+// record appends changes to history. Caller must hold the write lock.
+func (t *taskRepository) record(changes ...model.TaskChange) {
+	for _, change := range changes {
+		t.store.lastChangeID++
+		change.ID = t.store.lastChangeID
+		t.store.history = append(t.store.history, change)
+	}
+}
+
+// cloneTask copies a task to be stored, so that the caller can not change stored slices
+func cloneTask(task model.Task) model.Task {
+	task.Tags = append([]string(nil), task.Tags...)
+	task.BlockedBy = append([]int64(nil), task.BlockedBy...)
+
+	return task
+}
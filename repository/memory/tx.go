@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+type transactor struct {
+	store *Store
+}
+
+// NewTransactor will create an object that represent the repository.Transactor interface.
+// Changes are rolled back by restoring a copy of the store, so concurrent changes are not isolated.
+func NewTransactor(store *Store) repository.Transactor {
+	return &transactor{store}
+}
+
+func (t *transactor) Transaction(fn func(projects repository.ProjectRepository, tasks repository.TaskRepository) error) error {
+	saved := t.store.copy()
+	if err := fn(NewProjectRepository(t.store), NewTaskRepository(t.store)); err != nil {
+		t.store.restore(saved)
+		return err
+	}
+
+	return nil
+}
+
+// copy takes a snapshot of the store to restore it later. Stored tasks are never changed in place, so they are shared.
+func (s *Store) copy() *Store {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	saved := &Store{
+		projects:      make(map[int64]model.Project, len(s.projects)),
+		tasks:         make(map[int64]model.Task, len(s.tasks)),
+		history:       append([]model.TaskChange(nil), s.history...),
+		timeEntries:   append([]model.TimeEntry(nil), s.timeEntries...),
+		syncStates:    append([]model.SyncState(nil), s.syncStates...),
+		lastProjectID: s.lastProjectID,
+		lastTaskID:    s.lastTaskID,
+		lastChangeID:  s.lastChangeID,
+		lastEntryID:   s.lastEntryID,
+
+		lastSyncStateID: s.lastSyncStateID,
+	}
+	for id, project := range s.projects {
+		saved.projects[id] = project
+	}
+	for id, task := range s.tasks {
+		saved.tasks[id] = task
+	}
+
+	return saved
+}
+
+func (s *Store) restore(saved *Store) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.projects, s.tasks = saved.projects, saved.tasks
+	s.history, s.timeEntries = saved.history, saved.timeEntries
+	s.lastProjectID, s.lastTaskID = saved.lastProjectID, saved.lastTaskID
+	s.lastChangeID, s.lastEntryID = saved.lastChangeID, saved.lastEntryID
+	s.syncStates, s.lastSyncStateID = saved.syncStates, saved.lastSyncStateID
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// TaskCounts counts a list of tasks by their status
+type TaskCounts struct {
+	Total, Completed, Overdue int
+}
+
+// CountTasks counts tasks by status. Open tasks are overdue once their due time is passed,
+// or after their due date if they are due any time of the day.
+func CountTasks(tasks []model.Task, now time.Time) TaskCounts {
+	var counts TaskCounts
+	for _, task := range tasks {
+		counts.Total++
+		switch {
+		case task.Completed:
+			counts.Completed++
+		case IsOverdue(task, now):
+			counts.Overdue++
+		}
+	}
+
+	return counts
+}
+
+// CompletionPercent provides the percentage of completed tasks, 0 if there are no tasks
+func (counts TaskCounts) CompletionPercent() int {
+	if counts.Total == 0 {
+		return 0
+	}
+
+	return counts.Completed * 100 / counts.Total
+}
+
+// IsOverdue tells if an open task is past its due time, or its due date if it is due any time of the day
+func IsOverdue(task model.Task, now time.Time) bool {
+	if task.Completed || task.DueDate == 0 {
+		return false
+	}
+	if task.DueTime == "" {
+		return task.DueDate < RoundDueDate(now)
+	}
+
+	return DueAt(task).Before(now)
+}
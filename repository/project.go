@@ -1,15 +1,90 @@
-package repository
-
-import "github.com/ajaxray/geek-life/model"
-
-// ProjectRepository interface defines methods of project data accessor
-type ProjectRepository interface {
-	GetAll() ([]model.Project, error)
-	GetByID(id int64) (model.Project, error)
-	GetByTitle(title string) (model.Project, error)
-	GetByUUID(UUID string) (model.Project, error)
-	Create(title, UUID string) (model.Project, error)
-	Update(p *model.Project) error
-	UpdateField(p *model.Project, field string, value interface{}) error
-	Delete(p *model.Project) error
-}
+package repository
+
+import (
+	"sort"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// ProjectRepository interface defines methods of project data accessor.
+// Projects in trash are excluded from GetAll and GetByTitle. GetByID and GetByUUID find them too.
+// Create generates a UUID for the project unless one is given, see NewUUID.
+// Archived projects are not excluded anywhere, they are only hidden by the app.
+// GetAll and GetTrashed list projects in the order of SortProjects.
+// New projects and tasks are positioned after existing ones, by taking their ID as Position.
+// Projects form a hierarchy through ParentID, see ProjectDescendants and SetProjectParent.
+type ProjectRepository interface {
+	GetAll() ([]model.Project, error)
+	GetTrashed() ([]model.Project, error)
+	GetByID(id int64) (model.Project, error)
+	GetByTitle(title string) (model.Project, error)
+	GetByUUID(UUID string) (model.Project, error)
+	Create(title, UUID string) (model.Project, error)
+	Update(p *model.Project) error
+	UpdateField(p *model.Project, field string, value interface{}) error
+	Delete(p *model.Project) error
+}
+
+// SortProjects orders projects by their manual position, then by ID
+func SortProjects(projects []model.Project) {
+	sort.SliceStable(projects, func(i, j int) bool {
+		if projects[i].Position != projects[j].Position {
+			return projects[i].Position < projects[j].Position
+		}
+
+		return projects[i].ID < projects[j].ID
+	})
+}
+
+// ProjectDescendants finds sub-projects of a project in every depth, in the order of ProjectSubtree.
+// Projects in trash are not included.
+func ProjectDescendants(repo ProjectRepository, project model.Project) ([]model.Project, error) {
+	projects, err := repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return ProjectSubtree(projects, project.ID), nil
+}
+
+// ProjectSubtree picks the descendants of a project out of a list of projects.
+// Every project is followed by its own sub-projects, siblings are kept in the order of the list.
+func ProjectSubtree(projects []model.Project, id int64) []model.Project {
+	var subtree []model.Project
+	visited := map[int64]bool{id: true}
+
+	var addChildren func(parentID int64)
+	addChildren = func(parentID int64) {
+		for _, project := range projects {
+			// Guards against a broken hierarchy, where a project is an ancestor of itself
+			if project.ParentID == parentID && !visited[project.ID] {
+				visited[project.ID] = true
+				subtree = append(subtree, project)
+				addChildren(project.ID)
+			}
+		}
+	}
+	addChildren(id)
+
+	return subtree
+}
+
+// SetProjectParent places a project under another one, or on top level when parentID is 0.
+// Returns ErrProjectCycle if the parent is the project itself or one of its sub-projects.
+func SetProjectParent(repo ProjectRepository, project *model.Project, parentID int64) error {
+	if parentID == project.ID {
+		return ErrProjectCycle
+	}
+
+	descendants, err := ProjectDescendants(repo, *project)
+	if err != nil {
+		return err
+	}
+	for _, descendant := range descendants {
+		if descendant.ID == parentID {
+			return ErrProjectCycle
+		}
+	}
+
+	return repo.UpdateField(project, "ParentID", parentID)
+}
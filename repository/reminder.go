@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// DueTimeLayout is the layout of Task.DueTime
+const DueTimeLayout = "15:04"
+
+// DueAt provides the moment a task is due: its due time on the due date, or start of the due date if it has no time.
+// Zero time means the task has no due date.
+func DueAt(task model.Task) time.Time {
+	if task.DueDate == 0 {
+		return time.Time{}
+	}
+
+	due := time.Unix(task.DueDate, 0)
+	if clock, err := time.Parse(DueTimeLayout, task.DueTime); err == nil {
+		due = time.Date(due.Year(), due.Month(), due.Day(), clock.Hour(), clock.Minute(), 0, 0, due.Location())
+	}
+
+	return due
+}
+
+// ReminderTime calculates when to remind of a task, 0 if it should not be reminded.
+// Only tasks due at a time of day are reminded.
+func ReminderTime(task model.Task) int64 {
+	if task.DueDate == 0 || task.DueTime == "" || task.RemindBefore < 0 {
+		return 0
+	}
+
+	return DueAt(task).Add(-time.Duration(task.RemindBefore) * time.Minute).Unix()
+}
+
+// ScheduleReminder sets when the reminder of a task pops up, after its due date, time or reminder offset is changed.
+// Reminders falling in the past are not scheduled.
+func ScheduleReminder(repo TaskRepository, task *model.Task, now time.Time) error {
+	at := ReminderTime(*task)
+	if at <= now.Unix() {
+		at = 0
+	}
+
+	return SetReminder(repo, task, at)
+}
+
+// SetReminder changes when the reminder of a task pops up next, 0 to dismiss it
+func SetReminder(repo TaskRepository, task *model.Task, at int64) error {
+	if task.RemindAt == at {
+		return nil
+	}
+
+	if err := repo.UpdateField(task, "RemindAt", at); err != nil {
+		return err
+	}
+	task.RemindAt = at
+
+	return nil
+}
+
+// PendingReminders lists open tasks whose reminders are due by the given time, earliest first
+func PendingReminders(repo TaskRepository, now time.Time) ([]model.Task, error) {
+	return repo.Find(TaskQuery{RemindBy: now, Status: StatusPending, OrderBy: "RemindAt"})
+}
+
+// ParseDueTime validates a time of day like 9:30 or 15:04, formatting it as DueTimeLayout
+func ParseDueTime(text string) (string, error) {
+	clock, err := time.Parse(DueTimeLayout, strings.TrimSpace(text))
+	if err != nil {
+		return "", err
+	}
+
+	return clock.Format(DueTimeLayout), nil
+}
+
+// ParseReminder parses the offset of a reminder before due time, like 15m, 2h or 1d.
+// Empty text or 0 means reminding at due time, "none" means no reminder.
+func ParseReminder(text string) (int, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	switch text {
+	case "", "0":
+		return 0, nil
+	case "none", "off", "-":
+		return model.NoReminder, nil
+	}
+
+	units := map[byte]int{'m': 1, 'h': 60, 'd': 24 * 60}
+	unit, ok := units[text[len(text)-1]]
+	if !ok {
+		return 0, ErrInvalidReminder
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(text[:len(text)-1]))
+	if err != nil || n < 0 {
+		return 0, ErrInvalidReminder
+	}
+
+	return n * unit, nil
+}
+
+// FormatReminder formats the offset of a reminder like ParseReminder accepts, using the largest whole unit
+func FormatReminder(minutes int) string {
+	switch {
+	case minutes < 0:
+		return "none"
+	case minutes == 0:
+		return "0"
+	case minutes%(24*60) == 0:
+		return strconv.Itoa(minutes/(24*60)) + "d"
+	case minutes%60 == 0:
+		return strconv.Itoa(minutes/60) + "h"
+	}
+
+	return strconv.Itoa(minutes) + "m"
+}
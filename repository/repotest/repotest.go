@@ -0,0 +1,1612 @@
+// Package repotest provides a conformance suite for implementations of the repository interfaces.
+//
+// Every storage backend should pass it from its own tests:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository) {
+//			store := memory.NewStore()
+//			return memory.NewProjectRepository(store), memory.NewTaskRepository(store)
+//		})
+//	}
+//
+// Backends providing a repository.Transactor should pass RunTransactions too.
+package repotest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// Factory creates fresh and empty repositories for a single test
+type Factory func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository)
+
+// TransactorFactory creates fresh and empty repositories for a single test, along with a transactor on the same storage
+type TransactorFactory func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository, repository.Transactor)
+
+// day is the reference date used by the suite, far enough from today to not clash with anything
+var day = time.Date(2021, time.March, 10, 0, 0, 0, 0, time.Local)
+
+// Run executes the conformance suite against repositories created by factory
+func Run(t *testing.T, factory Factory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository)
+	}{
+		{"ProjectCRUD", testProjectCRUD},
+		{"ProjectNotFound", testProjectNotFound},
+		{"TaskCRUD", testTaskCRUD},
+		{"TaskUpdateClearsFields", testTaskUpdateClearsFields},
+		{"TaskUpdateField", testTaskUpdateField},
+		{"TaskUpdateIndexedField", testTaskUpdateIndexedField},
+		{"TaskNotFound", testTaskNotFound},
+		{"TaskDelete", testTaskDelete},
+		{"TasksByProject", testTasksByProject},
+		{"DateRounding", testDateRounding},
+		{"DateRange", testDateRange},
+		{"UnscheduledTasks", testUnscheduledTasks},
+		{"CompletedByDate", testCompletedByDate},
+		{"EmptyLists", testEmptyLists},
+		{"FindFilters", testFindFilters},
+		{"FindOrderAndPagination", testFindOrderAndPagination},
+		{"FindUnknownOrder", testFindUnknownOrder},
+		{"PriorityOrdering", testPriorityOrdering},
+		{"ManualPosition", testManualPosition},
+		{"Tags", testTags},
+		{"Subtasks", testSubtasks},
+		{"Blockers", testBlockers},
+		{"History", testHistory},
+		{"Trash", testTrash},
+		{"ProjectTree", testProjectTree},
+		{"TimeEntries", testTimeEntries},
+		{"Reminders", testReminders},
+		{"StartDates", testStartDates},
+		{"Effort", testEffort},
+		{"ProjectDescription", testProjectDescription},
+		{"Completion", testCompletion},
+		{"UUIDs", testUUIDs},
+		{"SyncStates", testSyncStates},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, tasks := factory(t)
+			tt.fn(t, projects, tasks)
+		})
+	}
+}
+
+// RunTransactions executes the transaction tests against repositories and transactor created by factory
+func RunTransactions(t *testing.T, factory TransactorFactory) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository, transactor repository.Transactor)
+	}{
+		{"Commit", testTransactionCommit},
+		{"Rollback", testTransactionRollback},
+		{"RollbackHelpers", testTransactionRollbackHelpers},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects, tasks, transactor := factory(t)
+			tt.fn(t, projects, tasks, transactor)
+		})
+	}
+}
+
+func testProjectCRUD(t *testing.T, projects repository.ProjectRepository, _ repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "uuid-home")
+	work := mustCreateProject(t, projects, "Work", "")
+
+	if home.ID == 0 || work.ID == 0 || home.ID == work.ID {
+		t.Fatalf("expected distinct non-zero IDs, got %d and %d", home.ID, work.ID)
+	}
+	if home.UpdatedAt == 0 {
+		t.Errorf("Create should set UpdatedAt, got %v", home)
+	}
+	created := home.UpdatedAt
+
+	all, err := projects.GetAll()
+	if err != nil {
+		t.Fatalf("GetAll: %v", err)
+	}
+	if got := projectTitles(all); got != "[Home Work]" {
+		t.Errorf("GetAll = %s, want [Home Work]", got)
+	}
+
+	if p, err := projects.GetByID(work.ID); err != nil || p.Title != "Work" {
+		t.Errorf("GetByID = %v, %v", p, err)
+	}
+	if p, err := projects.GetByTitle("Home"); err != nil || p.ID != home.ID {
+		t.Errorf("GetByTitle = %v, %v", p, err)
+	}
+	if p, err := projects.GetByUUID("uuid-home"); err != nil || p.ID != home.ID {
+		t.Errorf("GetByUUID = %v, %v", p, err)
+	}
+
+	home.Title = "House"
+	home.Working = true
+	if err := projects.Update(&home); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if p, _ := projects.GetByID(home.ID); p.Title != "House" || !p.Working || p.UpdatedAt < created || p.UpdatedAt != home.UpdatedAt {
+		t.Errorf("after Update got %v", p)
+	}
+
+	home.Working = false
+	if err := projects.Update(&home); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if p, _ := projects.GetByID(home.ID); p.Working {
+		t.Errorf("Update should store zero values, got %v", p)
+	}
+
+	if err := projects.UpdateField(&work, "Title", "Office"); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	if p, _ := projects.GetByID(work.ID); p.Title != "Office" || p.UpdatedAt < created || p.UpdatedAt != work.UpdatedAt {
+		t.Errorf("after UpdateField got %v", p)
+	}
+
+	if err := projects.UpdateField(&work, "Archived", true); err != nil {
+		t.Fatalf("UpdateField Archived: %v", err)
+	}
+	if p, _ := projects.GetByID(work.ID); !p.Archived {
+		t.Errorf("after archiving got %v", p)
+	}
+	if all, _ = projects.GetAll(); projectTitles(all) != "[House Office]" {
+		t.Errorf("GetAll should list archived projects too, got %s", projectTitles(all))
+	}
+
+	if err := projects.Delete(&work); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	all, _ = projects.GetAll()
+	if got := projectTitles(all); got != "[House]" {
+		t.Errorf("GetAll after Delete = %s, want [House]", got)
+	}
+}
+
+func testProjectNotFound(t *testing.T, projects repository.ProjectRepository, _ repository.TaskRepository) {
+	missing := model.Project{ID: 404, Title: "Missing"}
+
+	if _, err := projects.GetByID(missing.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID error = %v, want ErrNotFound", err)
+	}
+	if _, err := projects.GetByTitle(missing.Title); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByTitle error = %v, want ErrNotFound", err)
+	}
+	if _, err := projects.GetByUUID("no-such-uuid"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByUUID error = %v, want ErrNotFound", err)
+	}
+	if err := projects.Update(&missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update error = %v, want ErrNotFound", err)
+	}
+	if err := projects.Delete(&missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func testTaskCRUD(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	task, err := tasks.Create(project, "Buy milk", "2 litres", "uuid-milk", day.Unix())
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if task.ID == 0 || task.ProjectID != project.ID || task.Title != "Buy milk" || task.DueDate != day.Unix() {
+		t.Errorf("Create returned %v", task)
+	}
+
+	got, err := tasks.GetByID(fmt.Sprint(task.ID))
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !reflect.DeepEqual(got, task) {
+		t.Errorf("GetByID = %v, want %v", got, task)
+	}
+
+	if got, err := tasks.GetByUUID("uuid-milk"); err != nil || got.ID != task.ID {
+		t.Errorf("GetByUUID = %v, %v", got, err)
+	}
+
+	task.Title = "Buy oat milk"
+	task.Completed = true
+	task.CompletedAt = day.Add(time.Hour).Unix()
+	if err := tasks.Update(&task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := tasks.GetByID(fmt.Sprint(task.ID)); !reflect.DeepEqual(got, task) {
+		t.Errorf("after Update got %v, want %v", got, task)
+	}
+
+	all, err := tasks.GetAll()
+	if err != nil || len(all) != 1 {
+		t.Errorf("GetAll = %v, %v", all, err)
+	}
+}
+
+func testTaskUpdateClearsFields(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	task := mustCreateTask(t, tasks, project, "Buy milk", day)
+	task.Details = "some note"
+	task.Completed = true
+	if err := tasks.Update(&task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	task.Details = ""
+	task.Completed = false
+	task.DueDate = 0
+	if err := tasks.Update(&task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, _ := tasks.GetByID(fmt.Sprint(task.ID))
+	if got.Details != "" || got.Completed || got.DueDate != 0 {
+		t.Errorf("Update should store zero values, got %v", got)
+	}
+}
+
+func testTaskUpdateField(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	task := mustCreateTask(t, tasks, project, "Buy milk", day)
+
+	if err := tasks.UpdateField(&task, "Completed", true); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	if err := tasks.UpdateField(&task, "DueDate", int64(0)); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	if err := tasks.UpdateField(&task, "Recurrence", "FREQ=WEEKLY;BYDAY=MO"); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+
+	got, _ := tasks.GetByID(fmt.Sprint(task.ID))
+	if !got.Completed || got.DueDate != 0 || got.Title != "Buy milk" || got.Recurrence != "FREQ=WEEKLY;BYDAY=MO" {
+		t.Errorf("after UpdateField got %v", got)
+	}
+
+	if err := tasks.UpdateField(&task, "Completed", "yes"); err == nil {
+		t.Errorf("UpdateField with incompatible value should fail")
+	}
+	if err := tasks.UpdateField(&task, "NoSuchField", "x"); err == nil {
+		t.Errorf("UpdateField of unknown field should fail")
+	}
+}
+
+// Tasks are found by the new value of an indexed field only, while the struct given to UpdateField keeps the old one
+func testTaskUpdateIndexedField(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+	task := mustCreateTask(t, tasks, home, "Buy milk", day)
+	tomorrow := day.AddDate(0, 0, 1)
+
+	if err := tasks.UpdateField(&task, "DueDate", tomorrow.Unix()); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	for _, tt := range []struct {
+		date time.Time
+		want string
+	}{{day, "[]"}, {tomorrow, "[Buy milk]"}} {
+		found, _ := tasks.GetAllByDate(tt.date)
+		if got := taskTitles(found); got != tt.want {
+			t.Errorf("GetAllByDate(%s) after moving due date = %v, want %v", tt.date.Format("2006-01-02"), got, tt.want)
+		}
+		found, _ = tasks.Find(repository.TaskQuery{DueFrom: tt.date, DueTo: tt.date})
+		if got := taskTitles(found); got != tt.want {
+			t.Errorf("Find due on %s after moving due date = %v, want %v", tt.date.Format("2006-01-02"), got, tt.want)
+		}
+	}
+
+	if err := tasks.UpdateField(&task, "ProjectID", work.ID); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	for _, tt := range []struct {
+		project model.Project
+		want    string
+	}{{home, "[]"}, {work, "[Buy milk]"}} {
+		found, _ := tasks.GetAllByProject(tt.project)
+		if got := taskTitles(found); got != tt.want {
+			t.Errorf("GetAllByProject(%s) after moving task = %v, want %v", tt.project.Title, got, tt.want)
+		}
+		found, _ = tasks.Find(repository.TaskQuery{ProjectID: tt.project.ID})
+		if got := taskTitles(found); got != tt.want {
+			t.Errorf("Find in %s after moving task = %v, want %v", tt.project.Title, got, tt.want)
+		}
+	}
+}
+
+func testTaskNotFound(t *testing.T, _ repository.ProjectRepository, tasks repository.TaskRepository) {
+	missing := model.Task{ID: 404, Title: "Missing"}
+
+	if _, err := tasks.GetByID("404"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID error = %v, want ErrNotFound", err)
+	}
+	if _, err := tasks.GetByID("not-a-number"); err == nil {
+		t.Errorf("GetByID with invalid ID should fail")
+	}
+	if _, err := tasks.GetByUUID("no-such-uuid"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByUUID error = %v, want ErrNotFound", err)
+	}
+	if err := tasks.Update(&missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update error = %v, want ErrNotFound", err)
+	}
+	if err := tasks.UpdateField(&missing, "Title", "x"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdateField error = %v, want ErrNotFound", err)
+	}
+	if err := tasks.Delete(&missing); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func testTaskDelete(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	milk := mustCreateTask(t, tasks, project, "Buy milk", day)
+	bread := mustCreateTask(t, tasks, project, "Buy bread", time.Time{})
+
+	if err := tasks.Delete(&milk); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := tasks.GetByID(fmt.Sprint(milk.ID)); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByID of deleted task error = %v, want ErrNotFound", err)
+	}
+	if got, _ := tasks.GetAllByProject(project); taskTitles(got) != "[Buy bread]" {
+		t.Errorf("GetAllByProject after Delete = %s", taskTitles(got))
+	}
+	if got, _ := tasks.GetAllByDate(day); len(got) != 0 {
+		t.Errorf("deleted task still listed by date: %s", taskTitles(got))
+	}
+	if err := tasks.Delete(&milk); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("second Delete error = %v, want ErrNotFound", err)
+	}
+	if _, err := tasks.GetByID(fmt.Sprint(bread.ID)); err != nil {
+		t.Errorf("other task should survive Delete: %v", err)
+	}
+}
+
+func testTasksByProject(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+	mustCreateTask(t, tasks, home, "Buy milk", day)
+	mustCreateTask(t, tasks, work, "Write report", day)
+	mustCreateTask(t, tasks, home, "Buy bread", time.Time{})
+
+	got, err := tasks.GetAllByProject(home)
+	if err != nil {
+		t.Fatalf("GetAllByProject: %v", err)
+	}
+	if titles := taskTitles(got); titles != "[Buy milk Buy bread]" {
+		t.Errorf("GetAllByProject = %s, want [Buy milk Buy bread]", titles)
+	}
+}
+
+func testDateRounding(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	mustCreateTask(t, tasks, project, "Due on day", day)
+	mustCreateTask(t, tasks, project, "Due next day", day.AddDate(0, 0, 1))
+
+	for _, at := range []time.Time{day, day.Add(15*time.Hour + 30*time.Minute), day.Add(24*time.Hour - time.Second)} {
+		got, err := tasks.GetAllByDate(at)
+		if err != nil {
+			t.Fatalf("GetAllByDate(%v): %v", at, err)
+		}
+		if titles := taskTitles(got); titles != "[Due on day]" {
+			t.Errorf("GetAllByDate(%v) = %s, want [Due on day]", at, titles)
+		}
+	}
+
+	if due := repository.RoundDueDate(day.Add(13 * time.Hour)); due != day.Unix() {
+		t.Errorf("RoundDueDate = %d, want %d", due, day.Unix())
+	}
+}
+
+func testDateRange(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	mustCreateTask(t, tasks, project, "Before", day.AddDate(0, 0, -1))
+	mustCreateTask(t, tasks, project, "Last", day.AddDate(0, 0, 7))
+	mustCreateTask(t, tasks, project, "First", day)
+	mustCreateTask(t, tasks, project, "After", day.AddDate(0, 0, 8))
+	mustCreateTask(t, tasks, project, "Unscheduled", time.Time{})
+
+	got, err := tasks.GetAllByDateRange(day, day.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("GetAllByDateRange: %v", err)
+	}
+	if titles := taskTitles(got); titles != "[First Last]" {
+		t.Errorf("GetAllByDateRange = %s, want [First Last]", titles)
+	}
+}
+
+func testUnscheduledTasks(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+	mustCreateTask(t, tasks, work, "Work someday", time.Time{})
+	mustCreateTask(t, tasks, home, "Scheduled", day)
+	mustCreateTask(t, tasks, home, "Home someday", time.Time{})
+
+	got, err := tasks.GetAllByDate(time.Time{})
+	if err != nil {
+		t.Fatalf("GetAllByDate(zero): %v", err)
+	}
+	if titles := taskTitles(got); titles != "[Home someday Work someday]" {
+		t.Errorf("GetAllByDate(zero) = %s, want [Home someday Work someday]", titles)
+	}
+
+	found, err := tasks.Find(repository.TaskQuery{Unscheduled: true, DueFrom: day})
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if len(found) != 2 {
+		t.Errorf("Unscheduled should ignore due range, got %s", taskTitles(found))
+	}
+}
+
+func testCompletedByDate(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	complete := func(title string, at time.Time, completed bool) {
+		task := mustCreateTask(t, tasks, project, title, time.Time{})
+		task.Completed = completed
+		task.CompletedAt = at.Unix()
+		if err := tasks.Update(&task); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	complete("Previous night", day.Add(-time.Second), true)
+	complete("Midnight", day, true)
+	complete("Noon", day.Add(12*time.Hour), true)
+	complete("Last second", day.Add(24*time.Hour-time.Second), true)
+	complete("Next day", day.AddDate(0, 0, 1), true)
+	complete("Reopened", day.Add(13*time.Hour), false)
+
+	got, err := tasks.GetAllCompletedByDate(day.Add(9 * time.Hour))
+	if err != nil {
+		t.Fatalf("GetAllCompletedByDate: %v", err)
+	}
+	if titles := taskTitles(got); titles != "[Midnight Noon Last second]" {
+		t.Errorf("GetAllCompletedByDate = %s, want [Midnight Noon Last second]", titles)
+	}
+}
+
+func testEmptyLists(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	if got, err := projects.GetAll(); err != nil || len(got) != 0 {
+		t.Errorf("projects GetAll = %v, %v", got, err)
+	}
+
+	project := model.Project{ID: 404}
+	checks := map[string]func() ([]model.Task, error){
+		"GetAll":                func() ([]model.Task, error) { return tasks.GetAll() },
+		"GetAllByProject":       func() ([]model.Task, error) { return tasks.GetAllByProject(project) },
+		"GetAllByDate":          func() ([]model.Task, error) { return tasks.GetAllByDate(day) },
+		"GetAllByDate(zero)":    func() ([]model.Task, error) { return tasks.GetAllByDate(time.Time{}) },
+		"GetAllByDateRange":     func() ([]model.Task, error) { return tasks.GetAllByDateRange(day, day.AddDate(0, 0, 7)) },
+		"GetAllCompletedByDate": func() ([]model.Task, error) { return tasks.GetAllCompletedByDate(day) },
+		"Find":                  func() ([]model.Task, error) { return tasks.Find(repository.TaskQuery{Text: "nothing"}) },
+	}
+
+	for name, check := range checks {
+		if got, err := check(); err != nil || len(got) != 0 {
+			t.Errorf("%s on empty repository = %v, %v", name, got, err)
+		}
+	}
+}
+
+func testFindFilters(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+
+	milk := mustCreateTask(t, tasks, home, "Buy milk", day)
+	mustCreateTask(t, tasks, home, "Clean kitchen", time.Time{})
+	report := mustCreateTask(t, tasks, work, "Write report", day.AddDate(0, 0, 2))
+	mustCreateTask(t, tasks, work, "Review MILK budget", day.AddDate(0, 0, 10))
+
+	for _, done := range []*model.Task{&milk, &report} {
+		if err := tasks.UpdateField(done, "Completed", true); err != nil {
+			t.Fatalf("UpdateField: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		query repository.TaskQuery
+		want  string
+	}{
+		{"all", repository.TaskQuery{}, "[Buy milk Clean kitchen Write report Review MILK budget]"},
+		{"project", repository.TaskQuery{ProjectID: work.ID}, "[Write report Review MILK budget]"},
+		{"due from", repository.TaskQuery{DueFrom: day.AddDate(0, 0, 1)}, "[Write report Review MILK budget]"},
+		{"due to", repository.TaskQuery{DueTo: day.AddDate(0, 0, 2)}, "[Buy milk Write report]"},
+		{"due range", repository.TaskQuery{DueFrom: day.Add(5 * time.Hour), DueTo: day.AddDate(0, 0, 2)}, "[Buy milk Write report]"},
+		{"unscheduled", repository.TaskQuery{Unscheduled: true}, "[Clean kitchen]"},
+		{"pending", repository.TaskQuery{Status: repository.StatusPending}, "[Clean kitchen Review MILK budget]"},
+		{"completed", repository.TaskQuery{Status: repository.StatusCompleted}, "[Buy milk Write report]"},
+		{"text", repository.TaskQuery{Text: "Milk"}, "[Buy milk Review MILK budget]"},
+		{"combined", repository.TaskQuery{ProjectID: work.ID, Status: repository.StatusPending, Text: "budget"}, "[Review MILK budget]"},
+		{"no match", repository.TaskQuery{ProjectID: home.ID, Text: "report"}, "[]"},
+	}
+
+	for _, c := range cases {
+		got, err := tasks.Find(c.query)
+		if err != nil {
+			t.Errorf("Find %s: %v", c.name, err)
+			continue
+		}
+		if titles := taskTitles(got); titles != c.want {
+			t.Errorf("Find %s = %s, want %s", c.name, titles, c.want)
+		}
+	}
+}
+
+func testFindOrderAndPagination(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+
+	mustCreateTask(t, tasks, work, "charlie", day.AddDate(0, 0, 1))
+	mustCreateTask(t, tasks, home, "Alpha", day.AddDate(0, 0, 3))
+	mustCreateTask(t, tasks, work, "bravo", day)
+	mustCreateTask(t, tasks, home, "delta", day.AddDate(0, 0, 1))
+
+	cases := []struct {
+		name  string
+		query repository.TaskQuery
+		want  string
+	}{
+		{"default by ID", repository.TaskQuery{}, "[charlie Alpha bravo delta]"},
+		{"title ignores case", repository.TaskQuery{OrderBy: "Title"}, "[Alpha bravo charlie delta]"},
+		{"due date with ID ties", repository.TaskQuery{OrderBy: "DueDate"}, "[bravo charlie delta Alpha]"},
+		{"reversed", repository.TaskQuery{OrderBy: "DueDate", Reverse: true}, "[Alpha delta charlie bravo]"},
+		{"project", repository.TaskQuery{OrderBy: "ProjectID"}, "[Alpha delta charlie bravo]"},
+		{"limit", repository.TaskQuery{OrderBy: "Title", Limit: 2}, "[Alpha bravo]"},
+		{"offset", repository.TaskQuery{OrderBy: "Title", Offset: 3}, "[delta]"},
+		{"limit and offset", repository.TaskQuery{OrderBy: "Title", Limit: 2, Offset: 1}, "[bravo charlie]"},
+		{"offset beyond end", repository.TaskQuery{Offset: 10}, "[]"},
+	}
+
+	for _, c := range cases {
+		got, err := tasks.Find(c.query)
+		if err != nil {
+			t.Errorf("Find %s: %v", c.name, err)
+			continue
+		}
+		if titles := taskTitles(got); titles != c.want {
+			t.Errorf("Find %s = %s, want %s", c.name, titles, c.want)
+		}
+	}
+}
+
+func testFindUnknownOrder(t *testing.T, _ repository.ProjectRepository, tasks repository.TaskRepository) {
+	if _, err := tasks.Find(repository.TaskQuery{OrderBy: "Color"}); !errors.Is(err, repository.ErrUnknownOrderField) {
+		t.Errorf("Find error = %v, want ErrUnknownOrderField", err)
+	}
+}
+
+func testPriorityOrdering(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	priorities := map[string]model.Priority{
+		"Someday": model.PriorityNone,
+		"Soon":    model.PriorityMedium,
+		"Now":     model.PriorityUrgent,
+		"Later":   model.PriorityLow,
+		"Also":    model.PriorityMedium,
+	}
+
+	for _, title := range []string{"Someday", "Soon", "Now", "Later", "Also"} {
+		task := mustCreateTask(t, tasks, project, title, time.Time{})
+		if err := tasks.UpdateField(&task, "Priority", priorities[title]); err != nil {
+			t.Fatalf("UpdateField Priority: %v", err)
+		}
+	}
+
+	cases := []struct {
+		name  string
+		query repository.TaskQuery
+		want  string
+	}{
+		{"priority first, then ID", repository.TaskQuery{PriorityFirst: true}, "[Now Soon Also Later Someday]"},
+		{"priority first, then title", repository.TaskQuery{PriorityFirst: true, OrderBy: "Title"}, "[Now Also Soon Later Someday]"},
+		{"ordered by priority", repository.TaskQuery{OrderBy: "Priority"}, "[Someday Later Soon Also Now]"},
+	}
+
+	for _, c := range cases {
+		got, err := tasks.Find(c.query)
+		if err != nil {
+			t.Errorf("Find %s: %v", c.name, err)
+			continue
+		}
+		if titles := taskTitles(got); titles != c.want {
+			t.Errorf("Find %s = %s, want %s", c.name, titles, c.want)
+		}
+	}
+}
+
+func testManualPosition(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+	if home.Position == 0 || work.Position <= home.Position {
+		t.Fatalf("new projects should be positioned last, got %d and %d", home.Position, work.Position)
+	}
+
+	if err := projects.UpdateField(&home, "Position", work.Position); err == nil {
+		err = projects.UpdateField(&work, "Position", home.Position)
+	} else {
+		t.Fatalf("UpdateField Position: %v", err)
+	}
+	if all, _ := projects.GetAll(); projectTitles(all) != "[Work Home]" {
+		t.Errorf("GetAll after moving = %s, want [Work Home]", projectTitles(all))
+	}
+
+	first := mustCreateTask(t, tasks, home, "First", time.Time{})
+	second := mustCreateTask(t, tasks, home, "Second", time.Time{})
+	third := mustCreateTask(t, tasks, home, "Third", time.Time{})
+	if !(first.Position < second.Position && second.Position < third.Position) {
+		t.Fatalf("new tasks should be positioned last, got %d, %d, %d", first.Position, second.Position, third.Position)
+	}
+
+	// Move Third above First
+	if err := tasks.UpdateField(&third, "Position", first.Position-1); err != nil {
+		t.Fatalf("UpdateField Position: %v", err)
+	}
+	if err := tasks.UpdateField(&second, "Priority", model.PriorityHigh); err != nil {
+		t.Fatalf("UpdateField Priority: %v", err)
+	}
+
+	cases := []struct {
+		name  string
+		query repository.TaskQuery
+		want  string
+	}{
+		{"by position", repository.TaskQuery{ProjectID: home.ID, OrderBy: "Position"}, "[Third First Second]"},
+		{"priority first, then position", repository.TaskQuery{ProjectID: home.ID, OrderBy: "Position", PriorityFirst: true}, "[Second Third First]"},
+		{"position breaks ties", repository.TaskQuery{OrderBy: "ProjectID"}, "[Third First Second]"},
+	}
+	for _, c := range cases {
+		got, err := tasks.Find(c.query)
+		if err != nil {
+			t.Errorf("Find %s: %v", c.name, err)
+			continue
+		}
+		if titles := taskTitles(got); titles != c.want {
+			t.Errorf("Find %s = %s, want %s", c.name, titles, c.want)
+		}
+	}
+
+	if history, _ := tasks.GetHistory(third); len(history) != 0 {
+		t.Errorf("moving should not be recorded in history, got %v", history)
+	}
+}
+
+func testTags(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	if got, err := tasks.GetAllTags(); err != nil || len(got) != 0 {
+		t.Errorf("GetAllTags on empty repository = %v, %v", got, err)
+	}
+
+	home := mustCreateProject(t, projects, "Home", "")
+	office := mustCreateProject(t, projects, "Office", "")
+
+	milk := mustCreateTask(t, tasks, home, "Milk", time.Time{})
+	report := mustCreateTask(t, tasks, office, "Report", time.Time{})
+	plain := mustCreateTask(t, tasks, office, "Plain", time.Time{})
+
+	if err := tasks.UpdateField(&milk, "Tags", []string{"errand", "home"}); err != nil {
+		t.Fatalf("UpdateField Tags: %v", err)
+	}
+	report.Tags = []string{"errand"}
+	if err := tasks.Update(&report); err != nil {
+		t.Fatalf("Update with tags: %v", err)
+	}
+
+	got, err := tasks.GetByID(fmt.Sprint(milk.ID))
+	if err != nil || fmt.Sprint(got.Tags) != "[errand home]" {
+		t.Errorf("GetByID tags = %v, %v, want [errand home]", got.Tags, err)
+	}
+	if got, err := tasks.GetByID(fmt.Sprint(plain.ID)); err != nil || got.Tags != nil {
+		t.Errorf("untagged task has tags %#v, %v", got.Tags, err)
+	}
+
+	want := []repository.TagCount{{Tag: "errand", Tasks: 2}, {Tag: "home", Tasks: 1}}
+	if got, err := tasks.GetAllTags(); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetAllTags = %v, %v, want %v", got, err, want)
+	}
+
+	if found, err := tasks.Find(repository.TaskQuery{Tag: "errand"}); err != nil || taskTitles(found) != "[Milk Report]" {
+		t.Errorf("Find by tag = %s, %v, want [Milk Report]", taskTitles(found), err)
+	}
+	if found, err := tasks.Find(repository.TaskQuery{Tag: "errand", ProjectID: office.ID}); err != nil || taskTitles(found) != "[Report]" {
+		t.Errorf("Find by tag and project = %s, %v, want [Report]", taskTitles(found), err)
+	}
+
+	if err := tasks.UpdateField(&milk, "Tags", []string(nil)); err != nil {
+		t.Fatalf("UpdateField clearing Tags: %v", err)
+	}
+	if err := tasks.Delete(&report); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, err := tasks.GetAllTags(); err != nil || len(got) != 0 {
+		t.Errorf("GetAllTags after untagging and deleting = %v, %v", got, err)
+	}
+
+	missing := model.Task{ID: 404}
+	if err := tasks.UpdateField(&missing, "Tags", []string{"x"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("UpdateField Tags of missing task = %v, want ErrNotFound", err)
+	}
+}
+
+func testSubtasks(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	parent := mustCreateTask(t, tasks, project, "Move", time.Time{})
+	other := mustCreateTask(t, tasks, project, "Other", time.Time{})
+
+	for _, title := range []string{"Pack", "Clean"} {
+		child := mustCreateTask(t, tasks, project, title, time.Time{})
+		if err := tasks.UpdateField(&child, "ParentID", parent.ID); err != nil {
+			t.Fatalf("UpdateField ParentID: %v", err)
+		}
+	}
+
+	children, err := tasks.Find(repository.TaskQuery{ParentID: parent.ID})
+	if err != nil || taskTitles(children) != "[Pack Clean]" {
+		t.Fatalf("Find by parent = %s, %v, want [Pack Clean]", taskTitles(children), err)
+	}
+
+	grandChild := mustCreateTask(t, tasks, project, "Books", time.Time{})
+	grandChild.ParentID = children[0].ID
+	if err := tasks.Update(&grandChild); err != nil {
+		t.Fatalf("Update ParentID: %v", err)
+	}
+
+	descendants, err := repository.Descendants(tasks, parent)
+	if err != nil || taskTitles(descendants) != "[Pack Clean Books]" {
+		t.Errorf("Descendants = %s, %v, want [Pack Clean Books]", taskTitles(descendants), err)
+	}
+
+	// Books is listed after its ancestor, so it must be skipped as already deleted
+	deleted, err := repository.DeleteTrees(tasks, []model.Task{parent, grandChild})
+	if err != nil || len(deleted) != 4 {
+		t.Errorf("DeleteTrees deleted %v, %v, want 4 tasks", deleted, err)
+	}
+	if remaining, err := tasks.GetAll(); err != nil || taskTitles(remaining) != "[Other]" {
+		t.Errorf("tasks after DeleteTrees = %s, %v, want [Other]", taskTitles(remaining), err)
+	}
+	if descendants, err := repository.Descendants(tasks, other); err != nil || len(descendants) != 0 {
+		t.Errorf("Descendants of a task without subtasks = %v, %v", descendants, err)
+	}
+}
+
+func testBlockers(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+	buy := mustCreateTask(t, tasks, home, "Buy paint", time.Time{})
+	paint := mustCreateTask(t, tasks, home, "Paint wall", time.Time{})
+	report := mustCreateTask(t, tasks, work, "Report", time.Time{})
+
+	if err := repository.AddBlocker(tasks, &paint, buy); err != nil {
+		t.Fatalf("AddBlocker: %v", err)
+	}
+	if err := repository.AddBlocker(tasks, &report, paint); err != nil {
+		t.Fatalf("AddBlocker across projects: %v", err)
+	}
+	if err := repository.AddBlocker(tasks, &buy, report); err != repository.ErrDependencyCycle {
+		t.Errorf("AddBlocker making a cycle = %v, want ErrDependencyCycle", err)
+	}
+	if err := repository.AddBlocker(tasks, &buy, buy); err != repository.ErrDependencyCycle {
+		t.Errorf("AddBlocker of itself = %v, want ErrDependencyCycle", err)
+	}
+
+	stored, err := tasks.GetByID(strconv.FormatInt(paint.ID, 10))
+	if err != nil || len(stored.BlockedBy) != 1 || stored.BlockedBy[0] != buy.ID {
+		t.Fatalf("BlockedBy = %v, %v, want [%d]", stored.BlockedBy, err, buy.ID)
+	}
+
+	ready, err := repository.ReadyTasks(tasks)
+	if err != nil || taskTitles(ready) != "[Buy paint]" {
+		t.Errorf("ReadyTasks = %s, %v, want [Buy paint]", taskTitles(ready), err)
+	}
+
+	if err := tasks.UpdateField(&buy, "Completed", true); err != nil {
+		t.Fatalf("UpdateField Completed: %v", err)
+	}
+	if open, err := repository.OpenBlockers(tasks, paint); err != nil || len(open) != 0 {
+		t.Errorf("OpenBlockers after completing blocker = %s, %v", taskTitles(open), err)
+	}
+	if ready, err := repository.ReadyTasks(tasks); err != nil || taskTitles(ready) != "[Paint wall]" {
+		t.Errorf("ReadyTasks = %s, %v, want [Paint wall]", taskTitles(ready), err)
+	}
+
+	// A deleted blocker does not block anymore
+	if err := tasks.Delete(&paint); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if open, err := repository.OpenBlockers(tasks, report); err != nil || len(open) != 0 {
+		t.Errorf("OpenBlockers with deleted blocker = %s, %v", taskTitles(open), err)
+	}
+
+	if err := repository.RemoveBlocker(tasks, &report, paint.ID); err != nil {
+		t.Fatalf("RemoveBlocker: %v", err)
+	}
+	if stored, err := tasks.GetByID(strconv.FormatInt(report.ID, 10)); err != nil || len(stored.BlockedBy) != 0 {
+		t.Errorf("BlockedBy after RemoveBlocker = %v, %v", stored.BlockedBy, err)
+	}
+}
+
+func testHistory(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	task := mustCreateTask(t, tasks, project, "Buy milk", day)
+	if task.CreatedAt == 0 || task.UpdatedAt != task.CreatedAt {
+		t.Errorf("Create timestamps = %d, %d", task.CreatedAt, task.UpdatedAt)
+	}
+	created := task.CreatedAt
+
+	if err := tasks.UpdateField(&task, "DueDate", int64(0)); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	if err := tasks.UpdateField(&task, "Tags", []string{"home", "shop"}); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	// Setting the same value is not a change
+	if err := tasks.UpdateField(&task, "Title", "Buy milk"); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+
+	task.DueDate, task.Tags = 0, []string{"home", "shop"}
+	task.Title = "Buy oat milk"
+	task.CreatedAt = 0
+	if err := tasks.Update(&task); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	got, _ := tasks.GetByID(fmt.Sprint(task.ID))
+	if got.CreatedAt != created || got.UpdatedAt < created || task.CreatedAt != created {
+		t.Errorf("timestamps after Update = %d, %d, want created at %d", got.CreatedAt, got.UpdatedAt, created)
+	}
+
+	history, err := tasks.GetHistory(task)
+	if err != nil {
+		t.Fatalf("GetHistory: %v", err)
+	}
+	var changes []string
+	for _, change := range history {
+		if change.TaskID != task.ID || change.ChangedAt < created {
+			t.Errorf("unexpected change %+v", change)
+		}
+		changes = append(changes, change.Field+": "+change.OldValue+" > "+change.NewValue)
+	}
+	want := []string{"DueDate: " + fmt.Sprint(day.Unix()) + " > 0", "Tags:  > home,shop", "Title: Buy milk > Buy oat milk"}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("history = %q, want %q", changes, want)
+	}
+
+	if err := tasks.Delete(&task); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if history, err := tasks.GetHistory(task); err != nil || len(history) != 0 {
+		t.Errorf("history of deleted task = %v, %v", history, err)
+	}
+}
+
+func testTrash(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	home := mustCreateProject(t, projects, "Home", "")
+	work := mustCreateProject(t, projects, "Work", "")
+	move := mustCreateTask(t, tasks, home, "Move #home", day)
+	pack := mustCreateTask(t, tasks, home, "Pack", day)
+	mustCreateTask(t, tasks, home, "Water plants", time.Time{})
+	report := mustCreateTask(t, tasks, work, "Report", time.Time{})
+	if err := tasks.UpdateField(&pack, "ParentID", move.ID); err != nil {
+		t.Fatalf("UpdateField ParentID: %v", err)
+	}
+	if err := tasks.UpdateField(&move, "Tags", []string{"home"}); err != nil {
+		t.Fatalf("UpdateField Tags: %v", err)
+	}
+
+	trashed, err := repository.TrashTree(tasks, move)
+	if err != nil || len(trashed) != 2 {
+		t.Fatalf("TrashTree = %v, %v, want 2 tasks", trashed, err)
+	}
+	if all, _ := tasks.GetAll(); taskTitles(all) != "[Water plants Report]" {
+		t.Errorf("GetAll after trashing = %s", taskTitles(all))
+	}
+	if due, _ := tasks.GetAllByDate(day); len(due) != 0 {
+		t.Errorf("GetAllByDate lists trashed tasks %s", taskTitles(due))
+	}
+	if tags, _ := tasks.GetAllTags(); len(tags) != 0 {
+		t.Errorf("GetAllTags counts trashed tasks %v", tags)
+	}
+	if got, err := tasks.GetByID(fmt.Sprint(move.ID)); err != nil || got.DeletedAt == 0 {
+		t.Errorf("GetByID of trashed task = %v, %v", got, err)
+	}
+	inTrash, err := tasks.Find(repository.TaskQuery{Trash: repository.TrashedOnly})
+	if err != nil || taskTitles(inTrash) != "[Move #home Pack]" {
+		t.Errorf("Find trashed = %s, %v", taskTitles(inTrash), err)
+	}
+
+	if err := repository.RestoreTree(tasks, inTrash[0]); err != nil {
+		t.Fatalf("RestoreTree: %v", err)
+	}
+	if all, _ := tasks.GetAllByProject(home); len(all) != 3 {
+		t.Errorf("tasks after restore = %s", taskTitles(all))
+	}
+
+	if err := repository.TrashProject(projects, tasks, &work); err != nil {
+		t.Fatalf("TrashProject: %v", err)
+	}
+	if all, _ := projects.GetAll(); projectTitles(all) != "[Home]" {
+		t.Errorf("projects after trashing = %s", projectTitles(all))
+	}
+	if _, err := projects.GetByTitle("Work"); err != repository.ErrNotFound {
+		t.Errorf("GetByTitle of trashed project = %v, want ErrNotFound", err)
+	}
+	if trashed, err := projects.GetTrashed(); err != nil || projectTitles(trashed) != "[Work]" {
+		t.Errorf("GetTrashed = %s, %v", projectTitles(trashed), err)
+	}
+	if err := repository.RestoreProject(projects, tasks, &work); err != nil {
+		t.Fatalf("RestoreProject: %v", err)
+	}
+	if all, _ := tasks.GetAllByProject(work); taskTitles(all) != "[Report]" {
+		t.Errorf("tasks of restored project = %s", taskTitles(all))
+	}
+	if trashed, _ := projects.GetTrashed(); len(trashed) != 0 {
+		t.Errorf("GetTrashed after restore = %s", projectTitles(trashed))
+	}
+
+	// Purging keeps items trashed after the given time
+	if err := repository.TrashProject(projects, tasks, &work); err != nil {
+		t.Fatalf("TrashProject: %v", err)
+	}
+	if _, err := repository.TrashTree(tasks, move); err != nil {
+		t.Fatalf("TrashTree: %v", err)
+	}
+	if purged, err := repository.PurgeTrash(projects, tasks, time.Now().Add(-time.Hour)); err != nil || purged != 0 {
+		t.Errorf("PurgeTrash of recent items = %d, %v", purged, err)
+	}
+	if purged, err := repository.PurgeTrash(projects, tasks, time.Now().Add(time.Hour)); err != nil || purged != 4 {
+		t.Errorf("PurgeTrash = %d, %v, want 4", purged, err)
+	}
+	if _, err := tasks.GetByID(fmt.Sprint(report.ID)); err != repository.ErrNotFound {
+		t.Errorf("task of purged project still exists: %v", err)
+	}
+	if inTrash, _ := tasks.Find(repository.TaskQuery{Trash: repository.TrashedOrNot}); taskTitles(inTrash) != "[Water plants]" {
+		t.Errorf("tasks after purge = %s", taskTitles(inTrash))
+	}
+}
+
+func testProjectTree(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	work := mustCreateProject(t, projects, "Work", "")
+	backend := mustCreateProject(t, projects, "Backend", "")
+	migration := mustCreateProject(t, projects, "Migration", "")
+	mustCreateProject(t, projects, "Home", "")
+	mustCreateTask(t, tasks, backend, "Review", time.Time{})
+	mustCreateTask(t, tasks, migration, "Convert", time.Time{})
+
+	if err := repository.SetProjectParent(projects, &backend, work.ID); err != nil {
+		t.Fatalf("SetProjectParent: %v", err)
+	}
+	if err := repository.SetProjectParent(projects, &migration, backend.ID); err != nil {
+		t.Fatalf("SetProjectParent: %v", err)
+	}
+	if got, err := projects.GetByID(migration.ID); err != nil || got.ParentID != backend.ID {
+		t.Errorf("GetByID = %+v, %v, want ParentID %d", got, err, backend.ID)
+	}
+	if descendants, err := repository.ProjectDescendants(projects, work); err != nil || projectTitles(descendants) != "[Backend Migration]" {
+		t.Errorf("ProjectDescendants = %s, %v", projectTitles(descendants), err)
+	}
+	if err := repository.SetProjectParent(projects, &work, migration.ID); err != repository.ErrProjectCycle {
+		t.Errorf("SetProjectParent under a descendant = %v, want ErrProjectCycle", err)
+	}
+	if err := repository.SetProjectParent(projects, &work, work.ID); err != repository.ErrProjectCycle {
+		t.Errorf("SetProjectParent under itself = %v, want ErrProjectCycle", err)
+	}
+
+	// Sub-projects go to trash and come back along with their parent
+	if err := repository.TrashProject(projects, tasks, &work); err != nil {
+		t.Fatalf("TrashProject: %v", err)
+	}
+	if all, _ := projects.GetAll(); projectTitles(all) != "[Home]" {
+		t.Errorf("projects after trashing = %s", projectTitles(all))
+	}
+	if all, _ := tasks.GetAll(); len(all) != 0 {
+		t.Errorf("tasks of trashed sub-projects = %s", taskTitles(all))
+	}
+	if err := repository.RestoreProject(projects, tasks, &work); err != nil {
+		t.Fatalf("RestoreProject: %v", err)
+	}
+	if all, _ := projects.GetAll(); projectTitles(all) != "[Work Backend Migration Home]" {
+		t.Errorf("projects after restore = %s", projectTitles(all))
+	}
+	if all, _ := tasks.GetAll(); taskTitles(all) != "[Review Convert]" {
+		t.Errorf("tasks after restore = %s", taskTitles(all))
+	}
+
+	// Deleting a project keeps sub-projects restored on their own, moving them to top level
+	if err := repository.TrashProject(projects, tasks, &work); err != nil {
+		t.Fatalf("TrashProject: %v", err)
+	}
+	migration, _ = projects.GetByID(migration.ID)
+	if err := repository.RestoreProject(projects, tasks, &migration); err != nil {
+		t.Fatalf("RestoreProject: %v", err)
+	}
+	if _, err := repository.DeleteProject(projects, tasks, work); err != nil {
+		t.Fatalf("DeleteProject: %v", err)
+	}
+	if _, err := projects.GetByID(backend.ID); err != repository.ErrNotFound {
+		t.Errorf("sub-project trashed together still exists: %v", err)
+	}
+	if got, err := projects.GetByID(migration.ID); err != nil || got.ParentID != 0 || got.DeletedAt != 0 {
+		t.Errorf("restored sub-project = %+v, %v, want it on top level", got, err)
+	}
+	if all, _ := tasks.Find(repository.TaskQuery{Trash: repository.TrashedOrNot}); taskTitles(all) != "[Convert]" || all[0].DeletedAt != 0 {
+		t.Errorf("tasks after delete = %v", all)
+	}
+}
+
+func testTimeEntries(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Work", "")
+	write := mustCreateTask(t, tasks, project, "Write", time.Time{})
+	review := mustCreateTask(t, tasks, project, "Review", time.Time{})
+	start := time.Date(2021, time.March, 10, 9, 0, 0, 0, time.Local)
+
+	if _, err := tasks.GetRunningTimeEntry(); err != repository.ErrNotFound {
+		t.Errorf("GetRunningTimeEntry without timer = %v, want ErrNotFound", err)
+	}
+	if _, err := repository.StopTimer(tasks, start); err != repository.ErrNotFound {
+		t.Errorf("StopTimer without timer = %v, want ErrNotFound", err)
+	}
+
+	entry, err := repository.StartTimer(tasks, write, start)
+	if err != nil || entry.ID == 0 {
+		t.Fatalf("StartTimer = %+v, %v", entry, err)
+	}
+	if again, err := repository.StartTimer(tasks, write, start.Add(time.Minute)); err != nil || again.ID != entry.ID {
+		t.Errorf("StartTimer of running task = %+v, %v, want the running entry", again, err)
+	}
+	if running, err := tasks.GetRunningTimeEntry(); err != nil || running.TaskID != write.ID {
+		t.Errorf("GetRunningTimeEntry = %+v, %v", running, err)
+	}
+
+	// Starting another task stops the running timer
+	if _, err := repository.StartTimer(tasks, review, start.Add(30*time.Minute)); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	stopped, err := repository.StopTimer(tasks, start.Add(45*time.Minute))
+	if err != nil || stopped.TaskID != review.ID {
+		t.Fatalf("StopTimer = %+v, %v", stopped, err)
+	}
+	if _, err := tasks.GetRunningTimeEntry(); err != repository.ErrNotFound {
+		t.Errorf("GetRunningTimeEntry after stop = %v, want ErrNotFound", err)
+	}
+
+	entries, err := tasks.GetTimeEntries(write)
+	if err != nil || len(entries) != 1 || entries[0].End != start.Add(30*time.Minute).Unix() {
+		t.Errorf("GetTimeEntries = %+v, %v", entries, err)
+	}
+	if total, err := repository.TasksTrackedTime(tasks, []model.Task{write, review}, start); err != nil || total != 45*time.Minute {
+		t.Errorf("TasksTrackedTime = %v, %v, want 45m", total, err)
+	}
+
+	// A running timer counts up to now
+	if _, err := repository.StartTimer(tasks, write, start.Add(time.Hour)); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	entries, _ = tasks.GetTimeEntries(write)
+	if total := repository.TrackedTime(entries, start.Add(70*time.Minute)); total != 40*time.Minute {
+		t.Errorf("TrackedTime with running timer = %v, want 40m", total)
+	}
+
+	if err := tasks.Delete(&write); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if entries, err := tasks.GetTimeEntries(write); err != nil || len(entries) != 0 {
+		t.Errorf("time entries of deleted task = %+v, %v", entries, err)
+	}
+	if _, err := tasks.GetRunningTimeEntry(); err != repository.ErrNotFound {
+		t.Errorf("timer of deleted task is still running: %v", err)
+	}
+}
+
+func testReminders(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Work", "")
+	call := mustCreateTask(t, tasks, project, "Call", day)
+	allDay := mustCreateTask(t, tasks, project, "All day", day)
+	now := day.Add(8 * time.Hour)
+
+	call.DueTime, call.RemindBefore = "09:30", 15
+	if err := tasks.Update(&call); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repository.ScheduleReminder(tasks, &call, now); err != nil {
+		t.Fatalf("ScheduleReminder: %v", err)
+	}
+	if want := day.Add(9*time.Hour + 15*time.Minute).Unix(); call.RemindAt != want {
+		t.Errorf("RemindAt = %d, want %d", call.RemindAt, want)
+	}
+	if err := repository.ScheduleReminder(tasks, &allDay, now); err != nil || allDay.RemindAt != 0 {
+		t.Errorf("reminder of task without due time = %d, %v, want none", allDay.RemindAt, err)
+	}
+
+	got, err := tasks.GetByID(strconv.FormatInt(call.ID, 10))
+	if err != nil || got.DueTime != "09:30" || got.RemindBefore != 15 || got.RemindAt != call.RemindAt {
+		t.Errorf("GetByID = %+v, %v", got, err)
+	}
+
+	if pending, err := repository.PendingReminders(tasks, now); err != nil || len(pending) != 0 {
+		t.Errorf("PendingReminders before time = %q, %v, want none", taskTitles(pending), err)
+	}
+	pending, err := repository.PendingReminders(tasks, day.Add(10*time.Hour))
+	if err != nil || taskTitles(pending) != "[Call]" {
+		t.Errorf("PendingReminders = %q, %v, want Call", taskTitles(pending), err)
+	}
+
+	// Snoozing moves the reminder
+	if err := repository.SetReminder(tasks, &call, day.Add(9*time.Hour+45*time.Minute).Unix()); err != nil {
+		t.Fatalf("SetReminder: %v", err)
+	}
+	if pending, err := repository.PendingReminders(tasks, day.Add(9*time.Hour+30*time.Minute)); err != nil || len(pending) != 0 {
+		t.Errorf("PendingReminders before snoozed time = %q, %v, want none", taskTitles(pending), err)
+	}
+	if pending, err := repository.PendingReminders(tasks, day.Add(10*time.Hour)); err != nil || len(pending) != 1 {
+		t.Errorf("PendingReminders after snoozed time = %q, %v, want Call", taskTitles(pending), err)
+	}
+
+	// Dismissed and completed tasks are not reminded
+	if err := repository.SetReminder(tasks, &call, 0); err != nil {
+		t.Fatalf("SetReminder: %v", err)
+	}
+	if pending, err := repository.PendingReminders(tasks, day.Add(10*time.Hour)); err != nil || len(pending) != 0 {
+		t.Errorf("PendingReminders after dismiss = %q, %v, want none", taskTitles(pending), err)
+	}
+	if err := repository.SetReminder(tasks, &call, day.Add(11*time.Hour).Unix()); err != nil {
+		t.Fatalf("SetReminder: %v", err)
+	}
+	if err := tasks.UpdateField(&call, "Completed", true); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	if pending, err := repository.PendingReminders(tasks, day.Add(12*time.Hour)); err != nil || len(pending) != 0 {
+		t.Errorf("PendingReminders of completed task = %q, %v, want none", taskTitles(pending), err)
+	}
+
+	// Reminders in the past are not scheduled
+	if err := repository.ScheduleReminder(tasks, &call, day.Add(12*time.Hour)); err != nil || call.RemindAt != 0 {
+		t.Errorf("past reminder = %d, %v, want none", call.RemindAt, err)
+	}
+}
+
+func testStartDates(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Work", "")
+	mustCreateTask(t, tasks, project, "Now", day)
+	started := mustCreateTask(t, tasks, project, "Started", day)
+	deferred := mustCreateTask(t, tasks, project, "Deferred", day)
+
+	started.StartDate = repository.RoundDueDate(day)
+	deferred.StartDate = repository.RoundDueDate(day.AddDate(0, 0, 2))
+	for _, task := range []*model.Task{&started, &deferred} {
+		if err := tasks.Update(task); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+	}
+
+	got, err := tasks.Find(repository.TaskQuery{ProjectID: project.ID, AvailableBy: day.Add(time.Hour)})
+	if err != nil || taskTitles(got) != "[Now Started]" {
+		t.Errorf("Find available = %q, %v, want [Now Started]", taskTitles(got), err)
+	}
+
+	got, err = tasks.Find(repository.TaskQuery{DueFrom: day, DueTo: day, AvailableBy: day.AddDate(0, 0, 2)})
+	if err != nil || taskTitles(got) != "[Now Started Deferred]" {
+		t.Errorf("Find available on start date = %q, %v, want all", taskTitles(got), err)
+	}
+
+	if got, err := tasks.GetByID(strconv.FormatInt(deferred.ID, 10)); err != nil || got.StartDate != deferred.StartDate {
+		t.Errorf("GetByID = %+v, %v", got, err)
+	}
+}
+
+func testEffort(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Work", "")
+	write := mustCreateTask(t, tasks, project, "Write", day)
+	review := mustCreateTask(t, tasks, project, "Review", day.AddDate(0, 0, 1))
+	done := mustCreateTask(t, tasks, project, "Done", day)
+
+	write.Estimate, write.Spent = 90, 20
+	if err := tasks.Update(&write); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := tasks.UpdateField(&review, "Estimate", 60); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	done.Estimate, done.Completed = 30, true
+	if err := tasks.Update(&done); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	if got, err := tasks.GetByID(strconv.FormatInt(write.ID, 10)); err != nil || got.Estimate != 90 || got.Spent != 20 {
+		t.Errorf("GetByID = %+v, %v", got, err)
+	}
+
+	start := day.Add(9 * time.Hour)
+	if _, err := repository.StartTimer(tasks, write, start); err != nil {
+		t.Fatalf("StartTimer: %v", err)
+	}
+	if _, err := repository.StopTimer(tasks, start.Add(40*time.Minute)); err != nil {
+		t.Fatalf("StopTimer: %v", err)
+	}
+
+	all, err := tasks.GetAllByProject(project)
+	if err != nil {
+		t.Fatalf("GetAllByProject: %v", err)
+	}
+	estimate, actual, err := repository.TasksEffort(tasks, all, start)
+	if err != nil || estimate != 3*time.Hour || actual != time.Hour {
+		t.Errorf("TasksEffort = %v, %v, %v, want 3h, 1h", estimate, actual, err)
+	}
+
+	daily := repository.DailyEstimates(all)
+	if len(daily) != 2 || daily[repository.RoundDueDate(day)] != 90 || daily[repository.RoundDueDate(day.AddDate(0, 0, 1))] != 60 {
+		t.Errorf("DailyEstimates = %v, want 90 and 60 minutes on two days", daily)
+	}
+}
+
+func testProjectDescription(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Work", "")
+	project.Description = "# Goals\n\n- Ship it"
+	if err := projects.Update(&project); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, err := projects.GetByID(project.ID); err != nil || got.Description != project.Description {
+		t.Errorf("GetByID = %+v, %v", got, err)
+	}
+	if err := projects.UpdateField(&project, "Description", ""); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+	if got, err := projects.GetByID(project.ID); err != nil || got.Description != "" {
+		t.Errorf("after UpdateField got %+v, %v", got, err)
+	}
+
+	mustCreateTask(t, tasks, project, "Late", day)
+	mustCreateTask(t, tasks, project, "Later", day.AddDate(0, 0, 2))
+	mustCreateTask(t, tasks, project, "Anytime", time.Time{})
+	done := mustCreateTask(t, tasks, project, "Done", day)
+	if err := tasks.UpdateField(&done, "Completed", true); err != nil {
+		t.Fatalf("UpdateField: %v", err)
+	}
+
+	all, err := tasks.GetAllByProject(project)
+	if err != nil {
+		t.Fatalf("GetAllByProject: %v", err)
+	}
+	counts := repository.CountTasks(all, day.AddDate(0, 0, 1))
+	if counts != (repository.TaskCounts{Total: 4, Completed: 1, Overdue: 1}) || counts.CompletionPercent() != 25 {
+		t.Errorf("CountTasks = %+v, %d%%", counts, counts.CompletionPercent())
+	}
+}
+
+func testCompletion(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Home", "")
+	plan := mustCreateTask(t, tasks, project, "Plan", day)
+	book := mustCreateTask(t, tasks, project, "Book", day)
+	pack := mustCreateTask(t, tasks, project, "Pack", day)
+	for _, sub := range []*model.Task{&book, &pack} {
+		if err := tasks.UpdateField(sub, "ParentID", plan.ID); err != nil {
+			t.Fatalf("UpdateField: %v", err)
+		}
+	}
+	if err := repository.SetCompleted(tasks, &pack, true, day.Unix()); err != nil || !pack.Completed || pack.CompletedAt != day.Unix() {
+		t.Fatalf("SetCompleted = %+v, %v", pack, err)
+	}
+
+	at := day.Add(time.Hour).Unix()
+	if err := repository.SetCompleted(tasks, &plan, true, at); err != nil {
+		t.Fatalf("SetCompleted: %v", err)
+	}
+	completed, err := repository.CompleteDescendants(tasks, plan)
+	if err != nil || taskTitles(completed) != "[Book]" {
+		t.Fatalf("CompleteDescendants = %q, %v, want [Book]", taskTitles(completed), err)
+	}
+	if got, err := tasks.GetByID(strconv.FormatInt(book.ID, 10)); err != nil || !got.Completed || got.CompletedAt != at {
+		t.Errorf("completed subtask = %+v, %v", got, err)
+	}
+
+	if err := repository.SetCompleted(tasks, &plan, false, at); err != nil || plan.Completed || plan.CompletedAt != 0 {
+		t.Errorf("SetCompleted pending = %+v, %v", plan, err)
+	}
+	if got, err := tasks.GetByID(strconv.FormatInt(plan.ID, 10)); err != nil || got.Completed || got.CompletedAt != 0 {
+		t.Errorf("resumed task = %+v, %v", got, err)
+	}
+
+	water := mustCreateTask(t, tasks, project, "Water plants", day)
+	water.Recurrence, water.Estimate, water.Tags = "every week", 15, []string{"garden"}
+	water.StartDate = repository.RoundDueDate(day.AddDate(0, 0, -1))
+	if err := tasks.Update(&water); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	next := day.AddDate(0, 0, 7)
+	occurrence, err := repository.CreateOccurrence(tasks, &water, next, day)
+	if err != nil {
+		t.Fatalf("CreateOccurrence: %v", err)
+	}
+	got, err := tasks.GetByID(strconv.FormatInt(occurrence.ID, 10))
+	if err != nil || got.Title != "Water plants" || got.Recurrence != "every week" || got.Estimate != 15 ||
+		got.DueDate != repository.RoundDueDate(next) || got.StartDate != repository.RoundDueDate(next.AddDate(0, 0, -1)) {
+		t.Errorf("occurrence = %+v, %v", got, err)
+	}
+	if got, err := tasks.GetByID(strconv.FormatInt(water.ID, 10)); err != nil || got.Recurrence != "" || water.Recurrence != "" {
+		t.Errorf("repeated task keeps its rule: %+v, %v", got, err)
+	}
+}
+
+func testUUIDs(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	work := mustCreateProject(t, projects, "Work", "")
+	home := mustCreateProject(t, projects, "Home", "")
+	if work.UUID == "" || work.UUID == home.UUID {
+		t.Fatalf("generated project UUIDs = %q, %q, want unique ones", work.UUID, home.UUID)
+	}
+	write := mustCreateTask(t, tasks, work, "Write", day)
+	review := mustCreateTask(t, tasks, work, "Review", day)
+	if write.UUID == "" || write.UUID == review.UUID {
+		t.Fatalf("generated task UUIDs = %q, %q, want unique ones", write.UUID, review.UUID)
+	}
+
+	if got, err := projects.GetByUUID(home.UUID); err != nil || got.ID != home.ID {
+		t.Errorf("GetByUUID = %+v, %v, want Home", got, err)
+	}
+	if got, err := tasks.GetByUUID(review.UUID); err != nil || got.ID != review.ID {
+		t.Errorf("GetByUUID = %+v, %v, want Review", got, err)
+	}
+	if _, err := projects.GetByUUID(""); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByUUID empty = %v, want ErrNotFound", err)
+	}
+	if _, err := tasks.GetByUUID(""); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByUUID empty = %v, want ErrNotFound", err)
+	}
+
+	// UUIDs survive updates and trash
+	write.Title = "Rewrite"
+	if err := tasks.Update(&write); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := repository.TrashProject(projects, tasks, &work); err != nil {
+		t.Fatalf("TrashProject: %v", err)
+	}
+	if got, err := tasks.GetByUUID(write.UUID); err != nil || got.ID != write.ID || got.Title != "Rewrite" {
+		t.Errorf("GetByUUID trashed = %+v, %v", got, err)
+	}
+	if got, err := projects.GetByUUID(work.UUID); err != nil || got.ID != work.ID || got.DeletedAt == 0 {
+		t.Errorf("GetByUUID trashed = %+v, %v", got, err)
+	}
+}
+
+func testSyncStates(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository) {
+	project := mustCreateProject(t, projects, "Work", "")
+	write := mustCreateTask(t, tasks, project, "Write", time.Time{})
+	review := mustCreateTask(t, tasks, project, "Review", time.Time{})
+
+	if states, err := tasks.GetSyncStates(); err != nil || len(states) != 0 {
+		t.Errorf("GetSyncStates without states = %+v, %v", states, err)
+	}
+
+	state := model.SyncState{TaskID: write.ID, Href: "/work/write.ics", ETag: `"1"`, Hash: "a"}
+	if err := tasks.SaveSyncState(&state); err != nil || state.ID == 0 {
+		t.Fatalf("SaveSyncState = %+v, %v", state, err)
+	}
+	if err := tasks.SaveSyncState(&model.SyncState{TaskID: review.ID, Href: "/work/review.ics"}); err != nil {
+		t.Fatalf("SaveSyncState: %v", err)
+	}
+
+	// Saving the state of the same task again replaces it
+	replaced := model.SyncState{TaskID: write.ID, Href: "/work/write.ics", ETag: `"2"`, Hash: "b"}
+	if err := tasks.SaveSyncState(&replaced); err != nil || replaced.ID != state.ID {
+		t.Errorf("SaveSyncState of synced task = %+v, %v, want ID %d", replaced, err, state.ID)
+	}
+	states, err := tasks.GetSyncStates()
+	if err != nil || len(states) != 2 {
+		t.Fatalf("GetSyncStates = %+v, %v", states, err)
+	}
+	for _, got := range states {
+		if got.TaskID == write.ID && got != replaced {
+			t.Errorf("sync state = %+v, want %+v", got, replaced)
+		}
+	}
+
+	// States of projects are kept apart from the ones of tasks
+	home := mustCreateProject(t, projects, "Home", "")
+	collection := model.SyncState{ProjectID: project.ID, Href: "/work/"}
+	if err := tasks.SaveSyncState(&collection); err != nil || collection.ID == 0 {
+		t.Fatalf("SaveSyncState of project = %+v, %v", collection, err)
+	}
+	if err := tasks.SaveSyncState(&model.SyncState{ProjectID: home.ID, Href: "/home/"}); err != nil {
+		t.Fatalf("SaveSyncState of project: %v", err)
+	}
+	moved := model.SyncState{ProjectID: project.ID, Href: "/office/"}
+	if err := tasks.SaveSyncState(&moved); err != nil || moved.ID != collection.ID {
+		t.Errorf("SaveSyncState of synced project = %+v, %v, want ID %d", moved, err, collection.ID)
+	}
+	if states, _ := tasks.GetSyncStates(); len(states) != 4 {
+		t.Errorf("sync states of tasks and projects = %+v, want 4", states)
+	}
+	if err := tasks.DeleteSyncState(model.SyncState{ProjectID: home.ID}); err != nil {
+		t.Fatalf("DeleteSyncState of project: %v", err)
+	}
+	if err := tasks.DeleteSyncState(moved); err != nil {
+		t.Fatalf("DeleteSyncState of project: %v", err)
+	}
+
+	// States outlive their tasks, until they are deleted by themselves
+	if err := tasks.Delete(&write); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if states, _ := tasks.GetSyncStates(); len(states) != 2 {
+		t.Errorf("sync states after deleting task = %+v, want both", states)
+	}
+	if err := tasks.DeleteSyncState(replaced); err != nil {
+		t.Fatalf("DeleteSyncState: %v", err)
+	}
+	if states, _ := tasks.GetSyncStates(); len(states) != 1 || states[0].TaskID != review.ID {
+		t.Errorf("sync states after DeleteSyncState = %+v", states)
+	}
+	if err := tasks.DeleteSyncState(replaced); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("DeleteSyncState again = %v, want ErrNotFound", err)
+	}
+}
+
+func testTransactionCommit(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository, transactor repository.Transactor) {
+	var task model.Task
+	err := transactor.Transaction(func(txProjects repository.ProjectRepository, txTasks repository.TaskRepository) error {
+		project, err := txProjects.Create("Work", "")
+		if err != nil {
+			return err
+		}
+		if task, err = txTasks.Create(project, "Write", "", "", repository.RoundDueDate(day)); err != nil {
+			return err
+		}
+		if err := txTasks.UpdateField(&task, "Tags", []string{"docs"}); err != nil {
+			return err
+		}
+
+		// Changes are visible in the transaction before commit
+		got, err := txTasks.Find(repository.TaskQuery{ProjectID: project.ID, Tag: "docs"})
+		if err != nil || taskTitles(got) != "[Write]" {
+			t.Errorf("Find in transaction = %q, %v, want [Write]", taskTitles(got), err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+
+	if got, err := tasks.GetByID(strconv.FormatInt(task.ID, 10)); err != nil || got.Title != "Write" || fmt.Sprint(got.Tags) != "[docs]" {
+		t.Errorf("GetByID after commit = %+v, %v", got, err)
+	}
+	if got, err := projects.GetAll(); err != nil || projectTitles(got) != "[Work]" {
+		t.Errorf("GetAll after commit = %q, %v", projectTitles(got), err)
+	}
+	if history, err := tasks.GetHistory(task); err != nil || len(history) != 1 {
+		t.Errorf("GetHistory after commit = %+v, %v, want 1 change", history, err)
+	}
+}
+
+func testTransactionRollback(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository, transactor repository.Transactor) {
+	project := mustCreateProject(t, projects, "Work", "")
+	task := mustCreateTask(t, tasks, project, "Write", day)
+
+	failure := errors.New("failed")
+	err := transactor.Transaction(func(txProjects repository.ProjectRepository, txTasks repository.TaskRepository) error {
+		if _, err := txProjects.Create("Home", ""); err != nil {
+			return err
+		}
+		if _, err := txTasks.Create(project, "Review", "", "", 0); err != nil {
+			return err
+		}
+		if err := txTasks.UpdateField(&task, "Title", "Rewrite"); err != nil {
+			return err
+		}
+		if _, err := repository.StartTimer(txTasks, task, day); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Transaction = %v, want the error of fn", err)
+	}
+
+	if got, err := projects.GetAll(); err != nil || projectTitles(got) != "[Work]" {
+		t.Errorf("GetAll after rollback = %q, %v, want [Work]", projectTitles(got), err)
+	}
+	if got, err := tasks.GetAllByProject(project); err != nil || taskTitles(got) != "[Write]" {
+		t.Errorf("GetAllByProject after rollback = %q, %v, want [Write]", taskTitles(got), err)
+	}
+	if history, err := tasks.GetHistory(task); err != nil || len(history) != 0 {
+		t.Errorf("GetHistory after rollback = %+v, %v, want none", history, err)
+	}
+	if _, err := tasks.GetRunningTimeEntry(); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetRunningTimeEntry after rollback = %v, want ErrNotFound", err)
+	}
+
+	// Repositories keep working after a rollback
+	if _, err := tasks.Create(project, "Review", "", "", 0); err != nil {
+		t.Errorf("Create after rollback: %v", err)
+	}
+}
+
+func testTransactionRollbackHelpers(t *testing.T, projects repository.ProjectRepository, tasks repository.TaskRepository, transactor repository.Transactor) {
+	project := mustCreateProject(t, projects, "Work", "")
+	sub := mustCreateProject(t, projects, "Docs", "")
+	if err := repository.SetProjectParent(projects, &sub, project.ID); err != nil {
+		t.Fatalf("SetProjectParent: %v", err)
+	}
+	mustCreateTask(t, tasks, project, "Plan", day)
+	mustCreateTask(t, tasks, sub, "Write", day)
+
+	failure := errors.New("failed")
+	err := transactor.Transaction(func(txProjects repository.ProjectRepository, txTasks repository.TaskRepository) error {
+		if err := repository.TrashProject(txProjects, txTasks, &project); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Transaction = %v, want the error of fn", err)
+	}
+
+	if got, err := projects.GetAll(); err != nil || projectTitles(got) != "[Work Docs]" {
+		t.Errorf("GetAll after rollback = %q, %v, want [Work Docs]", projectTitles(got), err)
+	}
+	if got, err := tasks.Find(repository.TaskQuery{}); err != nil || taskTitles(got) != "[Plan Write]" {
+		t.Errorf("Find after rollback = %q, %v, want [Plan Write]", taskTitles(got), err)
+	}
+
+	err = transactor.Transaction(func(txProjects repository.ProjectRepository, txTasks repository.TaskRepository) error {
+		return repository.TrashProject(txProjects, txTasks, &project)
+	})
+	if err != nil {
+		t.Fatalf("Transaction: %v", err)
+	}
+	if got, err := projects.GetTrashed(); err != nil || projectTitles(got) != "[Work Docs]" {
+		t.Errorf("GetTrashed after commit = %q, %v, want [Work Docs]", projectTitles(got), err)
+	}
+}
+
+func mustCreateProject(t *testing.T, projects repository.ProjectRepository, title, UUID string) model.Project {
+	t.Helper()
+
+	project, err := projects.Create(title, UUID)
+	if err != nil {
+		t.Fatalf("could not create project %q: %v", title, err)
+	}
+
+	return project
+}
+
+func mustCreateTask(t *testing.T, tasks repository.TaskRepository, project model.Project, title string, due time.Time) model.Task {
+	t.Helper()
+
+	task, err := tasks.Create(project, title, "", "", repository.RoundDueDate(due))
+	if err != nil {
+		t.Fatalf("could not create task %q: %v", title, err)
+	}
+
+	return task
+}
+
+func projectTitles(projects []model.Project) string {
+	titles := make([]string, len(projects))
+	for i, p := range projects {
+		titles[i] = p.Title
+	}
+
+	return fmt.Sprint(titles)
+}
+
+func taskTitles(tasks []model.Task) string {
+	titles := make([]string, len(tasks))
+	for i, t := range tasks {
+		titles[i] = t.Title
+	}
+
+	return fmt.Sprint(titles)
+}
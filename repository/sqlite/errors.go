@@ -0,0 +1,16 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// translateError converts database/sql specific errors to their repository counterparts
+func translateError(err error) error {
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+
+	return err
+}
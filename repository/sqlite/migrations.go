@@ -0,0 +1,328 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/ajaxray/geek-life/migration"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+type migrationStore struct {
+	DB *sql.DB
+}
+
+// NewMigrator will create a migration.Migrator with all schema versions of SQLite database
+func NewMigrator(db *sql.DB) *migration.Migrator {
+	return migration.New(&migrationStore{db},
+		migration.Step{
+			Version:     1,
+			Description: "Create projects and tasks tables",
+			Apply:       execStep(db, schemaV1),
+		},
+		migration.Step{
+			Version:     2,
+			Description: "Round due dates of tasks to local midnight",
+			Apply:       func() error { return roundDueDates(db) },
+		},
+		migration.Step{
+			Version:     3,
+			Description: "Add priority to tasks",
+			Apply: execStep(db, `
+				ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;
+				CREATE INDEX idx_tasks_priority ON tasks (priority);`),
+		},
+		migration.Step{
+			Version:     4,
+			Description: "Add tags to tasks",
+			Apply: execStep(db, `
+				CREATE TABLE task_tags (
+					task_id INTEGER NOT NULL,
+					tag     TEXT    NOT NULL,
+					PRIMARY KEY (task_id, tag)
+				);
+				CREATE INDEX idx_task_tags_tag ON task_tags (tag);
+				CREATE TRIGGER tasks_delete_tags AFTER DELETE ON tasks BEGIN
+					DELETE FROM task_tags WHERE task_id = OLD.id;
+				END;`),
+		},
+		migration.Step{
+			Version:     5,
+			Description: "Add parent task to tasks",
+			Apply: execStep(db, `
+				ALTER TABLE tasks ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0;
+				CREATE INDEX idx_tasks_parent_id ON tasks (parent_id);`),
+		},
+		migration.Step{
+			Version:     6,
+			Description: "Add recurrence rule to tasks",
+			Apply:       execStep(db, `ALTER TABLE tasks ADD COLUMN recurrence TEXT NOT NULL DEFAULT '';`),
+		},
+		migration.Step{
+			Version:     7,
+			Description: "Add blockers to tasks",
+			Apply: execStep(db, `
+				CREATE TABLE task_blockers (
+					task_id    INTEGER NOT NULL,
+					blocker_id INTEGER NOT NULL,
+					PRIMARY KEY (task_id, blocker_id)
+				);
+				CREATE INDEX idx_task_blockers_blocker_id ON task_blockers (blocker_id);
+				CREATE TRIGGER tasks_delete_blockers AFTER DELETE ON tasks BEGIN
+					DELETE FROM task_blockers WHERE task_id = OLD.id;
+				END;`),
+		},
+		migration.Step{
+			Version:     8,
+			Description: "Add timestamps and change history to tasks",
+			Apply: execStep(db, `
+				ALTER TABLE tasks ADD COLUMN created_at INTEGER NOT NULL DEFAULT 0;
+				ALTER TABLE tasks ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;
+				CREATE TABLE task_history (
+					id         INTEGER PRIMARY KEY AUTOINCREMENT,
+					task_id    INTEGER NOT NULL,
+					field      TEXT    NOT NULL,
+					old_value  TEXT    NOT NULL,
+					new_value  TEXT    NOT NULL,
+					changed_at INTEGER NOT NULL
+				);
+				CREATE INDEX idx_task_history_task_id ON task_history (task_id);
+				CREATE TRIGGER tasks_delete_history AFTER DELETE ON tasks BEGIN
+					DELETE FROM task_history WHERE task_id = OLD.id;
+				END;`),
+		},
+		migration.Step{
+			Version:     9,
+			Description: "Add trash to projects and tasks",
+			Apply: execStep(db, `
+				ALTER TABLE projects ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0;
+				ALTER TABLE tasks ADD COLUMN deleted_at INTEGER NOT NULL DEFAULT 0;
+				CREATE INDEX idx_tasks_deleted_at ON tasks (deleted_at);`),
+		},
+		migration.Step{
+			Version:     10,
+			Description: "Add archived flag to projects",
+			Apply:       execStep(db, `ALTER TABLE projects ADD COLUMN archived INTEGER NOT NULL DEFAULT 0;`),
+		},
+		migration.Step{
+			Version:     11,
+			Description: "Add manual position to projects and tasks",
+			Apply: execStep(db, `
+				ALTER TABLE projects ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+				UPDATE projects SET position = id;
+				ALTER TABLE tasks ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+				UPDATE tasks SET position = id;`),
+		},
+		migration.Step{
+			Version:     12,
+			Description: "Add parent to projects",
+			Apply:       execStep(db, `ALTER TABLE projects ADD COLUMN parent_id INTEGER NOT NULL DEFAULT 0;`),
+		},
+		migration.Step{
+			Version:     13,
+			Description: "Add time entries of tasks",
+			Apply: execStep(db, `
+				CREATE TABLE time_entries (
+					id         INTEGER PRIMARY KEY AUTOINCREMENT,
+					task_id    INTEGER NOT NULL,
+					started_at INTEGER NOT NULL,
+					ended_at   INTEGER NOT NULL DEFAULT 0
+				);
+				CREATE INDEX idx_time_entries_task_id ON time_entries (task_id);
+				CREATE INDEX idx_time_entries_ended_at ON time_entries (ended_at);
+				CREATE TRIGGER tasks_delete_time_entries AFTER DELETE ON tasks BEGIN
+					DELETE FROM time_entries WHERE task_id = OLD.id;
+				END;`),
+		},
+		migration.Step{
+			Version:     14,
+			Description: "Add pomodoro count to tasks",
+			Apply:       execStep(db, `ALTER TABLE tasks ADD COLUMN pomodoros INTEGER NOT NULL DEFAULT 0;`),
+		},
+		migration.Step{
+			Version:     15,
+			Description: "Add due time and reminders to tasks",
+			Apply: execStep(db, `
+				ALTER TABLE tasks ADD COLUMN due_time TEXT NOT NULL DEFAULT '';
+				ALTER TABLE tasks ADD COLUMN remind_before INTEGER NOT NULL DEFAULT 0;
+				ALTER TABLE tasks ADD COLUMN remind_at INTEGER NOT NULL DEFAULT 0;
+				CREATE INDEX idx_tasks_remind_at ON tasks (remind_at);`),
+		},
+		migration.Step{
+			Version:     16,
+			Description: "Add start date to tasks",
+			Apply: execStep(db, `
+				ALTER TABLE tasks ADD COLUMN start_date INTEGER NOT NULL DEFAULT 0;
+				CREATE INDEX idx_tasks_start_date ON tasks (start_date);`),
+		},
+		migration.Step{
+			Version:     17,
+			Description: "Add effort estimate and time spent to tasks",
+			Apply: execStep(db, `
+				ALTER TABLE tasks ADD COLUMN estimate INTEGER NOT NULL DEFAULT 0;
+				ALTER TABLE tasks ADD COLUMN spent INTEGER NOT NULL DEFAULT 0;`),
+		},
+		migration.Step{
+			Version:     18,
+			Description: "Add description to projects",
+			Apply:       execStep(db, `ALTER TABLE projects ADD COLUMN description TEXT NOT NULL DEFAULT '';`),
+		},
+		migration.Step{
+			Version:     19,
+			Description: "Give a UUID to every project and task",
+			Apply:       func() error { return backfillUUIDs(db) },
+		},
+		migration.Step{
+			Version:     20,
+			Description: "Add CalDAV sync state of projects and tasks",
+			Apply: execStep(db, `
+				CREATE TABLE sync_states (
+					id         INTEGER PRIMARY KEY AUTOINCREMENT,
+					task_id    INTEGER NOT NULL DEFAULT 0,
+					project_id INTEGER NOT NULL DEFAULT 0,
+					href       TEXT    NOT NULL DEFAULT '',
+					etag       TEXT    NOT NULL DEFAULT '',
+					hash       TEXT    NOT NULL DEFAULT '',
+					UNIQUE (task_id, project_id)
+				);`),
+		},
+		migration.Step{
+			Version:     21,
+			Description: "Add time of last update to projects",
+			Apply:       execStep(db, `ALTER TABLE projects ADD COLUMN updated_at INTEGER NOT NULL DEFAULT 0;`),
+		},
+	)
+}
+
+const schemaV1 = `
+CREATE TABLE IF NOT EXISTS projects (
+	id      INTEGER PRIMARY KEY AUTOINCREMENT,
+	title   TEXT    NOT NULL DEFAULT '',
+	uuid    TEXT    UNIQUE,
+	working INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_projects_title ON projects (title);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_id   INTEGER NOT NULL DEFAULT 0,
+	uuid         TEXT    UNIQUE,
+	title        TEXT    NOT NULL DEFAULT '',
+	details      TEXT    NOT NULL DEFAULT '',
+	completed    INTEGER NOT NULL DEFAULT 0,
+	completed_at INTEGER NOT NULL DEFAULT 0,
+	due_date     INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_tasks_project_id ON tasks (project_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_completed ON tasks (completed);
+CREATE INDEX IF NOT EXISTS idx_tasks_completed_at ON tasks (completed_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_due_date ON tasks (due_date);
+`
+
+func (s *migrationStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.DB.QueryRow("PRAGMA user_version").Scan(&version)
+
+	return version, err
+}
+
+func (s *migrationStore) SetSchemaVersion(version int) error {
+	_, err := s.DB.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	return err
+}
+
+func (s *migrationStore) IsNew() (bool, error) {
+	var tables int
+	err := s.DB.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&tables)
+
+	return tables == 0, err
+}
+
+func (s *migrationStore) Backup(version int) (string, error) {
+	var seq int
+	var name, file string
+	if err := s.DB.QueryRow("PRAGMA database_list").Scan(&seq, &name, &file); err != nil {
+		return "", err
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", file, version, time.Now().Format("20060102150405"))
+	_, err := s.DB.Exec("VACUUM INTO ?", backupPath)
+
+	return backupPath, err
+}
+
+// execStep makes a migration step running SQL statements in a transaction
+func execStep(db *sql.DB, statements string) func() error {
+	return func() error {
+		return inTransaction(db, func(tx *sql.Tx) error {
+			_, err := tx.Exec(statements)
+			return err
+		})
+	}
+}
+
+func roundDueDates(db *sql.DB) error {
+	rows, err := db.Query("SELECT id, due_date FROM tasks WHERE due_date != 0")
+	if err != nil {
+		return err
+	}
+
+	rounded := make(map[int64]int64)
+	for rows.Next() {
+		var id, due int64
+		if err := rows.Scan(&id, &due); err != nil {
+			rows.Close()
+			return err
+		}
+		if r := repository.RoundDueDate(time.Unix(due, 0)); r != due {
+			rounded[id] = r
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, due := range rounded {
+		if _, err := db.Exec("UPDATE tasks SET due_date = ? WHERE id = ?", due, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backfillUUIDs generates UUIDs of projects and tasks created without one
+func backfillUUIDs(db *sql.DB) error {
+	return inTransaction(db, func(tx *sql.Tx) error {
+		for _, table := range []string{"projects", "tasks"} {
+			rows, err := tx.Query("SELECT id FROM " + table + " WHERE uuid IS NULL OR uuid = ''")
+			if err != nil {
+				return err
+			}
+
+			var ids []int64
+			for rows.Next() {
+				var id int64
+				if err := rows.Scan(&id); err != nil {
+					rows.Close()
+					return err
+				}
+				ids = append(ids, id)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				if _, err := tx.Exec("UPDATE "+table+" SET uuid = ? WHERE id = ?", repository.NewUUID(), id); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
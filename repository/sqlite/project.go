@@ -0,0 +1,155 @@
+package sqlite
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// projectColumns lists the columns of projects table (except id), in the order of projectValues
+var projectColumns = []string{"title", "uuid", "working", "deleted_at", "archived", "position", "parent_id", "description", "updated_at"}
+
+var selectProjects = "SELECT id, " + strings.Join(projectColumns, ", ") + " FROM projects"
+
+var projectFieldColumns = map[string]string{
+	"Title":       "title",
+	"UUID":        "uuid",
+	"Working":     "working",
+	"DeletedAt":   "deleted_at",
+	"Archived":    "archived",
+	"Position":    "position",
+	"ParentID":    "parent_id",
+	"Description": "description",
+	"UpdatedAt":   "updated_at",
+}
+
+type projectRepository struct {
+	DB conn
+}
+
+// NewProjectRepository will create an object that represent the repository.Project interface
+func NewProjectRepository(db *sql.DB) repository.ProjectRepository {
+	return &projectRepository{db}
+}
+
+func (repo *projectRepository) GetAll() ([]model.Project, error) {
+	return repo.queryProjects(selectProjects + " WHERE deleted_at = 0 ORDER BY position, id")
+}
+
+// GetTrashed lists projects in trash
+func (repo *projectRepository) GetTrashed() ([]model.Project, error) {
+	return repo.queryProjects(selectProjects + " WHERE deleted_at != 0 ORDER BY position, id")
+}
+
+func (repo *projectRepository) queryProjects(stmt string) ([]model.Project, error) {
+	rows, err := repo.DB.Query(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []model.Project
+	for rows.Next() {
+		project, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, project)
+	}
+
+	return projects, rows.Err()
+}
+
+func (repo *projectRepository) GetByID(id int64) (model.Project, error) {
+	return repo.getOneByColumn("id", id)
+}
+
+func (repo *projectRepository) GetByTitle(title string) (model.Project, error) {
+	row := repo.DB.QueryRow(selectProjects+" WHERE title = ? AND deleted_at = 0 ORDER BY id LIMIT 1", title)
+	project, err := scanProject(row)
+
+	return project, translateError(err)
+}
+
+func (repo *projectRepository) GetByUUID(UUID string) (model.Project, error) {
+	return repo.getOneByColumn("uuid", UUID)
+}
+
+func (repo *projectRepository) Create(title, UUID string) (model.Project, error) {
+	if UUID == "" {
+		UUID = repository.NewUUID()
+	}
+
+	project := model.Project{
+		Title:     title,
+		UUID:      UUID,
+		UpdatedAt: time.Now().Unix(),
+	}
+
+	err := inTransaction(repo.DB, func(tx *sql.Tx) error {
+		result, err := tx.Exec(insertStatement("projects", projectColumns), projectValues(&project)...)
+		if err != nil {
+			return err
+		}
+		if project.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		project.Position = project.ID
+		_, err = tx.Exec("UPDATE projects SET position = id WHERE id = ?", project.ID)
+		return err
+	})
+
+	return project, err
+}
+
+func (repo *projectRepository) Update(project *model.Project) error {
+	project.UpdatedAt = time.Now().Unix()
+	return translateError(checkAffected(repo.DB.Exec(updateStatement("projects", projectColumns), append(projectValues(project), project.ID)...)))
+}
+
+func (repo *projectRepository) UpdateField(project *model.Project, field string, value interface{}) error {
+	column, err := columnOf(projectFieldColumns, project, field, value)
+	if err != nil {
+		return err
+	}
+
+	if column == "uuid" {
+		value = nullIfEmpty(value.(string))
+	}
+
+	now := time.Now().Unix()
+	if err := translateError(checkAffected(repo.DB.Exec("UPDATE projects SET "+column+" = ?, updated_at = ? WHERE id = ?", value, now, project.ID))); err != nil {
+		return err
+	}
+	project.UpdatedAt = now
+
+	return nil
+}
+
+func (repo *projectRepository) Delete(project *model.Project) error {
+	return translateError(checkAffected(repo.DB.Exec("DELETE FROM projects WHERE id = ?", project.ID)))
+}
+
+func (repo *projectRepository) getOneByColumn(column string, val interface{}) (model.Project, error) {
+	row := repo.DB.QueryRow(selectProjects+" WHERE "+column+" = ? ORDER BY id LIMIT 1", val)
+	project, err := scanProject(row)
+
+	return project, translateError(err)
+}
+
+func projectValues(project *model.Project) []interface{} {
+	return []interface{}{project.Title, nullIfEmpty(project.UUID), project.Working, project.DeletedAt, project.Archived, project.Position, project.ParentID, project.Description, project.UpdatedAt}
+}
+
+func scanProject(row scanner) (model.Project, error) {
+	var project model.Project
+	var uuid sql.NullString
+	err := row.Scan(&project.ID, &project.Title, &uuid, &project.Working, &project.DeletedAt, &project.Archived, &project.Position, &project.ParentID, &project.Description, &project.UpdatedAt)
+	project.UUID = uuid.String
+
+	return project, err
+}
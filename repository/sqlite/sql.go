@@ -0,0 +1,91 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// insertStatement builds an INSERT of given columns, with one placeholder for each
+func insertStatement(table string, columns []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders + ")"
+}
+
+// updateStatement builds an UPDATE of given columns of the row having id of the last placeholder
+func updateStatement(table string, columns []string) string {
+	return "UPDATE " + table + " SET " + strings.Join(columns, " = ?, ") + " = ? WHERE id = ?"
+}
+
+// conn runs statements on the database, either directly or in a transaction
+type conn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// inTransaction runs fn in a transaction, which is committed only if fn succeeds.
+// If db is a transaction already, fn joins it, leaving commit or rollback to its owner.
+func inTransaction(db conn, fn func(tx *sql.Tx) error) error {
+	if tx, ok := db.(*sql.Tx); ok {
+		return fn(tx)
+	}
+
+	tx, err := db.(*sql.DB).Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// columnOf finds the column mapped to a model field and checks if value can be stored in it.
+// It mirrors storm's UpdateField, which accepts only values of the same kind as the field.
+func columnOf(columns map[string]string, record interface{}, field string, value interface{}) (string, error) {
+	column, ok := columns[field]
+	if !ok {
+		return "", fmt.Errorf("unknown field %q", field)
+	}
+
+	structField, _ := reflect.TypeOf(record).Elem().FieldByName(field)
+	if reflect.ValueOf(value).Kind() != structField.Type.Kind() {
+		return "", fmt.Errorf("incompatible value for field %q", field)
+	}
+
+	return column, nil
+}
+
+// nullIfEmpty stores empty strings as NULL, to keep UNIQUE columns usable for optional values
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// checkAffected reports sql.ErrNoRows when a statement did not touch any row
+func checkAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package sqlite_test
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/ajaxray/geek-life/repository"
+	"github.com/ajaxray/geek-life/repository/repotest"
+	repo "github.com/ajaxray/geek-life/repository/sqlite"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository) {
+		db := openDB(t)
+		return repo.NewProjectRepository(db), repo.NewTaskRepository(db)
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	repotest.RunTransactions(t, func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository, repository.Transactor) {
+		db := openDB(t)
+		return repo.NewProjectRepository(db), repo.NewTaskRepository(db), repo.NewTransactor(db)
+	})
+}
+
+// openDB opens a migrated database in a temporary directory, closed when the test ends.
+// A single connection is used, like the app does.
+func openDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.sqlite"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := repo.NewMigrator(db).Migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
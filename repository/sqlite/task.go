@@ -0,0 +1,526 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// taskColumns lists the columns of tasks table (except id), in the order of taskValues
+var taskColumns = []string{"project_id", "uuid", "title", "details", "completed", "completed_at", "due_date", "priority", "parent_id", "recurrence", "created_at", "updated_at", "deleted_at", "position", "pomodoros", "due_time", "remind_before", "remind_at", "start_date", "estimate", "spent"}
+
+var taskFieldColumns = map[string]string{
+	"ProjectID":    "project_id",
+	"UUID":         "uuid",
+	"Title":        "title",
+	"Details":      "details",
+	"Completed":    "completed",
+	"CompletedAt":  "completed_at",
+	"DueDate":      "due_date",
+	"Priority":     "priority",
+	"ParentID":     "parent_id",
+	"Recurrence":   "recurrence",
+	"CreatedAt":    "created_at",
+	"UpdatedAt":    "updated_at",
+	"DeletedAt":    "deleted_at",
+	"Position":     "position",
+	"Pomodoros":    "pomodoros",
+	"DueTime":      "due_time",
+	"RemindBefore": "remind_before",
+	"RemindAt":     "remind_at",
+	"StartDate":    "start_date",
+	"Estimate":     "estimate",
+	"Spent":        "spent",
+	"Tags":         tagsColumn,
+	"BlockedBy":    blockedByColumn,
+}
+
+// tagsColumn is the comma separated list of tags, selected from task_tags table along with tasks
+const tagsColumn = "tags"
+
+// blockedByColumn is the comma separated list of blocker IDs, selected from task_blockers table along with tasks
+const blockedByColumn = "blocked_by"
+
+var taskOrderColumns = map[string]string{
+	"ID":          "id",
+	"ProjectID":   "project_id",
+	"Title":       "title COLLATE NOCASE",
+	"DueDate":     "due_date",
+	"CompletedAt": "completed_at",
+	"Priority":    "priority",
+	"Position":    "position",
+	"RemindAt":    "remind_at",
+}
+
+var selectTasks = "SELECT id, " + strings.Join(taskColumns, ", ") +
+	", (SELECT group_concat(tag, ',' ORDER BY tag) FROM task_tags WHERE task_id = tasks.id) AS " + tagsColumn +
+	", (SELECT group_concat(blocker_id, ',' ORDER BY blocker_id) FROM task_blockers WHERE task_id = tasks.id) AS " + blockedByColumn + " FROM tasks"
+
+type taskRepository struct {
+	DB conn
+}
+
+// NewTaskRepository will create an object that represent the repository.Task interface
+func NewTaskRepository(db *sql.DB) repository.TaskRepository {
+	return &taskRepository{db}
+}
+
+func (t *taskRepository) GetAll() ([]model.Task, error) {
+	return t.Find(repository.TaskQuery{})
+}
+
+// Find translates every criterion of the query to SQL, so filtering, ordering and pagination use the table indexes
+func (t *taskRepository) Find(query repository.TaskQuery) ([]model.Task, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	if query.ProjectID != 0 {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, query.ProjectID)
+	}
+
+	if query.ParentID != 0 {
+		conditions = append(conditions, "parent_id = ?")
+		args = append(args, query.ParentID)
+	}
+
+	if query.Unscheduled {
+		conditions = append(conditions, "due_date = 0")
+	} else if query.HasDueRange() {
+		from, to := query.DueBounds()
+		conditions = append(conditions, "due_date BETWEEN ? AND ?")
+		args = append(args, from, to)
+	}
+
+	if !query.RemindBy.IsZero() {
+		conditions = append(conditions, "remind_at BETWEEN 1 AND ?")
+		args = append(args, query.RemindBy.Unix())
+	}
+
+	if !query.AvailableBy.IsZero() {
+		conditions = append(conditions, "start_date <= ?")
+		args = append(args, query.AvailableBy.Unix())
+	}
+
+	switch query.Status {
+	case repository.StatusPending:
+		conditions = append(conditions, "completed = 0")
+	case repository.StatusCompleted:
+		conditions = append(conditions, "completed = 1")
+	}
+
+	switch query.Trash {
+	case repository.NotTrashed:
+		conditions = append(conditions, "deleted_at = 0")
+	case repository.TrashedOnly:
+		conditions = append(conditions, "deleted_at != 0")
+	}
+
+	if query.Tag != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM task_tags WHERE task_id = tasks.id AND tag = ?)")
+		args = append(args, model.NormalizeTag(query.Tag))
+	}
+
+	if query.Text != "" {
+		conditions = append(conditions, "(instr(lower(title), lower(?)) > 0 OR instr(lower(details), lower(?)) > 0)")
+		args = append(args, query.Text, query.Text)
+	}
+
+	stmt := selectTasks
+	if len(conditions) > 0 {
+		stmt += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	direction := " ASC"
+	if query.Reverse {
+		direction = " DESC"
+	}
+	orderBy := query.OrderBy
+	if orderBy == "" {
+		orderBy = "ID"
+	}
+	stmt += " ORDER BY "
+	if query.PriorityFirst {
+		stmt += "priority DESC, "
+	}
+	stmt += taskOrderColumns[orderBy] + direction + ", position" + direction + ", id" + direction
+
+	if query.Limit > 0 || query.Offset > 0 {
+		limit := -1
+		if query.Limit > 0 {
+			limit = query.Limit
+		}
+		stmt += " LIMIT ? OFFSET ?"
+		args = append(args, limit, query.Offset)
+	}
+
+	return t.queryTasks(stmt, args...)
+}
+
+func (t *taskRepository) GetAllByProject(project model.Project) ([]model.Task, error) {
+	return t.Find(repository.TaskQuery{ProjectID: project.ID})
+}
+
+func (t *taskRepository) GetAllByDate(date time.Time) ([]model.Task, error) {
+	if date.IsZero() {
+		return t.Find(repository.TaskQuery{Unscheduled: true, OrderBy: "ProjectID"})
+	}
+
+	return t.Find(repository.TaskQuery{DueFrom: date, DueTo: date})
+}
+
+func (t *taskRepository) GetAllByDateRange(from, to time.Time) ([]model.Task, error) {
+	return t.Find(repository.TaskQuery{DueFrom: from, DueTo: to, OrderBy: "DueDate"})
+}
+
+func (t *taskRepository) GetAllCompletedByDate(date time.Time) ([]model.Task, error) {
+	return t.queryTasks(selectTasks+" WHERE completed = 1 AND deleted_at = 0 AND completed_at BETWEEN ? AND ? ORDER BY completed_at, id",
+		repository.RoundDueDate(date), repository.EndOfDay(date))
+}
+
+// GetAllTags lists tags in use with number of tasks labeled by each
+func (t *taskRepository) GetAllTags() ([]repository.TagCount, error) {
+	rows, err := t.DB.Query("SELECT tag, COUNT(*) FROM task_tags JOIN tasks ON tasks.id = task_id WHERE deleted_at = 0 GROUP BY tag ORDER BY tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []repository.TagCount{}
+	for rows.Next() {
+		var tag repository.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Tasks); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
+
+func (t *taskRepository) GetByID(ID string) (model.Task, error) {
+	id, err := strconv.ParseInt(ID, 10, 64)
+	if err != nil {
+		return model.Task{}, err
+	}
+
+	return t.getOneByColumn("id", id)
+}
+
+func (t *taskRepository) GetByUUID(UUID string) (model.Task, error) {
+	return t.getOneByColumn("uuid", UUID)
+}
+
+func (t *taskRepository) Create(project model.Project, title, details, UUID string, dueDate int64) (model.Task, error) {
+	if UUID == "" {
+		UUID = repository.NewUUID()
+	}
+
+	now := time.Now().Unix()
+	task := model.Task{
+		ProjectID: project.ID,
+		Title:     title,
+		Details:   details,
+		UUID:      UUID,
+		DueDate:   dueDate,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	err := inTransaction(t.DB, func(tx *sql.Tx) error {
+		result, err := tx.Exec(insertStatement("tasks", taskColumns), taskValues(&task)...)
+		if err != nil {
+			return err
+		}
+		if task.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		task.Position = task.ID
+		_, err = tx.Exec("UPDATE tasks SET position = id WHERE id = ?", task.ID)
+		return err
+	})
+
+	return task, err
+}
+
+func (t *taskRepository) Update(task *model.Task) error {
+	return translateError(inTransaction(t.DB, func(tx *sql.Tx) error {
+		existing, err := scanTask(tx.QueryRow(selectTasks+" WHERE id = ?", task.ID))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		task.CreatedAt, task.UpdatedAt = existing.CreatedAt, now
+		if _, err := tx.Exec(updateStatement("tasks", taskColumns), append(taskValues(task), task.ID)...); err != nil {
+			return err
+		}
+		if err := replaceTags(tx, task.ID, task.Tags); err != nil {
+			return err
+		}
+		if err := replaceBlockers(tx, task.ID, task.BlockedBy); err != nil {
+			return err
+		}
+
+		return insertChanges(tx, repository.DiffTask(existing, *task, now)...)
+	}))
+}
+
+func (t *taskRepository) UpdateField(task *model.Task, field string, value interface{}) error {
+	column, err := columnOf(taskFieldColumns, task, field, value)
+	if err != nil {
+		return err
+	}
+
+	switch column {
+	case tagsColumn:
+		if _, ok := value.([]string); !ok {
+			return fmt.Errorf("incompatible value for field %q", field)
+		}
+	case blockedByColumn:
+		if _, ok := value.([]int64); !ok {
+			return fmt.Errorf("incompatible value for field %q", field)
+		}
+	}
+
+	return translateError(inTransaction(t.DB, func(tx *sql.Tx) error {
+		existing, err := scanTask(tx.QueryRow(selectTasks+" WHERE id = ?", task.ID))
+		if err != nil {
+			return err
+		}
+
+		switch column {
+		case tagsColumn:
+			err = replaceTags(tx, task.ID, value.([]string))
+		case blockedByColumn:
+			err = replaceBlockers(tx, task.ID, value.([]int64))
+		case "uuid":
+			_, err = tx.Exec("UPDATE tasks SET uuid = ? WHERE id = ?", nullIfEmpty(value.(string)), task.ID)
+		default:
+			_, err = tx.Exec("UPDATE tasks SET "+column+" = ? WHERE id = ?", value, task.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		if _, err := tx.Exec("UPDATE tasks SET updated_at = ? WHERE id = ?", now, task.ID); err != nil {
+			return err
+		}
+		task.UpdatedAt = now
+
+		if change, ok := repository.FieldChange(existing, field, value, now); ok {
+			return insertChanges(tx, change)
+		}
+		return nil
+	}))
+}
+
+func (t *taskRepository) Delete(task *model.Task) error {
+	return translateError(checkAffected(t.DB.Exec("DELETE FROM tasks WHERE id = ?", task.ID)))
+}
+
+// GetHistory lists changes of a task, oldest first
+func (t *taskRepository) GetHistory(task model.Task) ([]model.TaskChange, error) {
+	rows, err := t.DB.Query("SELECT id, task_id, field, old_value, new_value, changed_at FROM task_history WHERE task_id = ? ORDER BY id", task.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []model.TaskChange{}
+	for rows.Next() {
+		var change model.TaskChange
+		if err := rows.Scan(&change.ID, &change.TaskID, &change.Field, &change.OldValue, &change.NewValue, &change.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
+
+// GetTimeEntries lists time entries of a task, oldest first
+func (t *taskRepository) GetTimeEntries(task model.Task) ([]model.TimeEntry, error) {
+	rows, err := t.DB.Query("SELECT id, task_id, started_at, ended_at FROM time_entries WHERE task_id = ? ORDER BY id", task.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []model.TimeEntry{}
+	for rows.Next() {
+		var entry model.TimeEntry
+		if err := rows.Scan(&entry.ID, &entry.TaskID, &entry.Start, &entry.End); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetRunningTimeEntry finds the time entry which is not ended yet
+func (t *taskRepository) GetRunningTimeEntry() (model.TimeEntry, error) {
+	var entry model.TimeEntry
+	err := t.DB.QueryRow("SELECT id, task_id, started_at, ended_at FROM time_entries WHERE ended_at = 0 ORDER BY id LIMIT 1").
+		Scan(&entry.ID, &entry.TaskID, &entry.Start, &entry.End)
+
+	return entry, translateError(err)
+}
+
+// SaveTimeEntry creates a time entry if it has no ID, or replaces the existing one
+func (t *taskRepository) SaveTimeEntry(entry *model.TimeEntry) error {
+	if entry.ID != 0 {
+		return translateError(checkAffected(t.DB.Exec("UPDATE time_entries SET task_id = ?, started_at = ?, ended_at = ? WHERE id = ?",
+			entry.TaskID, entry.Start, entry.End, entry.ID)))
+	}
+
+	result, err := t.DB.Exec("INSERT INTO time_entries (task_id, started_at, ended_at) VALUES (?, ?, ?)", entry.TaskID, entry.Start, entry.End)
+	if err != nil {
+		return err
+	}
+	entry.ID, err = result.LastInsertId()
+
+	return err
+}
+
+// GetSyncStates lists sync states of all tasks, including the deleted ones
+func (t *taskRepository) GetSyncStates() ([]model.SyncState, error) {
+	rows, err := t.DB.Query("SELECT id, task_id, project_id, href, etag, hash FROM sync_states ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := []model.SyncState{}
+	for rows.Next() {
+		var state model.SyncState
+		if err := rows.Scan(&state.ID, &state.TaskID, &state.ProjectID, &state.Href, &state.ETag, &state.Hash); err != nil {
+			return nil, err
+		}
+		states = append(states, state)
+	}
+
+	return states, rows.Err()
+}
+
+// SaveSyncState creates the sync state of a task or project, or replaces the existing one
+func (t *taskRepository) SaveSyncState(state *model.SyncState) error {
+	return t.DB.QueryRow(`INSERT INTO sync_states (task_id, project_id, href, etag, hash) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (task_id, project_id) DO UPDATE SET href = excluded.href, etag = excluded.etag, hash = excluded.hash
+		RETURNING id`, state.TaskID, state.ProjectID, state.Href, state.ETag, state.Hash).Scan(&state.ID)
+}
+
+func (t *taskRepository) DeleteSyncState(state model.SyncState) error {
+	return translateError(checkAffected(t.DB.Exec("DELETE FROM sync_states WHERE task_id = ? AND project_id = ?", state.TaskID, state.ProjectID)))
+}
+
+func (t *taskRepository) getOneByColumn(column string, val interface{}) (model.Task, error) {
+	row := t.DB.QueryRow(selectTasks+" WHERE "+column+" = ?", val)
+	task, err := scanTask(row)
+
+	return task, translateError(err)
+}
+
+func (t *taskRepository) queryTasks(stmt string, args ...interface{}) ([]model.Task, error) {
+	rows, err := t.DB.Query(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []model.Task
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// replaceTags stores the tags of a task in place of its previous tags
+func replaceTags(tx *sql.Tx, taskID int64, tags []string) error {
+	if _, err := tx.Exec("DELETE FROM task_tags WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO task_tags (task_id, tag) VALUES (?, ?)", taskID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceBlockers stores the blockers of a task in place of its previous blockers
+func replaceBlockers(tx *sql.Tx, taskID int64, blockers []int64) error {
+	if _, err := tx.Exec("DELETE FROM task_blockers WHERE task_id = ?", taskID); err != nil {
+		return err
+	}
+
+	for _, blocker := range blockers {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO task_blockers (task_id, blocker_id) VALUES (?, ?)", taskID, blocker); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertChanges appends changes to the history of tasks
+func insertChanges(tx *sql.Tx, changes ...model.TaskChange) error {
+	for _, change := range changes {
+		if _, err := tx.Exec("INSERT INTO task_history (task_id, field, old_value, new_value, changed_at) VALUES (?, ?, ?, ?, ?)",
+			change.TaskID, change.Field, change.OldValue, change.NewValue, change.ChangedAt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func taskValues(task *model.Task) []interface{} {
+	return []interface{}{task.ProjectID, nullIfEmpty(task.UUID), task.Title, task.Details,
+		task.Completed, task.CompletedAt, task.DueDate, task.Priority, task.ParentID, task.Recurrence, task.CreatedAt, task.UpdatedAt, task.DeletedAt, task.Position, task.Pomodoros, task.DueTime, task.RemindBefore, task.RemindAt, task.StartDate, task.Estimate, task.Spent}
+}
+
+func scanTask(row scanner) (model.Task, error) {
+	var task model.Task
+	var uuid, tags, blockers sql.NullString
+	err := row.Scan(&task.ID, &task.ProjectID, &uuid, &task.Title, &task.Details,
+		&task.Completed, &task.CompletedAt, &task.DueDate, &task.Priority, &task.ParentID, &task.Recurrence, &task.CreatedAt, &task.UpdatedAt, &task.DeletedAt, &task.Position, &task.Pomodoros, &task.DueTime, &task.RemindBefore, &task.RemindAt, &task.StartDate, &task.Estimate, &task.Spent, &tags, &blockers)
+	if err != nil {
+		return task, err
+	}
+
+	task.UUID = uuid.String
+	if tags.Valid {
+		task.Tags = strings.Split(tags.String, ",")
+	}
+	if blockers.Valid {
+		for _, id := range strings.Split(blockers.String, ",") {
+			blocker, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				return task, err
+			}
+			task.BlockedBy = append(task.BlockedBy, blocker)
+		}
+	}
+
+	return task, nil
+}
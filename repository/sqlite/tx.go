@@ -0,0 +1,22 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/ajaxray/geek-life/repository"
+)
+
+type transactor struct {
+	DB *sql.DB
+}
+
+// NewTransactor will create an object that represent the repository.Transactor interface
+func NewTransactor(db *sql.DB) repository.Transactor {
+	return &transactor{db}
+}
+
+func (t *transactor) Transaction(fn func(projects repository.ProjectRepository, tasks repository.TaskRepository) error) error {
+	return inTransaction(t.DB, func(tx *sql.Tx) error {
+		return fn(&projectRepository{tx}, &taskRepository{tx})
+	})
+}
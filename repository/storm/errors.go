@@ -0,0 +1,25 @@
+package storm
+
+import (
+	"github.com/asdine/storm/v3"
+
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// translateError converts storm specific errors to their repository counterparts
+func translateError(err error) error {
+	if err == storm.ErrNotFound {
+		return repository.ErrNotFound
+	}
+
+	return err
+}
+
+// ignoreNotFound drops the ErrNotFound that storm finders return for an empty result
+func ignoreNotFound(err error) error {
+	if err == storm.ErrNotFound {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,259 @@
+package storm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/asdine/storm/v3"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/ajaxray/geek-life/migration"
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+const (
+	metaBucket       = "meta"
+	schemaVersionKey = "schema_version"
+)
+
+type migrationStore struct {
+	DB *storm.DB
+}
+
+// NewMigrator will create a migration.Migrator with all schema versions of storm database
+func NewMigrator(db *storm.DB) *migration.Migrator {
+	return migration.New(&migrationStore{db},
+		migration.Step{
+			Version:     1,
+			Description: "Rebuild indexes of projects and tasks",
+			Apply:       func() error { return reIndex(db) },
+		},
+		migration.Step{
+			Version:     2,
+			Description: "Round due dates of tasks to local midnight",
+			Apply:       func() error { return roundDueDates(db) },
+		},
+		migration.Step{
+			Version:     3,
+			Description: "Add priority to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     4,
+			Description: "Add tags to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     5,
+			Description: "Add parent task to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     6,
+			Description: "Add recurrence rule to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     7,
+			Description: "Add blockers to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     8,
+			Description: "Add timestamps and change history to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     9,
+			Description: "Add trash to projects and tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     10,
+			Description: "Add archived flag to projects",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     11,
+			Description: "Add manual position to projects and tasks",
+			Apply:       func() error { return initPositions(db) },
+		},
+		migration.Step{
+			Version:     12,
+			Description: "Add parent to projects",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     13,
+			Description: "Add time entries of tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     14,
+			Description: "Add pomodoro count to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     15,
+			Description: "Add due time and reminders to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     16,
+			Description: "Add start date to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     17,
+			Description: "Add effort estimate and time spent to tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     18,
+			Description: "Add description to projects",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     19,
+			Description: "Give a UUID to every project and task",
+			Apply:       func() error { return backfillUUIDs(db) },
+		},
+		migration.Step{
+			Version:     20,
+			Description: "Add CalDAV sync state of projects and tasks",
+			Apply:       noChange,
+		},
+		migration.Step{
+			Version:     21,
+			Description: "Add time of last update to projects",
+			Apply:       noChange,
+		},
+	)
+}
+
+func (s *migrationStore) SchemaVersion() (int, error) {
+	var version int
+	err := s.DB.Get(metaBucket, schemaVersionKey, &version)
+
+	return version, ignoreNotFound(err)
+}
+
+func (s *migrationStore) SetSchemaVersion(version int) error {
+	return s.DB.Set(metaBucket, schemaVersionKey, version)
+}
+
+func (s *migrationStore) IsNew() (bool, error) {
+	isNew := true
+	err := s.DB.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if string(name) != metaBucket {
+				isNew = false
+			}
+			return nil
+		})
+	})
+
+	return isNew, err
+}
+
+func (s *migrationStore) Backup(version int) (string, error) {
+	backupPath := fmt.Sprintf("%s.v%d-%s.bak", s.DB.Bolt.Path(), version, time.Now().Format("20060102150405"))
+	err := s.DB.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(backupPath, 0600)
+	})
+
+	return backupPath, err
+}
+
+// noChange is used for steps that only add fields. Records are stored as JSON,
+// so a missing field is read as zero value without touching existing data.
+func noChange() error {
+	return nil
+}
+
+func reIndex(db *storm.DB) error {
+	if err := ignoreNotFound(db.ReIndex(&model.Project{})); err != nil {
+		return err
+	}
+
+	return ignoreNotFound(db.ReIndex(&model.Task{}))
+}
+
+func roundDueDates(db *storm.DB) error {
+	var tasks []model.Task
+	if err := db.All(&tasks); err != nil {
+		return err
+	}
+
+	for i := range tasks {
+		if tasks[i].DueDate == 0 {
+			continue
+		}
+
+		rounded := repository.RoundDueDate(time.Unix(tasks[i].DueDate, 0))
+		if rounded != tasks[i].DueDate {
+			if err := db.UpdateField(&tasks[i], "DueDate", rounded); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// backfillUUIDs generates UUIDs of projects and tasks created without one
+func backfillUUIDs(db *storm.DB) error {
+	return inTransaction(db, func(tx storm.Node) error {
+		var projects []model.Project
+		if err := ignoreNotFound(tx.All(&projects)); err != nil {
+			return err
+		}
+		for i := range projects {
+			if projects[i].UUID == "" {
+				if err := tx.UpdateField(&projects[i], "UUID", repository.NewUUID()); err != nil {
+					return err
+				}
+			}
+		}
+
+		var tasks []model.Task
+		if err := ignoreNotFound(tx.All(&tasks)); err != nil {
+			return err
+		}
+		for i := range tasks {
+			if tasks[i].UUID == "" {
+				if err := tx.UpdateField(&tasks[i], "UUID", repository.NewUUID()); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// initPositions keeps existing projects and tasks in the order of their IDs
+func initPositions(db *storm.DB) error {
+	var projects []model.Project
+	if err := ignoreNotFound(db.All(&projects)); err != nil {
+		return err
+	}
+	for i := range projects {
+		if err := db.UpdateField(&projects[i], "Position", projects[i].ID); err != nil {
+			return err
+		}
+	}
+
+	var tasks []model.Task
+	if err := ignoreNotFound(db.All(&tasks)); err != nil {
+		return err
+	}
+	for i := range tasks {
+		if err := db.UpdateField(&tasks[i], "Position", tasks[i].ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,65 +1,137 @@
-package storm
-
-import (
-	"github.com/asdine/storm/v3"
-
-	"github.com/ajaxray/geek-life/model"
-	"github.com/ajaxray/geek-life/repository"
-)
-
-type projectRepository struct {
-	DB *storm.DB
-}
-
-// NewProjectRepository will create an object that represent the repository.Project interface
-func NewProjectRepository(db *storm.DB) repository.ProjectRepository {
-	return &projectRepository{db}
-}
-
-func (repo *projectRepository) GetAll() ([]model.Project, error) {
-	var projects []model.Project
-	err := repo.DB.All(&projects)
-
-	return projects, err
-}
-
-func (repo *projectRepository) GetByID(id int64) (model.Project, error) {
-	return repo.getOneByField("ID", id)
-}
-
-func (repo *projectRepository) GetByTitle(title string) (model.Project, error) {
-	return repo.getOneByField("Title", title)
-}
-
-func (repo *projectRepository) GetByUUID(UUID string) (model.Project, error) {
-	return repo.getOneByField("CloudId", UUID)
-}
-
-func (repo *projectRepository) Create(title, UUID string) (model.Project, error) {
-	project := model.Project{
-		Title: title,
-		UUID:  UUID,
-	}
-
-	err := repo.DB.Save(&project)
-	return project, err
-}
-
-func (repo *projectRepository) Update(project *model.Project) error {
-	return repo.DB.Save(project)
-}
-
-func (repo *projectRepository) Delete(project *model.Project) error {
-	return repo.DB.DeleteStruct(project)
-}
-
-func (repo *projectRepository) UpdateField(task *model.Project, field string, value interface{}) error {
-	return repo.DB.UpdateField(task, field, value)
-}
-
-func (repo *projectRepository) getOneByField(fieldName string, val interface{}) (model.Project, error) {
-	var project model.Project
-	err := repo.DB.One(fieldName, val, &project)
-
-	return project, err
-}
+package storm
+
+import (
+	"math"
+	"time"
+
+	"github.com/asdine/storm/v3"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+type projectRepository struct {
+	DB storm.Node
+}
+
+// NewProjectRepository will create an object that represent the repository.Project interface
+func NewProjectRepository(db *storm.DB) repository.ProjectRepository {
+	return &projectRepository{db}
+}
+
+func (repo *projectRepository) GetAll() ([]model.Project, error) {
+	var projects []model.Project
+	if err := repo.DB.All(&projects); ignoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	projects = notTrashed(projects)
+	repository.SortProjects(projects)
+
+	return projects, nil
+}
+
+// GetTrashed lists projects in trash
+func (repo *projectRepository) GetTrashed() ([]model.Project, error) {
+	var projects []model.Project
+	err := repo.DB.Range("DeletedAt", int64(1), int64(math.MaxInt64), &projects)
+	repository.SortProjects(projects)
+
+	return projects, ignoreNotFound(err)
+}
+
+func (repo *projectRepository) GetByID(id int64) (model.Project, error) {
+	return repo.getOneByField("ID", id)
+}
+
+func (repo *projectRepository) GetByTitle(title string) (model.Project, error) {
+	var projects []model.Project
+	if err := repo.DB.Find("Title", title, &projects); err != nil {
+		return model.Project{}, translateError(err)
+	}
+
+	if projects = notTrashed(projects); len(projects) == 0 {
+		return model.Project{}, repository.ErrNotFound
+	}
+	return projects[0], nil
+}
+
+func (repo *projectRepository) GetByUUID(UUID string) (model.Project, error) {
+	return repo.getOneByField("UUID", UUID)
+}
+
+func (repo *projectRepository) Create(title, UUID string) (model.Project, error) {
+	if UUID == "" {
+		UUID = repository.NewUUID()
+	}
+
+	project := model.Project{
+		Title:     title,
+		UUID:      UUID,
+		UpdatedAt: time.Now().Unix(),
+	}
+
+	err := inTransaction(repo.DB, func(tx storm.Node) error {
+		if err := tx.Save(&project); err != nil {
+			return err
+		}
+
+		project.Position = project.ID
+		return tx.Save(&project)
+	})
+
+	return project, err
+}
+
+func (repo *projectRepository) Update(project *model.Project) error {
+	if _, err := repo.GetByID(project.ID); err != nil {
+		return err
+	}
+
+	project.UpdatedAt = time.Now().Unix()
+	return repo.DB.Save(project)
+}
+
+func (repo *projectRepository) Delete(project *model.Project) error {
+	return translateError(repo.DB.DeleteStruct(project))
+}
+
+func (repo *projectRepository) UpdateField(project *model.Project, field string, value interface{}) error {
+	return translateError(inTransaction(repo.DB, func(tx storm.Node) error {
+		if err := tx.UpdateField(project, field, value); err != nil {
+			return err
+		}
+
+		// Indexes are updated from the given struct, which does not have the new value yet
+		var updated model.Project
+		if err := tx.One("ID", project.ID, &updated); err != nil {
+			return err
+		}
+		now := time.Now().Unix()
+		if err := tx.UpdateField(&updated, "UpdatedAt", now); err != nil {
+			return err
+		}
+		project.UpdatedAt = now
+
+		return nil
+	}))
+}
+
+// notTrashed filters out projects in trash
+func notTrashed(projects []model.Project) []model.Project {
+	var result []model.Project
+	for _, project := range projects {
+		if project.DeletedAt == 0 {
+			result = append(result, project)
+		}
+	}
+
+	return result
+}
+
+func (repo *projectRepository) getOneByField(fieldName string, val interface{}) (model.Project, error) {
+	var project model.Project
+	err := repo.DB.One(fieldName, val, &project)
+
+	return project, translateError(err)
+}
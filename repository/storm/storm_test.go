@@ -0,0 +1,40 @@
+package storm_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/asdine/storm/v3"
+
+	"github.com/ajaxray/geek-life/repository"
+	"github.com/ajaxray/geek-life/repository/repotest"
+	repo "github.com/ajaxray/geek-life/repository/storm"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository) {
+		db := openDB(t)
+		return repo.NewProjectRepository(db), repo.NewTaskRepository(db)
+	})
+}
+
+func TestTransactions(t *testing.T) {
+	repotest.RunTransactions(t, func(t *testing.T) (repository.ProjectRepository, repository.TaskRepository, repository.Transactor) {
+		db := openDB(t)
+		return repo.NewProjectRepository(db), repo.NewTaskRepository(db), repo.NewTransactor(db)
+	})
+}
+
+// openDB opens a migrated database in a temporary directory, closed when the test ends
+func openDB(t *testing.T) *storm.DB {
+	db, err := storm.Open(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := repo.NewMigrator(db).Migrate(false); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
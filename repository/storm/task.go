@@ -1,16 +1,19 @@
 package storm
 
 import (
+	"math"
+	"strconv"
 	"time"
 
 	"github.com/asdine/storm/v3"
+	"github.com/asdine/storm/v3/q"
 
 	"github.com/ajaxray/geek-life/model"
 	"github.com/ajaxray/geek-life/repository"
 )
 
 type taskRepository struct {
-	DB *storm.DB
+	DB storm.Node
 }
 
 // NewTaskRepository will create an object that represent the repository.Task interface
@@ -19,41 +22,59 @@ func NewTaskRepository(db *storm.DB) repository.TaskRepository {
 }
 
 func (t *taskRepository) GetAll() ([]model.Task, error) {
-	panic("implement me")
+	return t.Find(repository.TaskQuery{})
 }
 
-func (t *taskRepository) GetAllByProject(project model.Project) ([]model.Task, error) {
+// Find loads candidates through the most selective storm index available for the query,
+// then leaves remaining criteria, ordering and pagination to TaskQuery.Apply.
+// Zero values are never indexed by storm, so pending or unscheduled tasks are scanned.
+func (t *taskRepository) Find(query repository.TaskQuery) ([]model.Task, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
 	var tasks []model.Task
-	//err = db.Find("ProjetID", project.ID, &tasks, storm.Limit(10), storm.Skip(10), storm.Reverse())
-	err := t.DB.Find("ProjectID", project.ID, &tasks)
+	var err error
+
+	switch {
+	case query.ParentID != 0:
+		err = t.DB.Find("ParentID", query.ParentID, &tasks)
+	case query.ProjectID != 0:
+		err = t.DB.Find("ProjectID", query.ProjectID, &tasks)
+	case query.Trash == repository.TrashedOnly:
+		err = t.DB.Range("DeletedAt", int64(1), int64(math.MaxInt64), &tasks)
+	case !query.RemindBy.IsZero():
+		err = t.DB.Range("RemindAt", int64(1), query.RemindBy.Unix(), &tasks)
+	case query.HasDueRange():
+		from, to := query.DueBounds()
+		err = t.DB.Range("DueDate", from, to, &tasks)
+	case query.Status == repository.StatusCompleted:
+		err = t.DB.Find("Completed", true, &tasks)
+	default:
+		err = t.DB.All(&tasks)
+	}
 
-	return tasks, err
+	if err = ignoreNotFound(err); err != nil {
+		return nil, err
+	}
+
+	return query.Apply(tasks), nil
 }
 
-func (t *taskRepository) GetAllByDate(date time.Time) ([]model.Task, error) {
-	var tasks []model.Task
+func (t *taskRepository) GetAllByProject(project model.Project) ([]model.Task, error) {
+	return t.Find(repository.TaskQuery{ProjectID: project.ID})
+}
 
+func (t *taskRepository) GetAllByDate(date time.Time) ([]model.Task, error) {
 	if date.IsZero() {
-		var allTasks []model.Task
-		err := t.DB.AllByIndex("ProjectID", &allTasks)
-		for _, t := range allTasks {
-			if t.DueDate == 0 {
-				tasks = append(tasks, t)
-			}
-		}
-
-		return tasks, err
-	} else {
-		err := t.DB.Find("DueDate", getRoundedDueDate(date), &tasks)
-		return tasks, err
+		return t.Find(repository.TaskQuery{Unscheduled: true, OrderBy: "ProjectID"})
 	}
+
+	return t.Find(repository.TaskQuery{DueFrom: date, DueTo: date})
 }
 
 func (t *taskRepository) GetAllByDateRange(from, to time.Time) ([]model.Task, error) {
-	var tasks []model.Task
-
-	err := t.DB.Range("DueDate", getRoundedDueDate(from), getRoundedDueDate(to), &tasks)
-	return tasks, err
+	return t.Find(repository.TaskQuery{DueFrom: from, DueTo: to, OrderBy: "DueDate"})
 }
 
 func (t *taskRepository) GetAllCompletedByDate(date time.Time) ([]model.Task, error) {
@@ -65,14 +86,14 @@ func (t *taskRepository) GetAllCompletedByDate(date time.Time) ([]model.Task, er
 	
 	// 查询在指定日期内完成的任务
 	err := t.DB.Range("CompletedAt", startOfDay, endOfDay, &tasks)
-	if err != nil {
+	if err = ignoreNotFound(err); err != nil {
 		return tasks, err
 	}
 	
 	// 过滤确保任务确实是已完成的
 	var completedTasks []model.Task
 	for _, task := range tasks {
-		if task.Completed && task.CompletedAt >= startOfDay && task.CompletedAt <= endOfDay {
+		if task.Completed && task.CompletedAt >= startOfDay && task.CompletedAt <= endOfDay && task.DeletedAt == 0 {
 			completedTasks = append(completedTasks, task)
 		}
 	}
@@ -80,43 +101,195 @@ func (t *taskRepository) GetAllCompletedByDate(date time.Time) ([]model.Task, er
 	return completedTasks, nil
 }
 
+// GetAllTags lists tags in use with number of tasks labeled by each
+func (t *taskRepository) GetAllTags() ([]repository.TagCount, error) {
+	tasks, err := t.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.CountTags(tasks), nil
+}
+
 func (t *taskRepository) GetByID(ID string) (model.Task, error) {
-	panic("implement me")
+	var task model.Task
+
+	id, err := strconv.ParseInt(ID, 10, 64)
+	if err != nil {
+		return task, err
+	}
+
+	err = t.DB.One("ID", id, &task)
+	return task, translateError(err)
 }
 
 func (t *taskRepository) GetByUUID(UUID string) (model.Task, error) {
-	panic("implement me")
+	var task model.Task
+	err := t.DB.One("UUID", UUID, &task)
+
+	return task, translateError(err)
 }
 
 func (t *taskRepository) Create(project model.Project, title, details, UUID string, dueDate int64) (model.Task, error) {
+	if UUID == "" {
+		UUID = repository.NewUUID()
+	}
+
+	now := time.Now().Unix()
 	task := model.Task{
 		ProjectID: project.ID,
 		Title:     title,
 		Details:   details,
 		UUID:      UUID,
 		DueDate:   dueDate,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 
-	err := t.DB.Save(&task)
+	err := inTransaction(t.DB, func(tx storm.Node) error {
+		if err := tx.Save(&task); err != nil {
+			return err
+		}
+
+		task.Position = task.ID
+		return tx.Save(&task)
+	})
+
 	return task, err
 }
 
+// Update replaces all fields of an existing task.
+// storm's own Update skips zero values, which would make it impossible to clear a field.
 func (t *taskRepository) Update(task *model.Task) error {
-	return t.DB.Update(task)
+	return translateError(inTransaction(t.DB, func(tx storm.Node) error {
+		var existing model.Task
+		if err := tx.One("ID", task.ID, &existing); err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		task.CreatedAt, task.UpdatedAt = existing.CreatedAt, now
+		if err := tx.Save(task); err != nil {
+			return err
+		}
+
+		return saveChanges(tx, repository.DiffTask(existing, *task, now)...)
+	}))
 }
 
 func (t *taskRepository) UpdateField(task *model.Task, field string, value interface{}) error {
-	return t.DB.UpdateField(task, field, value)
+	return translateError(inTransaction(t.DB, func(tx storm.Node) error {
+		var existing model.Task
+		if err := tx.One("ID", task.ID, &existing); err != nil {
+			return err
+		}
+
+		// Indexes are updated from the given struct, so the stored record is written instead of the caller's copy
+		updated := existing
+		if err := tx.UpdateField(&updated, field, value); err != nil {
+			return err
+		}
+		if err := tx.One("ID", task.ID, &updated); err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		if err := tx.UpdateField(&updated, "UpdatedAt", now); err != nil {
+			return err
+		}
+		task.UpdatedAt = now
+
+		if change, ok := repository.FieldChange(existing, field, value, now); ok {
+			return saveChanges(tx, change)
+		}
+		return nil
+	}))
 }
 
 func (t *taskRepository) Delete(task *model.Task) error {
-	return t.DB.DeleteStruct(task)
+	return translateError(inTransaction(t.DB, func(tx storm.Node) error {
+		if err := tx.DeleteStruct(task); err != nil {
+			return err
+		}
+
+		if err := ignoreNotFound(tx.Select(q.Eq("TaskID", task.ID)).Delete(new(model.TaskChange))); err != nil {
+			return err
+		}
+
+		return ignoreNotFound(tx.Select(q.Eq("TaskID", task.ID)).Delete(new(model.TimeEntry)))
+	}))
 }
 
-func getRoundedDueDate(date time.Time) int64 {
-	if date.IsZero() {
-		return 0
+// GetHistory lists changes of a task, oldest first
+func (t *taskRepository) GetHistory(task model.Task) ([]model.TaskChange, error) {
+	changes := []model.TaskChange{}
+	err := t.DB.Find("TaskID", task.ID, &changes)
+
+	return changes, ignoreNotFound(err)
+}
+
+// GetTimeEntries lists time entries of a task, oldest first
+func (t *taskRepository) GetTimeEntries(task model.Task) ([]model.TimeEntry, error) {
+	entries := []model.TimeEntry{}
+	err := t.DB.Find("TaskID", task.ID, &entries)
+
+	return entries, ignoreNotFound(err)
+}
+
+// GetRunningTimeEntry finds the time entry which is not ended yet.
+// Zero values are not indexed by storm, so it is matched by scanning.
+func (t *taskRepository) GetRunningTimeEntry() (model.TimeEntry, error) {
+	var entry model.TimeEntry
+	err := t.DB.Select(q.Eq("End", int64(0))).First(&entry)
+
+	return entry, translateError(err)
+}
+
+// SaveTimeEntry creates a time entry if it has no ID, or replaces the existing one
+func (t *taskRepository) SaveTimeEntry(entry *model.TimeEntry) error {
+	if entry.ID != 0 {
+		var existing model.TimeEntry
+		if err := t.DB.One("ID", entry.ID, &existing); err != nil {
+			return translateError(err)
+		}
+	}
+
+	return t.DB.Save(entry)
+}
+
+// GetSyncStates lists sync states of all tasks and projects, including the deleted ones
+func (t *taskRepository) GetSyncStates() ([]model.SyncState, error) {
+	states := []model.SyncState{}
+	err := t.DB.All(&states)
+
+	return states, ignoreNotFound(err)
+}
+
+// SaveSyncState creates the sync state of a task or project, or replaces the existing one
+func (t *taskRepository) SaveSyncState(state *model.SyncState) error {
+	return translateError(inTransaction(t.DB, func(tx storm.Node) error {
+		var existing model.SyncState
+		err := tx.Select(q.Eq("TaskID", state.TaskID), q.Eq("ProjectID", state.ProjectID)).First(&existing)
+		if err != nil && err != storm.ErrNotFound {
+			return err
+		}
+		state.ID = existing.ID
+
+		return tx.Save(state)
+	}))
+}
+
+func (t *taskRepository) DeleteSyncState(state model.SyncState) error {
+	return translateError(t.DB.Select(q.Eq("TaskID", state.TaskID), q.Eq("ProjectID", state.ProjectID)).Delete(new(model.SyncState)))
+}
+
+// saveChanges appends changes to the history of tasks
+func saveChanges(tx storm.Node, changes ...model.TaskChange) error {
+	for i := range changes {
+		if err := tx.Save(&changes[i]); err != nil {
+			return err
+		}
 	}
 
-	return date.Unix()
+	return nil
 }
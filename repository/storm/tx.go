@@ -0,0 +1,56 @@
+package storm
+
+import (
+	"github.com/asdine/storm/v3"
+
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// inTransaction runs fn in a writable transaction, which is committed only if fn succeeds
+func inTransaction(db storm.Node, fn func(tx storm.Node) error) error {
+	tx, err := db.Begin(true)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// joinedTx is a transaction shared by the repositories of a unit of work.
+// Their own transactions join it, leaving commit or rollback to the transactor.
+type joinedTx struct {
+	storm.Node
+}
+
+func (tx joinedTx) Begin(writable bool) (storm.Node, error) {
+	return tx, nil
+}
+
+func (tx joinedTx) Commit() error {
+	return nil
+}
+
+func (tx joinedTx) Rollback() error {
+	return nil
+}
+
+type transactor struct {
+	DB *storm.DB
+}
+
+// NewTransactor will create an object that represent the repository.Transactor interface
+func NewTransactor(db *storm.DB) repository.Transactor {
+	return &transactor{db}
+}
+
+func (t *transactor) Transaction(fn func(projects repository.ProjectRepository, tasks repository.TaskRepository) error) error {
+	return inTransaction(t.DB, func(tx storm.Node) error {
+		shared := joinedTx{tx}
+		return fn(&projectRepository{shared}, &taskRepository{shared})
+	})
+}
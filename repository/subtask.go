@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"github.com/ajaxray/geek-life/model"
+)
+
+// Descendants finds subtasks of a task in every depth, parents before their children.
+// Subtasks in trash are not included.
+func Descendants(repo TaskRepository, task model.Task) ([]model.Task, error) {
+	return descendants(repo, task, NotTrashed)
+}
+
+func descendants(repo TaskRepository, task model.Task, trash TrashFilter) ([]model.Task, error) {
+	var descendants []model.Task
+	visited := map[int64]bool{task.ID: true}
+
+	for queue := []int64{task.ID}; len(queue) > 0; queue = queue[1:] {
+		children, err := repo.Find(TaskQuery{ParentID: queue[0], Trash: trash})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, child := range children {
+			// Guards against a broken hierarchy, where a task is an ancestor of itself
+			if !visited[child.ID] {
+				visited[child.ID] = true
+				descendants = append(descendants, child)
+				queue = append(queue, child.ID)
+			}
+		}
+	}
+
+	return descendants, nil
+}
+
+// DeleteTree permanently deletes a task along with all of its descendants, including the trashed ones.
+// Returns IDs of the deleted tasks, even if it failed in the middle.
+func DeleteTree(repo TaskRepository, task model.Task) ([]int64, error) {
+	descendants, err := descendants(repo, task, TrashedOrNot)
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted []int64
+	// Children first, so that a failure never leaves orphan subtasks behind
+	for i := len(descendants) - 1; i >= 0; i-- {
+		if err := repo.Delete(&descendants[i]); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, descendants[i].ID)
+	}
+
+	if err := repo.Delete(&task); err != nil {
+		return deleted, err
+	}
+
+	return append(deleted, task.ID), nil
+}
+
+// DeleteTrees deletes a list of tasks along with their descendants.
+// Tasks already deleted as a descendant of a previous one are skipped.
+func DeleteTrees(repo TaskRepository, tasks []model.Task) ([]int64, error) {
+	var deleted []int64
+	isDeleted := make(map[int64]bool)
+
+	for _, task := range tasks {
+		if isDeleted[task.ID] {
+			continue
+		}
+
+		ids, err := DeleteTree(repo, task)
+		deleted = append(deleted, ids...)
+		if err != nil {
+			return deleted, err
+		}
+		for _, id := range ids {
+			isDeleted[id] = true
+		}
+	}
+
+	return deleted, nil
+}
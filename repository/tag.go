@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"sort"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// TagCount tells how many tasks are labeled with a tag
+type TagCount struct {
+	Tag   string
+	Tasks int
+}
+
+// CountTags counts tags used in a list of tasks, ordered by tag name
+func CountTags(tasks []model.Task) []TagCount {
+	counts := make(map[string]int)
+	for _, task := range tasks {
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Tasks: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+
+	return tags
+}
@@ -6,15 +6,28 @@ import (
 	"github.com/ajaxray/geek-life/model"
 )
 
-// TaskRepository interface defines methods of task data accessor
+// TaskRepository interface defines methods of task data accessor.
+// Tasks in trash are excluded from lists, unless asked with TaskQuery.Trash. GetByID and GetByUUID find them too.
+// Create generates a UUID for the task unless one is given, see NewUUID.
+// Time entries of a task are deleted along with it. GetRunningTimeEntry returns ErrNotFound if no timer is running.
+// Sync states outlive their tasks, so that a sync can tell the tasks deleted permanently. SaveSyncState replaces the state of the same task or project.
 type TaskRepository interface {
 	GetAll() ([]model.Task, error)
+	Find(query TaskQuery) ([]model.Task, error)
 	GetAllByProject(project model.Project) ([]model.Task, error)
 	GetAllByDate(date time.Time) ([]model.Task, error)
 	GetAllByDateRange(from, to time.Time) ([]model.Task, error)
 	GetAllCompletedByDate(date time.Time) ([]model.Task, error)
+	GetAllTags() ([]TagCount, error)
 	GetByID(ID string) (model.Task, error)
 	GetByUUID(UUID string) (model.Task, error)
+	GetHistory(t model.Task) ([]model.TaskChange, error)
+	GetTimeEntries(t model.Task) ([]model.TimeEntry, error)
+	GetRunningTimeEntry() (model.TimeEntry, error)
+	SaveTimeEntry(e *model.TimeEntry) error
+	GetSyncStates() ([]model.SyncState, error)
+	SaveSyncState(s *model.SyncState) error
+	DeleteSyncState(s model.SyncState) error
 	Create(project model.Project, title, details, UUID string, dueDate int64) (model.Task, error)
 	Update(t *model.Task) error
 	UpdateField(t *model.Task, field string, value interface{}) error
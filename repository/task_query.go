@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// TaskStatus filters tasks by their completion state
+type TaskStatus int
+
+// Possible values of TaskQuery.Status
+const (
+	StatusAny TaskStatus = iota
+	StatusPending
+	StatusCompleted
+)
+
+// TrashFilter selects tasks by whether they are in trash
+type TrashFilter int
+
+// Possible values of TaskQuery.Trash
+const (
+	NotTrashed TrashFilter = iota
+	TrashedOnly
+	TrashedOrNot
+)
+
+// TaskQuery combines the criteria of a task search.
+// Zero value of every field means "no constraint", so TaskQuery{} selects all tasks ordered by ID.
+type TaskQuery struct {
+	ProjectID     int64       // Only tasks of this project
+	ParentID      int64       // Only subtasks of this task
+	DueFrom       time.Time   // Only tasks due on or after this date
+	DueTo         time.Time   // Only tasks due on or before this date
+	Unscheduled   bool        // Only tasks without due date. Ignores DueFrom and DueTo
+	RemindBy      time.Time   // Only tasks with a pending reminder at or before this time
+	AvailableBy   time.Time   // Only tasks not deferred beyond this time, i,e, without start date or starting by then
+	Status        TaskStatus  // Pending or completed tasks
+	Text          string      // Case-insensitive match in Title or Details
+	Tag           string      // Only tasks labeled with this tag
+	Trash         TrashFilter // Trashed tasks are excluded by default
+	OrderBy       string      // Name of the Task field to sort by. Default is "ID"
+	Reverse       bool        // Sort in descending order
+	PriorityFirst bool        // Sort by priority (highest first) before OrderBy
+	Limit         int         // Maximum number of tasks to return. 0 means unlimited
+	Offset        int         // Number of matching tasks to skip
+}
+
+var taskOrderings = map[string]func(a, b *model.Task) bool{
+	"ID":          func(a, b *model.Task) bool { return a.ID < b.ID },
+	"ProjectID":   func(a, b *model.Task) bool { return a.ProjectID < b.ProjectID },
+	"Title":       func(a, b *model.Task) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) },
+	"DueDate":     func(a, b *model.Task) bool { return a.DueDate < b.DueDate },
+	"CompletedAt": func(a, b *model.Task) bool { return a.CompletedAt < b.CompletedAt },
+	"Priority":    func(a, b *model.Task) bool { return a.Priority < b.Priority },
+	"Position":    func(a, b *model.Task) bool { return a.Position < b.Position },
+	"RemindAt":    func(a, b *model.Task) bool { return a.RemindAt < b.RemindAt },
+}
+
+// Validate checks if the query can be executed
+func (q TaskQuery) Validate() error {
+	if _, ok := taskOrderings[q.orderBy()]; !ok {
+		return ErrUnknownOrderField
+	}
+
+	return nil
+}
+
+// HasDueRange tells if the query limits tasks by a range of due dates
+func (q TaskQuery) HasDueRange() bool {
+	return !q.Unscheduled && (!q.DueFrom.IsZero() || !q.DueTo.IsZero())
+}
+
+// DueBounds provides the (inclusive) range of due date timestamps covered by the query.
+// Bounds are widened to whole days, from the start of DueFrom's day to the end of DueTo's day.
+func (q TaskQuery) DueBounds() (from, to int64) {
+	from, to = 1, math.MaxInt64
+	if !q.DueFrom.IsZero() {
+		from = RoundDueDate(q.DueFrom)
+	}
+	if !q.DueTo.IsZero() {
+		to = EndOfDay(q.DueTo)
+	}
+
+	return from, to
+}
+
+// Matches checks if a task satisfies the filtering criteria of the query
+func (q TaskQuery) Matches(task model.Task) bool {
+	if q.ProjectID != 0 && task.ProjectID != q.ProjectID {
+		return false
+	}
+
+	if q.ParentID != 0 && task.ParentID != q.ParentID {
+		return false
+	}
+
+	if q.Unscheduled {
+		if task.DueDate != 0 {
+			return false
+		}
+	} else if q.HasDueRange() {
+		from, to := q.DueBounds()
+		if task.DueDate < from || task.DueDate > to {
+			return false
+		}
+	}
+
+	if !q.RemindBy.IsZero() && (task.RemindAt == 0 || task.RemindAt > q.RemindBy.Unix()) {
+		return false
+	}
+
+	if !q.AvailableBy.IsZero() && task.StartDate > q.AvailableBy.Unix() {
+		return false
+	}
+
+	switch q.Status {
+	case StatusPending:
+		if task.Completed {
+			return false
+		}
+	case StatusCompleted:
+		if !task.Completed {
+			return false
+		}
+	}
+
+	switch q.Trash {
+	case NotTrashed:
+		if task.DeletedAt != 0 {
+			return false
+		}
+	case TrashedOnly:
+		if task.DeletedAt == 0 {
+			return false
+		}
+	}
+
+	if q.Tag != "" && !task.HasTag(q.Tag) {
+		return false
+	}
+
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(task.Title), text) && !strings.Contains(strings.ToLower(task.Details), text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Apply filters, sorts and paginates a list of candidate tasks according to the query.
+// Backends that can not express every criterion natively use it to finish the job.
+func (q TaskQuery) Apply(tasks []model.Task) []model.Task {
+	result := make([]model.Task, 0, len(tasks))
+	for _, task := range tasks {
+		if q.Matches(task) {
+			result = append(result, task)
+		}
+	}
+
+	q.Sort(result)
+
+	return q.Paginate(result)
+}
+
+// Sort orders tasks by the query's OrderBy field, after priority if PriorityFirst is set.
+// Ties are broken by manual position, then by ID to keep results stable.
+func (q TaskQuery) Sort(tasks []model.Task) {
+	less := taskOrderings[q.orderBy()]
+	sort.SliceStable(tasks, func(i, j int) bool {
+		a, b := &tasks[i], &tasks[j]
+		if q.PriorityFirst && a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if q.Reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		if a.Position != b.Position {
+			return a.Position < b.Position
+		}
+
+		return a.ID < b.ID
+	})
+}
+
+// Paginate applies Offset and Limit of the query on a sorted list of tasks
+func (q TaskQuery) Paginate(tasks []model.Task) []model.Task {
+	if q.Offset > 0 {
+		if q.Offset >= len(tasks) {
+			return tasks[:0]
+		}
+		tasks = tasks[q.Offset:]
+	}
+
+	if q.Limit > 0 && q.Limit < len(tasks) {
+		tasks = tasks[:q.Limit]
+	}
+
+	return tasks
+}
+
+func (q TaskQuery) orderBy() string {
+	if q.OrderBy == "" {
+		return "ID"
+	}
+
+	return q.OrderBy
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// StartTimer starts tracking time spent on a task, stopping the timer running for another task.
+// If the timer of the task is running already, it keeps running.
+func StartTimer(repo TaskRepository, task model.Task, at time.Time) (model.TimeEntry, error) {
+	running, err := repo.GetRunningTimeEntry()
+	switch {
+	case err == nil && running.TaskID == task.ID:
+		return running, nil
+	case err == nil:
+		if _, err := StopTimer(repo, at); err != nil {
+			return running, err
+		}
+	case err != ErrNotFound:
+		return running, err
+	}
+
+	entry := model.TimeEntry{TaskID: task.ID, Start: at.Unix()}
+	return entry, repo.SaveTimeEntry(&entry)
+}
+
+// StopTimer stops the running timer and provides its entry. Returns ErrNotFound if no timer is running.
+func StopTimer(repo TaskRepository, at time.Time) (model.TimeEntry, error) {
+	running, err := repo.GetRunningTimeEntry()
+	if err != nil {
+		return running, err
+	}
+
+	running.End = at.Unix()
+	if running.End < running.Start {
+		running.End = running.Start
+	}
+
+	return running, repo.SaveTimeEntry(&running)
+}
+
+// TrackedTime sums up the time of entries, counting running ones up to now
+func TrackedTime(entries []model.TimeEntry, now time.Time) time.Duration {
+	var seconds int64
+	for _, entry := range entries {
+		end := entry.End
+		if end == 0 {
+			end = now.Unix()
+		}
+		if end > entry.Start {
+			seconds += end - entry.Start
+		}
+	}
+
+	return time.Duration(seconds) * time.Second
+}
+
+// TasksTrackedTime sums up the time tracked on a list of tasks, counting running timers up to now
+func TasksTrackedTime(repo TaskRepository, tasks []model.Task, now time.Time) (time.Duration, error) {
+	var total time.Duration
+	for _, task := range tasks {
+		entries, err := repo.GetTimeEntries(task)
+		if err != nil {
+			return total, err
+		}
+		total += TrackedTime(entries, now)
+	}
+
+	return total, nil
+}
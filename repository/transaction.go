@@ -0,0 +1,10 @@
+package repository
+
+// Transactor interface runs several changes of projects and tasks as one unit of work.
+// Transaction commits the changes made through the given repositories only if fn returns nil, otherwise none of them are kept.
+// Those repositories are bound to the transaction, so they must not be used after fn returns.
+// Storage may be locked while fn runs, so fn should use only them, and not start another transaction.
+// Multi-step helpers like TrashProject or DeleteTree should be run in a transaction to be atomic.
+type Transactor interface {
+	Transaction(fn func(projects ProjectRepository, tasks TaskRepository) error) error
+}
@@ -0,0 +1,255 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+)
+
+// TrashTree moves a task along with its descendants to trash.
+// Returns IDs of the trashed tasks, even if it failed in the middle.
+func TrashTree(repo TaskRepository, task model.Task) ([]int64, error) {
+	descendants, err := Descendants(repo, task)
+	if err != nil {
+		return nil, err
+	}
+
+	return trash(repo, append([]model.Task{task}, descendants...), time.Now().Unix())
+}
+
+// TrashTrees moves a list of tasks along with their descendants to trash.
+// Tasks already trashed as a descendant of a previous one are skipped.
+func TrashTrees(repo TaskRepository, tasks []model.Task) ([]int64, error) {
+	var trashed []int64
+	isTrashed := make(map[int64]bool)
+
+	for _, task := range tasks {
+		if isTrashed[task.ID] {
+			continue
+		}
+
+		ids, err := TrashTree(repo, task)
+		trashed = append(trashed, ids...)
+		if err != nil {
+			return trashed, err
+		}
+		for _, id := range ids {
+			isTrashed[id] = true
+		}
+	}
+
+	return trashed, nil
+}
+
+// TrashProject moves a project to trash along with its sub-projects and their tasks
+func TrashProject(projects ProjectRepository, tasks TaskRepository, project *model.Project) error {
+	descendants, err := ProjectDescendants(projects, *project)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for i := range descendants {
+		if err := trashProject(projects, tasks, &descendants[i], now); err != nil {
+			return err
+		}
+	}
+
+	return trashProject(projects, tasks, project, now)
+}
+
+func trashProject(projects ProjectRepository, tasks TaskRepository, project *model.Project, at int64) error {
+	projectTasks, err := tasks.Find(TaskQuery{ProjectID: project.ID})
+	if err != nil {
+		return err
+	}
+	if _, err := trash(tasks, projectTasks, at); err != nil {
+		return err
+	}
+
+	if err := projects.UpdateField(project, "DeletedAt", at); err != nil {
+		return err
+	}
+	project.DeletedAt = at
+
+	return nil
+}
+
+// RestoreTree takes a task out of trash, along with the descendants trashed together with it
+func RestoreTree(repo TaskRepository, task model.Task) error {
+	descendants, err := descendants(repo, task, TrashedOrNot)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range append([]model.Task{task}, descendants...) {
+		if t.DeletedAt == task.DeletedAt {
+			if err := repo.UpdateField(&t, "DeletedAt", int64(0)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestoreProject takes a project out of trash, along with the sub-projects and tasks trashed together with it
+func RestoreProject(projects ProjectRepository, tasks TaskRepository, project *model.Project) error {
+	trashed, err := projects.GetTrashed()
+	if err != nil {
+		return err
+	}
+
+	for _, subProject := range ProjectSubtree(trashed, project.ID) {
+		if subProject.DeletedAt == project.DeletedAt {
+			if err := restoreProject(projects, tasks, &subProject); err != nil {
+				return err
+			}
+		}
+	}
+
+	return restoreProject(projects, tasks, project)
+}
+
+func restoreProject(projects ProjectRepository, tasks TaskRepository, project *model.Project) error {
+	trashed, err := tasks.Find(TaskQuery{ProjectID: project.ID, Trash: TrashedOnly})
+	if err != nil {
+		return err
+	}
+
+	for _, task := range trashed {
+		if task.DeletedAt == project.DeletedAt {
+			if err := tasks.UpdateField(&task, "DeletedAt", int64(0)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := projects.UpdateField(project, "DeletedAt", int64(0)); err != nil {
+		return err
+	}
+	project.DeletedAt = 0
+
+	return nil
+}
+
+// DeleteProject permanently deletes a project along with all of its tasks, including the trashed ones.
+// Sub-projects trashed together with it are deleted too, other sub-projects are moved to top level.
+// Returns IDs of the deleted tasks, even if it failed in the middle.
+func DeleteProject(projects ProjectRepository, tasks TaskRepository, project model.Project) ([]int64, error) {
+	trashed, err := projects.GetTrashed()
+	if err != nil {
+		return nil, err
+	}
+
+	var subProjects []model.Project
+	for _, subProject := range ProjectSubtree(trashed, project.ID) {
+		if project.DeletedAt != 0 && subProject.DeletedAt == project.DeletedAt {
+			subProjects = append(subProjects, subProject)
+		}
+	}
+
+	var deleted []int64
+	isDeleted := map[int64]bool{project.ID: true}
+	// Children first, so that a failure never leaves orphan sub-projects behind
+	for i := len(subProjects) - 1; i >= 0; i-- {
+		ids, err := deleteProject(projects, tasks, subProjects[i])
+		deleted = append(deleted, ids...)
+		if err != nil {
+			return deleted, err
+		}
+		isDeleted[subProjects[i].ID] = true
+	}
+
+	ids, err := deleteProject(projects, tasks, project)
+	deleted = append(deleted, ids...)
+	if err != nil {
+		return deleted, err
+	}
+
+	remaining, err := projects.GetAll()
+	if err != nil {
+		return deleted, err
+	}
+	for _, other := range append(remaining, trashed...) {
+		if isDeleted[other.ParentID] && !isDeleted[other.ID] {
+			if err := projects.UpdateField(&other, "ParentID", int64(0)); err != nil {
+				return deleted, err
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+func deleteProject(projects ProjectRepository, tasks TaskRepository, project model.Project) ([]int64, error) {
+	projectTasks, err := tasks.Find(TaskQuery{ProjectID: project.ID, Trash: TrashedOrNot})
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := DeleteTrees(tasks, projectTasks)
+	if err != nil {
+		return deleted, err
+	}
+
+	return deleted, projects.Delete(&project)
+}
+
+// PurgeTrash permanently deletes projects and tasks which were moved to trash before given time.
+// Returns the number of deleted projects and tasks.
+func PurgeTrash(projects ProjectRepository, tasks TaskRepository, before time.Time) (int, error) {
+	purged := 0
+
+	trashedProjects, err := projects.GetTrashed()
+	if err != nil {
+		return purged, err
+	}
+	for _, project := range trashedProjects {
+		if project.DeletedAt < before.Unix() {
+			// Already deleted along with its parent project
+			if _, err := projects.GetByID(project.ID); err == ErrNotFound {
+				purged++
+				continue
+			}
+
+			deleted, err := DeleteProject(projects, tasks, project)
+			purged += len(deleted)
+			if err != nil {
+				return purged, err
+			}
+			purged++
+		}
+	}
+
+	trashedTasks, err := tasks.Find(TaskQuery{Trash: TrashedOnly})
+	if err != nil {
+		return purged, err
+	}
+	var expired []model.Task
+	for _, task := range trashedTasks {
+		if task.DeletedAt < before.Unix() {
+			expired = append(expired, task)
+		}
+	}
+	deleted, err := DeleteTrees(tasks, expired)
+
+	return purged + len(deleted), err
+}
+
+// trash marks tasks as trashed at given time, skipping the ones already in trash
+func trash(repo TaskRepository, tasks []model.Task, at int64) ([]int64, error) {
+	var trashed []int64
+	for _, task := range tasks {
+		if task.DeletedAt != 0 {
+			continue
+		}
+
+		if err := repo.UpdateField(&task, "DeletedAt", at); err != nil {
+			return trashed, err
+		}
+		trashed = append(trashed, task.ID)
+	}
+
+	return trashed, nil
+}
@@ -0,0 +1,10 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+)
+
+// NewUUID generates a random UUID, identifying a project or task across databases
+func NewUUID() string {
+	return uuid.NewString()
+}
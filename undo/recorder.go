@@ -0,0 +1,79 @@
+package undo
+
+import (
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+// projectRecorder is a repository.ProjectRepository recording changes to the action being recorded
+type projectRecorder struct {
+	repository.ProjectRepository
+	manager *Manager
+}
+
+func (r *projectRecorder) Create(title, UUID string) (model.Project, error) {
+	project, err := r.ProjectRepository.Create(title, UUID)
+	if err == nil {
+		r.manager.recordProject(r.ProjectRepository, project.ID, true)
+	}
+
+	return project, err
+}
+
+func (r *projectRecorder) Update(project *model.Project) error {
+	r.manager.recordProject(r.ProjectRepository, project.ID, false)
+	return r.ProjectRepository.Update(project)
+}
+
+func (r *projectRecorder) UpdateField(project *model.Project, field string, value interface{}) error {
+	r.manager.recordProject(r.ProjectRepository, project.ID, false)
+	return r.ProjectRepository.UpdateField(project, field, value)
+}
+
+func (r *projectRecorder) Delete(project *model.Project) error {
+	r.manager.recordDeletion()
+	return r.ProjectRepository.Delete(project)
+}
+
+// taskRecorder is a repository.TaskRepository recording changes to the action being recorded
+type taskRecorder struct {
+	repository.TaskRepository
+	manager *Manager
+}
+
+func (r *taskRecorder) Create(project model.Project, title, details, UUID string, dueDate int64) (model.Task, error) {
+	task, err := r.TaskRepository.Create(project, title, details, UUID, dueDate)
+	if err == nil {
+		r.manager.recordTask(r.TaskRepository, task.ID, true)
+	}
+
+	return task, err
+}
+
+func (r *taskRecorder) Update(task *model.Task) error {
+	r.manager.recordTask(r.TaskRepository, task.ID, false)
+	return r.TaskRepository.Update(task)
+}
+
+func (r *taskRecorder) UpdateField(task *model.Task, field string, value interface{}) error {
+	r.manager.recordTask(r.TaskRepository, task.ID, false)
+	return r.TaskRepository.UpdateField(task, field, value)
+}
+
+func (r *taskRecorder) Delete(task *model.Task) error {
+	r.manager.recordDeletion()
+	return r.TaskRepository.Delete(task)
+}
+
+// transactionRecorder is a repository.Transactor recording changes made in its transactions to the action being recorded.
+// Records are read through the repositories of the transaction, as storage may be locked by it.
+type transactionRecorder struct {
+	repository.Transactor
+	manager *Manager
+}
+
+func (r *transactionRecorder) Transaction(fn func(projects repository.ProjectRepository, tasks repository.TaskRepository) error) error {
+	return r.Transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		return fn(&projectRecorder{projects, r.manager}, &taskRecorder{tasks, r.manager})
+	})
+}
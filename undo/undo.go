@@ -0,0 +1,366 @@
+// Package undo records changes made to projects and tasks as user actions, to undo and redo them.
+//
+// A Manager wraps the repositories and the transactor of the app. Changes made through the wrapped
+// repositories between Begin and End are recorded as one Action, by keeping the state of every touched record
+// before and after the action. Undoing an action puts the fields it changed back to their values before it,
+// so that changes made to other fields without being recorded (like counting pomodoros) are kept.
+// Records created by the action are moved to trash instead, so that redoing it can bring them back
+// with the same IDs. They are deleted for good once the action can not be redone anymore,
+// unless the user restored them from trash meanwhile.
+// Changes rolled back by a transaction are left out of the action.
+//
+// Permanent deletion can not be undone. An action deleting records clears the undo history.
+package undo
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+)
+
+var (
+	// ErrNothingToUndo is returned by Undo when no action has been recorded
+	ErrNothingToUndo = errors.New("nothing to undo")
+
+	// ErrNothingToRedo is returned by Redo when no action has been undone since the last recorded one
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// Action is a set of changes made by a user action
+type Action struct {
+	Description string
+	projects    []*projectChange
+	tasks       []*taskChange
+}
+
+type projectChange struct {
+	before, after model.Project
+	created       bool
+}
+
+type taskChange struct {
+	before, after model.Task
+	created       bool
+}
+
+// Manager keeps the list of recorded actions to undo and redo
+type Manager struct {
+	projects   repository.ProjectRepository
+	tasks      repository.TaskRepository
+	transactor repository.Transactor
+	limit      int
+
+	current *Action
+	depth   int
+	deleted bool
+	done    []*Action
+	undone  []*Action
+}
+
+// New makes a Manager keeping up to limit actions, recording changes made through repositories it provides.
+// Actions are undone and redone in transactions of transactor.
+func New(projects repository.ProjectRepository, tasks repository.TaskRepository, transactor repository.Transactor, limit int) *Manager {
+	return &Manager{projects: projects, tasks: tasks, transactor: transactor, limit: limit}
+}
+
+// Projects provides the project repository recording changes of actions
+func (m *Manager) Projects() repository.ProjectRepository {
+	return &projectRecorder{m.projects, m}
+}
+
+// Tasks provides the task repository recording changes of actions
+func (m *Manager) Tasks() repository.TaskRepository {
+	return &taskRecorder{m.tasks, m}
+}
+
+// Transactor provides the transactor whose repositories record changes of actions, like Projects and Tasks do
+func (m *Manager) Transactor() repository.Transactor {
+	return &transactionRecorder{m.transactor, m}
+}
+
+// Begin starts recording an action. Actions started while recording are part of the outer one.
+func (m *Manager) Begin(description string) {
+	if m.depth == 0 {
+		m.current = &Action{Description: description}
+		m.deleted = false
+	}
+	m.depth++
+}
+
+// End finishes recording the action, which is kept to be undone if it changed anything
+func (m *Manager) End() {
+	if m.depth == 0 {
+		return
+	}
+	if m.depth--; m.depth > 0 {
+		return
+	}
+
+	action := m.current
+	m.current = nil
+
+	if m.deleted {
+		m.discard(m.undone)
+		m.done, m.undone = nil, nil
+		return
+	}
+
+	var projects []*projectChange
+	for _, change := range action.projects {
+		if project, err := m.projects.GetByID(change.before.ID); err == nil {
+			change.after = project
+		} else if change.created {
+			// Creation was rolled back
+			continue
+		}
+		if change.created || !sameProject(change.before, change.after) {
+			projects = append(projects, change)
+		}
+	}
+	var tasks []*taskChange
+	for _, change := range action.tasks {
+		if task, err := m.tasks.GetByID(strconv.FormatInt(change.before.ID, 10)); err == nil {
+			change.after = task
+		} else if change.created {
+			continue
+		}
+		if change.created || !sameTask(change.before, change.after) {
+			tasks = append(tasks, change)
+		}
+	}
+
+	// Nothing changed actually
+	if len(projects) == 0 && len(tasks) == 0 {
+		return
+	}
+	action.projects, action.tasks = projects, tasks
+
+	m.discard(m.undone)
+	m.undone = nil
+	m.done = append(m.done, action)
+	if m.limit > 0 && len(m.done) > m.limit {
+		m.done = m.done[len(m.done)-m.limit:]
+	}
+}
+
+// Undo reverts the last recorded action and returns its description.
+// The action is reverted as a whole, or kept to be undone again if it fails.
+func (m *Manager) Undo() (string, error) {
+	if len(m.done) == 0 {
+		return "", ErrNothingToUndo
+	}
+
+	action := m.done[len(m.done)-1]
+	if err := m.transactor.Transaction(action.revert); err != nil {
+		return action.Description, err
+	}
+
+	m.done = m.done[:len(m.done)-1]
+	m.undone = append(m.undone, action)
+
+	return action.Description, nil
+}
+
+// Redo applies the last undone action again and returns its description.
+// The action is applied as a whole, or kept to be redone again if it fails.
+func (m *Manager) Redo() (string, error) {
+	if len(m.undone) == 0 {
+		return "", ErrNothingToRedo
+	}
+
+	action := m.undone[len(m.undone)-1]
+	if err := m.transactor.Transaction(action.apply); err != nil {
+		return action.Description, err
+	}
+
+	m.undone = m.undone[:len(m.undone)-1]
+	m.done = append(m.done, action)
+
+	return action.Description, nil
+}
+
+// revert puts fields changed by the action back to their values before it, trashing the created records
+func (action *Action) revert(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+	now := time.Now().Unix()
+	for i := len(action.tasks) - 1; i >= 0; i-- {
+		change := action.tasks[i]
+		if change.created {
+			task := change.after
+			if err := tasks.UpdateField(&task, "DeletedAt", now); err != nil {
+				return err
+			}
+		} else if err := putTask(tasks, change.after, change.before); err != nil {
+			return err
+		}
+	}
+	for i := len(action.projects) - 1; i >= 0; i-- {
+		change := action.projects[i]
+		if change.created {
+			project := change.after
+			if err := projects.UpdateField(&project, "DeletedAt", now); err != nil {
+				return err
+			}
+		} else if err := putProject(projects, change.after, change.before); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// apply puts fields changed by the action to their values after it, bringing the created records back from trash
+func (action *Action) apply(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+	for _, change := range action.projects {
+		if change.created {
+			project := change.after
+			if err := projects.UpdateField(&project, "DeletedAt", int64(0)); err != nil {
+				return err
+			}
+		} else if err := putProject(projects, change.before, change.after); err != nil {
+			return err
+		}
+	}
+	for _, change := range action.tasks {
+		if change.created {
+			task := change.after
+			if err := tasks.UpdateField(&task, "DeletedAt", int64(0)); err != nil {
+				return err
+			}
+		} else if err := putTask(tasks, change.before, change.after); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putProject moves a project from state from to state to.
+// Only the fields which differ between the states are written, keeping changes made to others without being recorded.
+func putProject(projects repository.ProjectRepository, from, to model.Project) error {
+	project, err := projects.GetByID(to.ID)
+	if err != nil {
+		return err
+	}
+	setChanged(&project, from, to)
+
+	return projects.Update(&project)
+}
+
+// putTask moves a task from state from to state to, like putProject does for projects
+func putTask(tasks repository.TaskRepository, from, to model.Task) error {
+	task, err := tasks.GetByID(strconv.FormatInt(to.ID, 10))
+	if err != nil {
+		return err
+	}
+	setChanged(&task, from, to)
+
+	return tasks.Update(&task)
+}
+
+// setChanged sets the fields of record (a pointer to struct) which differ between states from and to, to their values in to
+func setChanged(record, from, to interface{}) {
+	current, before, after := reflect.ValueOf(record).Elem(), reflect.ValueOf(from), reflect.ValueOf(to)
+	for i := 0; i < current.NumField(); i++ {
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			current.Field(i).Set(after.Field(i))
+		}
+	}
+}
+
+// discard deletes records created by actions which can not be redone anymore.
+// Records the user has restored from trash meanwhile are kept, along with projects holding restored tasks.
+func (m *Manager) discard(actions []*Action) {
+	_ = m.transactor.Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		for _, action := range actions {
+			for _, change := range action.tasks {
+				if !change.created {
+					continue
+				}
+				if task, err := tasks.GetByID(strconv.FormatInt(change.after.ID, 10)); err == nil && task.DeletedAt != 0 {
+					_ = tasks.Delete(&task)
+				}
+			}
+			for _, change := range action.projects {
+				if !change.created {
+					continue
+				}
+				project, err := projects.GetByID(change.after.ID)
+				if err != nil || project.DeletedAt == 0 {
+					continue
+				}
+				if live, err := tasks.Find(repository.TaskQuery{ProjectID: project.ID}); err == nil && len(live) == 0 {
+					_ = projects.Delete(&project)
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// recordProject keeps the state of a project before its first change in current action, reading it through repo
+func (m *Manager) recordProject(repo repository.ProjectRepository, id int64, created bool) {
+	if m.current == nil {
+		return
+	}
+	for _, change := range m.current.projects {
+		if change.before.ID == id {
+			return
+		}
+	}
+
+	change := &projectChange{created: created}
+	if created {
+		change.before.ID = id
+	} else if project, err := repo.GetByID(id); err == nil {
+		change.before = project
+	} else {
+		return
+	}
+	m.current.projects = append(m.current.projects, change)
+}
+
+// recordTask keeps the state of a task before its first change in current action, reading it through repo
+func (m *Manager) recordTask(repo repository.TaskRepository, id int64, created bool) {
+	if m.current == nil {
+		return
+	}
+	for _, change := range m.current.tasks {
+		if change.before.ID == id {
+			return
+		}
+	}
+
+	change := &taskChange{created: created}
+	if created {
+		change.before.ID = id
+	} else if task, err := repo.GetByID(strconv.FormatInt(id, 10)); err == nil {
+		change.before = task
+	} else {
+		return
+	}
+	m.current.tasks = append(m.current.tasks, change)
+}
+
+// sameProject tells if two states of a project are same, apart from the time of last update
+func sameProject(a, b model.Project) bool {
+	a.UpdatedAt = b.UpdatedAt
+	return reflect.DeepEqual(a, b)
+}
+
+// sameTask tells if two states of a task are same, apart from the time of last update
+func sameTask(a, b model.Task) bool {
+	a.UpdatedAt = b.UpdatedAt
+	return reflect.DeepEqual(a, b)
+}
+
+// recordDeletion notes that current action deleted a record permanently
+func (m *Manager) recordDeletion() {
+	if m.current != nil {
+		m.deleted = true
+	}
+}
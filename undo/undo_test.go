@@ -0,0 +1,257 @@
+package undo_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/ajaxray/geek-life/model"
+	"github.com/ajaxray/geek-life/repository"
+	"github.com/ajaxray/geek-life/repository/memory"
+	"github.com/ajaxray/geek-life/undo"
+)
+
+func newManager(t *testing.T, limit int) (*undo.Manager, model.Project) {
+	store := memory.NewStore()
+	manager := undo.New(memory.NewProjectRepository(store), memory.NewTaskRepository(store), memory.NewTransactor(store), limit)
+
+	project, err := manager.Projects().Create("Inbox", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager, project
+}
+
+// do records fn as an action, failing the test on error
+func do(t *testing.T, manager *undo.Manager, description string, fn func() error) {
+	t.Helper()
+
+	manager.Begin(description)
+	defer manager.End()
+	if err := fn(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func getTask(t *testing.T, manager *undo.Manager, id int64) (model.Task, bool) {
+	t.Helper()
+
+	task, err := manager.Tasks().GetByID(strconv.FormatInt(id, 10))
+	if errors.Is(err, repository.ErrNotFound) {
+		return task, false
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return task, true
+}
+
+func TestUndoRedoUpdate(t *testing.T) {
+	manager, project := newManager(t, 0)
+	task, _ := manager.Tasks().Create(project, "Draft", "", "", 0)
+
+	do(t, manager, "Rename task", func() error {
+		return manager.Tasks().UpdateField(&task, "Title", "Final")
+	})
+
+	description, err := manager.Undo()
+	if err != nil || description != "Rename task" {
+		t.Fatalf("Undo() = %q, %v", description, err)
+	}
+	if got, _ := getTask(t, manager, task.ID); got.Title != "Draft" {
+		t.Errorf("Title after undo = %q, want Draft", got.Title)
+	}
+	if _, err := manager.Undo(); !errors.Is(err, undo.ErrNothingToUndo) {
+		t.Errorf("second Undo() error = %v, want ErrNothingToUndo", err)
+	}
+
+	if description, err = manager.Redo(); err != nil || description != "Rename task" {
+		t.Fatalf("Redo() = %q, %v", description, err)
+	}
+	if got, _ := getTask(t, manager, task.ID); got.Title != "Final" {
+		t.Errorf("Title after redo = %q, want Final", got.Title)
+	}
+	if _, err := manager.Redo(); !errors.Is(err, undo.ErrNothingToRedo) {
+		t.Errorf("second Redo() error = %v, want ErrNothingToRedo", err)
+	}
+}
+
+// Changes made outside of actions, like counting pomodoros or snoozing a reminder, survive undoing and redoing
+func TestUnrecordedChangesAreKept(t *testing.T) {
+	manager, project := newManager(t, 0)
+	task, _ := manager.Tasks().Create(project, "Draft", "", "", 0)
+
+	do(t, manager, "Rename task", func() error {
+		return manager.Tasks().UpdateField(&task, "Title", "Final")
+	})
+	if err := manager.Tasks().UpdateField(&task, "Pomodoros", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := manager.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := getTask(t, manager, task.ID); got.Title != "Draft" || got.Pomodoros != 2 {
+		t.Errorf("after undo = %q with %d pomodoros, want Draft with 2", got.Title, got.Pomodoros)
+	}
+
+	if err := manager.Tasks().UpdateField(&task, "RemindAt", int64(1700000000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.Redo(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := getTask(t, manager, task.ID); got.Title != "Final" || got.Pomodoros != 2 || got.RemindAt != 1700000000 {
+		t.Errorf("after redo = %q with %d pomodoros, reminder at %d, want Final with 2, at 1700000000", got.Title, got.Pomodoros, got.RemindAt)
+	}
+}
+
+func TestUndoRedoCreate(t *testing.T) {
+	manager, project := newManager(t, 0)
+
+	var task model.Task
+	do(t, manager, "Add task", func() (err error) {
+		task, err = manager.Tasks().Create(project, "Write tests", "", "", 0)
+		return err
+	})
+
+	if _, err := manager.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := getTask(t, manager, task.ID); !ok || got.DeletedAt == 0 {
+		t.Fatalf("undone task = %+v, %v, want it in trash", got, ok)
+	}
+
+	if _, err := manager.Redo(); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := getTask(t, manager, task.ID); !ok || got.DeletedAt != 0 || got.Title != "Write tests" {
+		t.Errorf("redone task = %+v, %v, want it back with same ID", got, ok)
+	}
+}
+
+func TestUnchangedActionIsNotKept(t *testing.T) {
+	manager, project := newManager(t, 0)
+	task, _ := manager.Tasks().Create(project, "Same", "", "", 0)
+
+	do(t, manager, "Rename task", func() error {
+		return manager.Tasks().UpdateField(&task, "Title", "Same")
+	})
+
+	if _, err := manager.Undo(); !errors.Is(err, undo.ErrNothingToUndo) {
+		t.Errorf("Undo() error = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestNewActionDiscardsUndone(t *testing.T) {
+	manager, project := newManager(t, 0)
+
+	var task model.Task
+	do(t, manager, "Add task", func() (err error) {
+		task, err = manager.Tasks().Create(project, "Temporary", "", "", 0)
+		return err
+	})
+	if _, err := manager.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	do(t, manager, "Rename project", func() error {
+		return manager.Projects().UpdateField(&project, "Title", "Renamed")
+	})
+
+	if _, err := manager.Redo(); !errors.Is(err, undo.ErrNothingToRedo) {
+		t.Errorf("Redo() error = %v, want ErrNothingToRedo", err)
+	}
+	if got, ok := getTask(t, manager, task.ID); ok {
+		t.Errorf("task of discarded action = %+v, want it deleted", got)
+	}
+}
+
+func TestDiscardKeepsRestoredRecords(t *testing.T) {
+	manager, _ := newManager(t, 0)
+
+	var project model.Project
+	var task model.Task
+	do(t, manager, "Add project with task", func() (err error) {
+		if project, err = manager.Projects().Create("Garden", ""); err != nil {
+			return err
+		}
+		task, err = manager.Tasks().Create(project, "Water plants", "", "", 0)
+		return err
+	})
+	if _, err := manager.Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring the task from trash is an action, making the undone one impossible to redo
+	trashed, _ := getTask(t, manager, task.ID)
+	do(t, manager, "Restore task", func() error {
+		return repository.RestoreTree(manager.Tasks(), trashed)
+	})
+
+	if got, ok := getTask(t, manager, task.ID); !ok || got.DeletedAt != 0 {
+		t.Errorf("restored task = %+v, %v, want it kept", got, ok)
+	}
+	if _, err := manager.Projects().GetByID(project.ID); err != nil {
+		t.Errorf("project of restored task: %v, want it kept", err)
+	}
+}
+
+func TestDeletionClearsHistory(t *testing.T) {
+	manager, project := newManager(t, 0)
+	task, _ := manager.Tasks().Create(project, "Old", "", "", 0)
+
+	do(t, manager, "Rename task", func() error {
+		return manager.Tasks().UpdateField(&task, "Title", "New")
+	})
+	do(t, manager, "Delete task", func() error {
+		return manager.Tasks().Delete(&task)
+	})
+
+	if _, err := manager.Undo(); !errors.Is(err, undo.ErrNothingToUndo) {
+		t.Errorf("Undo() error = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	manager, project := newManager(t, 2)
+	task, _ := manager.Tasks().Create(project, "0", "", "", 0)
+
+	for i := 1; i <= 3; i++ {
+		do(t, manager, "Rename task", func() error {
+			return manager.Tasks().UpdateField(&task, "Title", strconv.Itoa(i))
+		})
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := manager.Undo(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := manager.Undo(); !errors.Is(err, undo.ErrNothingToUndo) {
+		t.Errorf("Undo() beyond limit error = %v, want ErrNothingToUndo", err)
+	}
+	if got, _ := getTask(t, manager, task.ID); got.Title != "1" {
+		t.Errorf("Title = %q, want 1", got.Title)
+	}
+}
+
+func TestRolledBackChangesAreLeftOut(t *testing.T) {
+	manager, project := newManager(t, 0)
+	failure := errors.New("failed")
+
+	manager.Begin("Add task")
+	err := manager.Transactor().Transaction(func(projects repository.ProjectRepository, tasks repository.TaskRepository) error {
+		if _, err := tasks.Create(project, "Never", "", "", 0); err != nil {
+			return err
+		}
+		return failure
+	})
+	manager.End()
+
+	if !errors.Is(err, failure) {
+		t.Fatalf("Transaction() error = %v, want %v", err, failure)
+	}
+	if _, err := manager.Undo(); !errors.Is(err, undo.ErrNothingToUndo) {
+		t.Errorf("Undo() error = %v, want ErrNothingToUndo", err)
+	}
+}